@@ -11,7 +11,8 @@ func Routes(r *chi.Mux) http.Handler {
 	// r := chi.NewRouter()
 
 	r.Get("/swagger.yaml", func(w http.ResponseWriter, r *http.Request) {
-		http.ServeFile(w, r, "./api/swagger.yaml")
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(Spec)
 	})
 
 	r.Get("/*", httpSwagger.Handler(
@@ -0,0 +1,10 @@
+package api
+
+import (
+	_ "embed"
+)
+
+// Spec is the OpenAPI description of the HTTP API, request bodies are validated against it.
+//
+//go:embed swagger.yaml
+var Spec []byte
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/pressly/goose/v3"
+
+	"trainee-pvz/internal/repository"
+	"trainee-pvz/internal/service"
+	"trainee-pvz/migrations"
+)
+
+// runCommand executes a maintenance command instead of starting the servers.
+func runCommand(ctx context.Context, db *sqlx.DB, args []string) error {
+	stats := service.NewStatsService(repository.NewStatsRepository(db, db))
+
+	switch name := args[0]; name {
+	case "migrate":
+		return runMigrate(ctx, db.DB, args[1:])
+
+	case "backfill-intake":
+		rows, err := stats.RebuildDailyIntake(ctx)
+		if err != nil {
+			return err
+		}
+		slog.Info("daily intake has been rebuilt", slog.Int64("rows", rows))
+
+	case "check-intake":
+		mismatches, err := stats.CheckDailyIntake(ctx)
+		if err != nil {
+			return err
+		}
+		for _, m := range mismatches {
+			fmt.Printf("%s\t%s\t%s\tstored=%d\tactual=%d\n",
+				m.Day.Format("2006-01-02"), m.PVZID, m.ProductType, m.Stored, m.Actual)
+		}
+		if len(mismatches) > 0 {
+			return errors.Errorf("daily intake differs from products in %d rows, run backfill-intake", len(mismatches))
+		}
+		slog.Info("daily intake is consistent")
+
+	default:
+		return errors.Errorf("unknown command %q, expected migrate, backfill-intake or check-intake", name)
+	}
+
+	return nil
+}
+
+// runMigrate applies or rolls back the embedded migrations: up, down (one step), status or version.
+func runMigrate(ctx context.Context, db *sql.DB, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: migrate up|down|status|version")
+	}
+
+	provider, err := migrations.NewProvider(db)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		results, err := provider.Up(ctx)
+		if err != nil {
+			return errors.Wrap(err, "migrate up")
+		}
+		for _, r := range results {
+			slog.Info("migration applied", slog.Int64("version", r.Source.Version), slog.Duration("duration", r.Duration))
+		}
+		if len(results) == 0 {
+			slog.Info("no migrations to apply")
+		}
+
+	case "down":
+		r, err := provider.Down(ctx)
+		if err != nil {
+			return errors.Wrap(err, "migrate down")
+		}
+		slog.Info("migration rolled back", slog.Int64("version", r.Source.Version), slog.Duration("duration", r.Duration))
+
+	case "status":
+		statuses, err := provider.Status(ctx)
+		if err != nil {
+			return errors.Wrap(err, "migrate status")
+		}
+		for _, st := range statuses {
+			applied := "pending"
+			if st.State == goose.StateApplied {
+				applied = st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%d\t%s\t%s\n", st.Source.Version, applied, filepath.Base(st.Source.Path))
+		}
+
+	case "version":
+		current, target, err := provider.GetVersions(ctx)
+		if err != nil {
+			return errors.Wrap(err, "migrate version")
+		}
+		fmt.Printf("current=%d\tlatest=%d\n", current, target)
+
+	default:
+		return errors.Errorf("unknown migrate command %q, expected up, down, status or version", args[0])
+	}
+
+	return nil
+}
+
+// autoMigrate brings the schema up to date on startup; concurrent replicas wait on the advisory lock.
+func autoMigrate(ctx context.Context, db *sql.DB) error {
+	provider, err := migrations.NewProvider(db)
+	if err != nil {
+		return err
+	}
+
+	results, err := provider.Up(ctx)
+	if err != nil {
+		return errors.Wrap(err, "auto-migrate")
+	}
+	slog.Info("schema is up to date", slog.Int("applied", len(results)))
+
+	return nil
+}
@@ -7,78 +7,163 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/health"
+
+	"trainee-pvz/api"
 	"trainee-pvz/config"
 	"trainee-pvz/internal/auth"
-	"trainee-pvz/internal/database"
 	proto_pvz "trainee-pvz/internal/grpc"
 	"trainee-pvz/internal/handler"
+	"trainee-pvz/internal/lifecycle"
+	"trainee-pvz/internal/logger"
 	"trainee-pvz/internal/metrics"
-	"trainee-pvz/internal/repository"
+	"trainee-pvz/internal/pagination"
 	"trainee-pvz/internal/service"
+	"trainee-pvz/internal/tracing"
+	"trainee-pvz/internal/validation"
+	"trainee-pvz/migrations"
 )
 
+const healthCheckInterval = 5 * time.Second
+
 func main() {
-	ctx, cancelFunc := signal.NotifyContext(context.Background(), os.Interrupt)
+	err := run()
+	if err != nil {
+		slog.Error("exit with error", slog.Any("err", err))
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	ctx, cancelFunc := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancelFunc()
 
-	cfg, err := config.GetConfig("config.yaml")
+	cfg, args, err := config.Load(os.Args[1:], os.LookupEnv)
+	if err != nil {
+		return errors.Wrap(err, "invalid config")
+	}
+
+	log, err := logger.New(os.Stdout, cfg.Log.Format, cfg.Log.Level)
 	if err != nil {
-		slog.Error("Can't read config.yaml", slog.Any("error", err))
-		return
+		return errors.Wrap(err, "invalid log config")
 	}
+	slog.SetDefault(log)
 
-	db, err := database.ConnectDB(ctx, cfg.DB.Connection)
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
 	if err != nil {
-		slog.Error("failed to connect", slog.Any("err", err))
-		return
+		return errors.Wrap(err, "can't set up tracing")
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			slog.Error("failed to flush traces", slog.Any("err", err))
+		}
+	}()
+
+	st, err := openStorage(ctx, cfg.DB)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect")
+	}
+	defer st.Close()
+
+	if len(args) > 0 {
+		if st.db == nil {
+			return errors.Errorf("command %s needs postgres storage", args[0])
+		}
+		return errors.Wrapf(runCommand(ctx, st.db, args), "command %s failed", args[0])
+	}
+
+	if cfg.DB.AutoMigrate && st.db != nil {
+		err = autoMigrate(ctx, st.db.DB)
+		if err != nil {
+			return err
+		}
 	}
-	defer db.Close()
 
 	m := metrics.InitMetrics()
+	repos, err := st.repositories(ctx, m)
+	if err != nil {
+		return err
+	}
 
-	userRepo := repository.NewUserRepository(db)
-	receptionRepo := repository.NewReceptionRepository(db)
-	productRepo := repository.NewProductRepository(db)
-	PVZRepo := repository.NewPVZRepository(db)
+	userService := service.NewUserService(repos.user)
+	receptionService := service.NewReceptionService(repos.reception, m)
+	productService := service.NewProductService(repos.product, m, cfg.Limits.RejectOverCapacity)
+	PVZService := service.NewPVZService(repos.pvz, m)
+	statsService := service.NewStatsService(repos.stats)
+	exportService := service.NewExportService(repos.export)
+	auditService := service.NewAuditService(repos.audit)
 
-	userService := service.NewUserService(userRepo)
-	receptionService := service.NewReceptionService(receptionRepo, m)
-	productService := service.NewProductService(productRepo, m)
-	PVZService := service.NewPVZService(PVZRepo, m)
+	schemaVersion, err := migrations.LatestVersion()
+	if err != nil {
+		return errors.Wrap(err, "can't read embedded migrations")
+	}
+	healthService := service.NewHealthService(repos.health, schemaVersion)
+
+	m.RegisterOpenReceptions(statsService.OpenReceptionsByCity)
+	m.RegisterCapacityUtilisation(statsService.CapacityUtilisationByCity)
 
 	services := handler.Services{
 		User:      userService,
 		Product:   productService,
 		Reception: receptionService,
 		PVZ:       PVZService,
+		Stats:     statsService,
+		Export:    exportService,
+		Audit:     auditService,
+		Health:    healthService,
 	}
 
 	jwtManager := auth.NewJWTManager(cfg.Auth.JWTSecret, cfg.Auth.JWTExpirationMinutes)
 
-	server := handler.NewServer(services, jwtManager, cfg, m)
-	router := server.Routes()
-
-	go metrics.RunMetricServer(cfg.Prometheus.Port)
-
-	go func() {
-		err = proto_pvz.StartGRPCServer(PVZRepo, fmt.Sprintf(":%s", cfg.GRPC.Port))
+	store := config.NewStore(cfg)
+	store.OnReload(func(next config.Cfg) {
+		err := logger.SetLevel(next.Log.Level)
 		if err != nil {
-			slog.Error("Can't start GRPC:", slog.Any("error", err))
+			slog.Error("can't apply log level", slog.Any("err", err))
 		}
-	}()
+	})
+
+	validator, err := validation.New(api.Spec)
+	if err != nil {
+		return errors.Wrap(err, "can't load api spec")
+	}
 
-	slog.Info("Starting GRPC server on", slog.String("port", cfg.GRPC.Port))
+	server := handler.NewServer(services, jwtManager, validator, store, m)
 
-	go func() {
-		err := http.ListenAndServe(fmt.Sprintf(":%s", cfg.HTTP.Port), router)
+	grpcHealth := health.NewServer()
+	limits := func() pagination.Limits {
+		cur := store.Get().Limits
+		return pagination.Limits{Default: cur.PaginationLimit, Max: cur.MaxPaginationLimit}
+	}
+	grpcServer := proto_pvz.NewGRPCServer(PVZService, statsService, limits, jwtManager, m, grpcHealth)
+
+	app := lifecycle.New(time.Duration(cfg.Shutdown.DrainTimeout)*time.Millisecond,
+		time.Duration(cfg.Shutdown.ReadinessDelay)*time.Millisecond)
+	app.AddServer("http", fmt.Sprintf(":%s", cfg.HTTP.Port), &http.Server{Handler: server.Routes()})
+	app.AddServer("grpc", fmt.Sprintf(":%s", cfg.GRPC.Port), lifecycle.GRPC(grpcServer))
+	app.AddServer("metrics", fmt.Sprintf(":%s", cfg.Prometheus.Port), metrics.NewServer(m.Handler()))
+	app.AddWorker("grpc-health", func(ctx context.Context) {
+		proto_pvz.WatchHealth(ctx, healthService, grpcHealth, healthCheckInterval)
+	})
+	app.AddWorker("config-watch", func(ctx context.Context) {
+		load := func() (config.Cfg, error) {
+			next, _, err := config.Load(os.Args[1:], os.LookupEnv)
+			return next, err
+		}
+		err := config.Watch(ctx, store, load, m)
 		if err != nil {
-			slog.Error("Can't start service:", slog.Any("error", err))
+			slog.Error("config hot reload is disabled", slog.Any("err", err))
 		}
-	}()
-
-	slog.Info("Starting HTTP server on", slog.String("port", cfg.HTTP.Port))
+	})
+	app.OnShutdown(healthService.SetShuttingDown)
+	// the health worker only polls readiness, gRPC health is switched right away
+	app.OnShutdown(grpcHealth.Shutdown)
 
-	<-ctx.Done()
-	slog.Info("Got shutdown signal, exit program")
+	return app.Run(ctx)
 }
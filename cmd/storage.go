@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"trainee-pvz/config"
+	"trainee-pvz/internal/database"
+	"trainee-pvz/internal/metrics"
+	"trainee-pvz/internal/repository"
+	"trainee-pvz/internal/repository/memory"
+	"trainee-pvz/internal/repository/pgxrepo"
+	"trainee-pvz/internal/service"
+	"trainee-pvz/migrations"
+)
+
+// repositories are the service dependencies built on the configured driver.
+type repositories struct {
+	user      service.UserRepository
+	reception service.ReceptionRepository
+	product   service.ProductRepository
+	pvz       service.PVZRepository
+	stats     service.StatsRepository
+	export    service.ExportRepository
+	audit     service.AuditRepository
+	health    service.HealthRepository
+}
+
+// storage is the primary database connection. db serves migrations and maintenance commands
+// with either driver; with pgx it runs on top of pool, so the connections are shared.
+// With memory storage there is no database and db is nil.
+type storage struct {
+	cfg     config.DbCfg
+	db      *sqlx.DB
+	pool    *pgxpool.Pool
+	memory  *memory.Store
+	closers []func()
+}
+
+func openStorage(ctx context.Context, cfg config.DbCfg) (*storage, error) {
+	s := &storage{cfg: cfg}
+	if cfg.Storage == config.StorageMemory {
+		slog.Warn("using memory storage, data is lost on restart")
+		s.memory = memory.NewStore()
+		return s, nil
+	}
+
+	if cfg.Driver != config.DriverPGX {
+		db, err := database.ConnectDB(ctx, cfg.Connection, cfg)
+		if err != nil {
+			return nil, err
+		}
+		s.db = db
+		s.closers = append(s.closers, func() { db.Close() })
+		return s, nil
+	}
+
+	pool, err := database.ConnectPool(ctx, cfg.Connection, cfg)
+	if err != nil {
+		return nil, err
+	}
+	s.pool = pool
+	s.db = sqlx.NewDb(stdlib.OpenDBFromPool(pool), "pgx")
+	s.closers = append(s.closers, pool.Close, func() { s.db.Close() })
+	return s, nil
+}
+
+// Close closes the replica and primary connections in reverse order of opening.
+func (s *storage) Close() {
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		s.closers[i]()
+	}
+}
+
+// repositories connects to the replica when it's configured, registers the pool metrics and
+// builds the repositories of the configured driver.
+func (s *storage) repositories(ctx context.Context, m *metrics.Metrics) (repositories, error) {
+	if s.memory != nil {
+		return s.memoryRepositories()
+	}
+	if s.pool != nil {
+		return s.pgxRepositories(ctx, m)
+	}
+
+	replica := s.db
+	if s.cfg.ReplicaConnection != "" {
+		var err error
+		replica, err = database.ConnectDB(ctx, s.cfg.ReplicaConnection, s.cfg)
+		if err != nil {
+			return repositories{}, errors.Wrap(err, "failed to connect to replica")
+		}
+		s.closers = append(s.closers, func() { replica.Close() })
+	}
+
+	m.RegisterDBStats(s.db.DB, "primary")
+	if replica != s.db {
+		m.RegisterDBStats(replica.DB, "replica")
+	}
+
+	return repositories{
+		user:      repository.NewUserRepository(s.db),
+		reception: repository.NewReceptionRepository(s.db),
+		product:   repository.NewProductRepository(s.db),
+		pvz:       repository.NewPVZRepository(s.db, replica),
+		stats:     repository.NewStatsRepository(s.db, replica),
+		export:    repository.NewExportRepository(s.db),
+		audit:     repository.NewAuditRepository(s.db),
+		health:    repository.NewHealthRepository(s.db),
+	}, nil
+}
+
+func (s *storage) pgxRepositories(ctx context.Context, m *metrics.Metrics) (repositories, error) {
+	replica := s.pool
+	if s.cfg.ReplicaConnection != "" {
+		var err error
+		replica, err = database.ConnectPool(ctx, s.cfg.ReplicaConnection, s.cfg)
+		if err != nil {
+			return repositories{}, errors.Wrap(err, "failed to connect to replica")
+		}
+		s.closers = append(s.closers, replica.Close)
+	}
+
+	m.RegisterPoolStats(s.pool, "primary")
+	if replica != s.pool {
+		m.RegisterPoolStats(replica, "replica")
+	}
+
+	return repositories{
+		user:      pgxrepo.NewUserRepository(s.pool),
+		reception: pgxrepo.NewReceptionRepository(s.pool),
+		product:   pgxrepo.NewProductRepository(s.pool),
+		pvz:       pgxrepo.NewPVZRepository(s.pool, replica),
+		stats:     pgxrepo.NewStatsRepository(s.pool, replica),
+		export:    pgxrepo.NewExportRepository(s.pool),
+		audit:     pgxrepo.NewAuditRepository(s.pool),
+		health:    pgxrepo.NewHealthRepository(s.pool),
+	}, nil
+}
+
+// memoryRepositories report the embedded schema version as applied, so the service is ready
+// right away.
+func (s *storage) memoryRepositories() (repositories, error) {
+	version, err := migrations.LatestVersion()
+	if err != nil {
+		return repositories{}, errors.Wrap(err, "can't read embedded migrations")
+	}
+
+	return repositories{
+		user:      memory.NewUserRepository(s.memory),
+		reception: memory.NewReceptionRepository(s.memory),
+		product:   memory.NewProductRepository(s.memory),
+		pvz:       memory.NewPVZRepository(s.memory),
+		stats:     memory.NewStatsRepository(s.memory),
+		export:    memory.NewExportRepository(s.memory),
+		audit:     memory.NewAuditRepository(s.memory),
+		health:    memory.NewHealthRepository(version),
+	}, nil
+}
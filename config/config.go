@@ -14,47 +14,129 @@ type Cfg struct {
 	Prometheus PrometheusCfg `yaml:"prometheus"`
 	Auth       AuthCfg       `yaml:"auth"`
 	Limits     LimitsCfg     `yaml:"limits"`
+	Log        LogCfg        `yaml:"log"`
+	Tracing    TracingCfg    `yaml:"tracing"`
+	Shutdown   ShutdownCfg   `yaml:"shutdown"`
+
+	File string `yaml:"-"` // path the config was read from, watched for reloads
 }
 
+// Database drivers the repositories can be built on.
+const (
+	DriverSQLX = "sqlx"
+	DriverPGX  = "pgx"
+)
+
+// Storages the service keeps its data in. Memory storage is lost on restart and serves
+// tests and local demos.
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
+
 type DbCfg struct {
-	Connection string `yaml:"connection"`
+	Storage           string `yaml:"storage" env:"PVZ_DB_STORAGE"` // postgres or memory, the rest of the section is for postgres
+	Driver            string `yaml:"driver" env:"PVZ_DB_DRIVER"`   // sqlx or pgx
+	Connection        string `yaml:"connection" env:"PVZ_DB_CONNECTION"`
+	ReplicaConnection string `yaml:"replica_connection" env:"PVZ_DB_REPLICA_CONNECTION"` // optional, serves list and report reads
+	AutoMigrate       bool   `yaml:"auto_migrate" env:"PVZ_DB_AUTO_MIGRATE"`             // apply embedded migrations on startup
+	MaxOpenConns      int    `yaml:"max_open_conns" env:"PVZ_DB_MAX_OPEN_CONNS"`
+	MaxIdleConns      int    `yaml:"max_idle_conns" env:"PVZ_DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime   int    `yaml:"conn_max_lifetime_ms" env:"PVZ_DB_CONN_MAX_LIFETIME_MS"`
+	ConnMaxIdleTime   int    `yaml:"conn_max_idle_time_ms" env:"PVZ_DB_CONN_MAX_IDLE_TIME_MS"`
+	ConnectTimeout    int    `yaml:"connect_timeout_ms" env:"PVZ_DB_CONNECT_TIMEOUT_MS"` // how long startup waits for Postgres
+	// prepared statements kept per connection by the pgx driver, 0 disables the cache (e.g. behind PgBouncer)
+	StatementCacheCapacity int `yaml:"statement_cache_capacity" env:"PVZ_DB_STATEMENT_CACHE_CAPACITY"`
 }
 
 type HTTPServerCfg struct {
-	Port    string `yaml:"http_port"`
-	Timeout int    `yaml:"timeout_ms"`
+	Port          string `yaml:"http_port" env:"PVZ_HTTP_PORT"`
+	Timeout       int    `yaml:"timeout_ms" env:"PVZ_HTTP_TIMEOUT_MS"`
+	ExportTimeout int    `yaml:"export_timeout_ms" env:"PVZ_HTTP_EXPORT_TIMEOUT_MS"`
+	ImportTimeout int    `yaml:"import_timeout_ms" env:"PVZ_HTTP_IMPORT_TIMEOUT_MS"`
 }
 
 type GRPSCfg struct {
-	Port string `yaml:"grps_port"`
+	Port string `yaml:"grps_port" env:"PVZ_GRPC_PORT"`
 }
 
 type PrometheusCfg struct {
-	Port string `yaml:"prometheus_port"`
+	Port string `yaml:"prometheus_port" env:"PVZ_METRICS_PORT"`
 }
 
 type AuthCfg struct {
-	DummyTokenPrefix     string `yaml:"dummy_token_prefix"`
-	JWTSecret            string `yaml:"jwt_secret"`
-	JWTExpirationMinutes int    `yaml:"jwt_expiration_minutes"`
+	DummyTokenPrefix     string `yaml:"dummy_token_prefix" env:"PVZ_AUTH_DUMMY_TOKEN_PREFIX"`
+	JWTSecret            string `yaml:"jwt_secret" env:"PVZ_AUTH_JWT_SECRET"`
+	JWTExpirationMinutes int    `yaml:"jwt_expiration_minutes" env:"PVZ_AUTH_JWT_EXPIRATION_MINUTES"`
 }
 
 type LimitsCfg struct {
-	PaginationLimit int `yaml:"pagination_limit"`
+	PaginationLimit    int  `yaml:"pagination_limit" env:"PVZ_LIMITS_PAGINATION_LIMIT"`
+	MaxPaginationLimit int  `yaml:"max_pagination_limit" env:"PVZ_LIMITS_MAX_PAGINATION_LIMIT"`
+	RejectOverCapacity bool `yaml:"reject_over_capacity" env:"PVZ_LIMITS_REJECT_OVER_CAPACITY"`
+}
+
+type LogCfg struct {
+	Format string `yaml:"format" env:"PVZ_LOG_FORMAT"` // json or text
+	Level  string `yaml:"level" env:"PVZ_LOG_LEVEL"`   // debug, info, warn or error
 }
 
+type TracingCfg struct {
+	Exporter     string  `yaml:"exporter" env:"PVZ_TRACING_EXPORTER"` // none, stdout or otlp
+	OTLPEndpoint string  `yaml:"otlp_endpoint" env:"PVZ_TRACING_OTLP_ENDPOINT"`
+	OTLPInsecure bool    `yaml:"otlp_insecure" env:"PVZ_TRACING_OTLP_INSECURE"`
+	SampleRatio  float64 `yaml:"sample_ratio" env:"PVZ_TRACING_SAMPLE_RATIO"`
+	ServiceName  string  `yaml:"service_name" env:"PVZ_TRACING_SERVICE_NAME"`
+}
+
+type ShutdownCfg struct {
+	// time the servers keep accepting connections with failing readiness, so load balancers notice it
+	ReadinessDelay int `yaml:"readiness_delay_ms" env:"PVZ_SHUTDOWN_READINESS_DELAY_MS"`
+	DrainTimeout   int `yaml:"drain_timeout_ms" env:"PVZ_SHUTDOWN_DRAIN_TIMEOUT_MS"` // time given to in-flight requests after a signal
+}
+
+// Default returns the settings used for everything the file, env and flags leave unset.
+func Default() Cfg {
+	return Cfg{
+		DB: DbCfg{
+			Storage:                StoragePostgres,
+			Driver:                 DriverSQLX,
+			MaxOpenConns:           25,
+			MaxIdleConns:           10,
+			ConnMaxLifetime:        30 * 60 * 1000,
+			ConnMaxIdleTime:        5 * 60 * 1000,
+			ConnectTimeout:         30000,
+			StatementCacheCapacity: 512,
+		},
+		HTTP:       HTTPServerCfg{Port: "8080", Timeout: 1000, ExportTimeout: 60000, ImportTimeout: 30000},
+		GRPC:       GRPSCfg{Port: "3000"},
+		Prometheus: PrometheusCfg{Port: "9000"},
+		Auth:       AuthCfg{DummyTokenPrefix: "dummy-token-for-", JWTExpirationMinutes: 60},
+		Limits:     LimitsCfg{PaginationLimit: 10, MaxPaginationLimit: 30},
+		Log:        LogCfg{Format: "text", Level: "info"},
+		Tracing:    TracingCfg{Exporter: "none", SampleRatio: 1, ServiceName: "pvz"},
+		Shutdown:   ShutdownCfg{ReadinessDelay: 5000, DrainTimeout: 10000},
+	}
+}
+
+// GetConfig reads the file over the defaults, without env and flag overrides.
 func GetConfig(path string) (Cfg, error) {
-	var cfg Cfg
+	cfg := Default()
+	cfg.File = path
+	err := readFile(path, &cfg)
+	return cfg, err
+}
 
+func readFile(path string, cfg *Cfg) error {
 	file, err := os.Open(path)
 	if err != nil {
-		return cfg, errors.Wrap(err, "failed to open config file")
+		return errors.Wrap(err, "failed to open config file")
 	}
 	defer file.Close()
 
-	if err := yaml.NewDecoder(file).Decode(&cfg); err != nil {
-		return cfg, errors.Wrap(err, "failed to decode config")
+	if err := yaml.NewDecoder(file).Decode(cfg); err != nil {
+		return errors.Wrap(err, "failed to decode config")
 	}
 
-	return cfg, nil
+	return nil
 }
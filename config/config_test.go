@@ -0,0 +1,142 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"trainee-pvz/config"
+)
+
+const testYAML = `
+db:
+  connection: "postgres://file"
+http_server:
+  http_port: "8081"
+auth:
+  jwt_secret: "from-file"
+limits:
+  pagination_limit: 5
+`
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+func TestLoad_Layers(t *testing.T) {
+	path := writeFile(t, "config.yaml", testYAML)
+	secret := writeFile(t, "jwt", "from-secret-file\n")
+
+	cfg, args, err := config.Load(
+		[]string{"-config", path, "-http-port", "8090", "backfill-intake"},
+		env(map[string]string{
+			"PVZ_HTTP_PORT":                   "8085",
+			"PVZ_DB_CONNECTION":               "postgres://env",
+			"PVZ_AUTH_JWT_SECRET_FILE":        secret,
+			"PVZ_LIMITS_MAX_PAGINATION_LIMIT": "50",
+		}),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"backfill-intake"}, args)
+	assert.Equal(t, "8090", cfg.HTTP.Port, "flag wins over env and file")
+	assert.Equal(t, "postgres://env", cfg.DB.Connection, "env wins over file")
+	assert.Equal(t, "from-secret-file", cfg.Auth.JWTSecret)
+	assert.Equal(t, 5, cfg.Limits.PaginationLimit, "file wins over defaults")
+	assert.Equal(t, 50, cfg.Limits.MaxPaginationLimit)
+	assert.Equal(t, "3000", cfg.GRPC.Port, "default is kept")
+}
+
+func TestLoad_EnvErrors(t *testing.T) {
+	path := writeFile(t, "config.yaml", testYAML)
+
+	_, _, err := config.Load([]string{"-config", path}, env(map[string]string{
+		"PVZ_HTTP_TIMEOUT_MS":      "soon",
+		"PVZ_AUTH_JWT_SECRET":      "a",
+		"PVZ_AUTH_JWT_SECRET_FILE": "/run/secrets/jwt",
+	}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "PVZ_HTTP_TIMEOUT_MS")
+	assert.Contains(t, err.Error(), "both PVZ_AUTH_JWT_SECRET and PVZ_AUTH_JWT_SECRET_FILE")
+}
+
+func TestValidate_ReportsAllErrors(t *testing.T) {
+	cfg := config.Default()
+	cfg.GRPC.Port = cfg.HTTP.Port
+	cfg.Prometheus.Port = ""
+	cfg.HTTP.Timeout = 0
+	cfg.Limits.PaginationLimit = -1
+	cfg.DB.Driver = "pq"
+	cfg.DB.Storage = "redis"
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	for _, want := range []string{
+		"grps.grps_port: port 8080 is already used by http_server.http_port",
+		"prometheus.prometheus_port: invalid port",
+		"db.connection: required",
+		`db.driver: unknown driver "pq", expected sqlx or pgx`,
+		`db.storage: unknown storage "redis", expected postgres or memory`,
+		"auth.jwt_secret: required",
+		"http_server.timeout_ms: must be positive",
+		"limits.pagination_limit: must be positive",
+	} {
+		assert.Contains(t, err.Error(), want)
+	}
+}
+
+func TestValidate_RepoConfig(t *testing.T) {
+	cfg, err := config.GetConfig("../config.yaml")
+	require.NoError(t, err)
+	assert.Empty(t, cfg.DB.Connection, "no credentials in the repo")
+	assert.Empty(t, cfg.Auth.JWTSecret, "no secrets in the repo")
+
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "auth.jwt_secret: required, set PVZ_AUTH_JWT_SECRET")
+
+	cfg.DB.Connection = "postgres://test"
+	cfg.Auth.JWTSecret = "secret"
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestLoad_CommandsNeedOnlyDB(t *testing.T) {
+	dbOnly := env(map[string]string{"PVZ_DB_CONNECTION": "postgres://migrations"})
+
+	// the repo config has no secrets, a migration job only gets the database
+	cfg, args, err := config.Load([]string{"-config", "../config.yaml", "migrate", "up"}, dbOnly)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"migrate", "up"}, args)
+	assert.Equal(t, "postgres://migrations", cfg.DB.Connection)
+
+	_, _, err = config.Load([]string{"-config", "../config.yaml"}, dbOnly)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "auth.jwt_secret: required")
+
+	_, _, err = config.Load([]string{"-config", "../config.yaml", "check-intake"}, env(nil))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "db.connection: required")
+	assert.NotContains(t, err.Error(), "auth.jwt_secret")
+}
+
+func TestLoad_MemoryStorageNeedsNoConnection(t *testing.T) {
+	path := writeFile(t, "config.yaml", testYAML)
+
+	cfg, _, err := config.Load([]string{"-config", path, "-storage", "memory"}, env(map[string]string{
+		"PVZ_DB_CONNECTION": "",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, config.StorageMemory, cfg.DB.Storage)
+}
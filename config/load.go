@@ -0,0 +1,161 @@
+package config
+
+import (
+	stderrors "errors"
+	"flag"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultPath = "config.yaml"
+
+	// fileEnvSuffix marks an env var holding a path to a file with the value, e.g.
+	// PVZ_AUTH_JWT_SECRET_FILE=/run/secrets/jwt, so secrets don't have to be in the env or config.
+	fileEnvSuffix = "_FILE"
+)
+
+// Load builds the config in layers: defaults, the file given by -config, PVZ_* env vars and
+// then command-line flags, and validates the result. It returns the arguments left after the
+// flags, i.e. the subcommand; with a subcommand only the database settings are validated.
+func Load(args []string, lookupEnv func(string) (string, bool)) (Cfg, []string, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("pvz", flag.ContinueOnError)
+	path := fs.String("config", DefaultPath, "path to the YAML config file")
+	httpPort := fs.String("http-port", "", "HTTP port")
+	grpcPort := fs.String("grpc-port", "", "gRPC port")
+	metricsPort := fs.String("metrics-port", "", "Prometheus metrics port")
+	dbConnection := fs.String("db", "", "Postgres connection string")
+	storage := fs.String("storage", "", "storage: postgres or memory")
+	logLevel := fs.String("log-level", "", "log level: debug, info, warn or error")
+	logFormat := fs.String("log-format", "", "log format: json or text")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return cfg, nil, errors.Wrap(err, "can't parse flags")
+	}
+
+	cfg.File = *path
+	err = readFile(*path, &cfg)
+	if err != nil {
+		return cfg, nil, errors.Wrapf(err, "config %s", *path)
+	}
+
+	err = applyEnv(&cfg, lookupEnv)
+	if err != nil {
+		return cfg, nil, err
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "http-port":
+			cfg.HTTP.Port = *httpPort
+		case "grpc-port":
+			cfg.GRPC.Port = *grpcPort
+		case "metrics-port":
+			cfg.Prometheus.Port = *metricsPort
+		case "db":
+			cfg.DB.Connection = *dbConnection
+		case "storage":
+			cfg.DB.Storage = *storage
+		case "log-level":
+			cfg.Log.Level = *logLevel
+		case "log-format":
+			cfg.Log.Format = *logFormat
+		}
+	})
+
+	if fs.NArg() > 0 {
+		return cfg, fs.Args(), cfg.ValidateDB()
+	}
+
+	return cfg, fs.Args(), cfg.Validate()
+}
+
+// applyEnv sets every field that has an env tag from NAME or from the file named by NAME_FILE.
+// All bad values are reported together.
+func applyEnv(cfg *Cfg, lookupEnv func(string) (string, bool)) error {
+	var errs []error
+
+	sections := reflect.ValueOf(cfg).Elem()
+	for i := range sections.NumField() {
+		section := sections.Field(i)
+		if section.Kind() != reflect.Struct {
+			continue
+		}
+		for j := range section.NumField() {
+			name := section.Type().Field(j).Tag.Get("env")
+			if name == "" {
+				continue
+			}
+
+			value, ok, err := lookupValue(name, lookupEnv)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if !ok {
+				continue
+			}
+
+			err = setField(section.Field(j), value)
+			if err != nil {
+				errs = append(errs, errors.Wrapf(err, "invalid %s", name))
+			}
+		}
+	}
+
+	return stderrors.Join(errs...)
+}
+
+func lookupValue(name string, lookupEnv func(string) (string, bool)) (string, bool, error) {
+	value, ok := lookupEnv(name)
+	path, fromFile := lookupEnv(name + fileEnvSuffix)
+	if !fromFile {
+		return value, ok, nil
+	}
+	if ok {
+		return "", false, errors.Errorf("both %s and %s%s are set", name, name, fileEnvSuffix)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, errors.Wrapf(err, "can't read %s%s", name, fileEnvSuffix)
+	}
+
+	return strings.TrimSpace(string(content)), true, nil
+}
+
+func setField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return errors.Errorf("unsupported field kind %s", field.Kind())
+	}
+
+	return nil
+}
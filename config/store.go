@@ -0,0 +1,99 @@
+package config
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// Store holds the current config and swaps it on reload. Only the settings copied by
+// reloadable take effect at runtime; everything else needs a restart.
+type Store struct {
+	cur     atomic.Pointer[Cfg]
+	version atomic.Int64
+
+	mu       sync.Mutex // serialises reloads and hooks
+	onReload []func(Cfg)
+}
+
+func NewStore(cfg Cfg) *Store {
+	s := &Store{}
+	s.cur.Store(&cfg)
+	s.version.Store(1)
+	return s
+}
+
+// Get returns the current config; callers must not modify it.
+func (s *Store) Get() *Cfg {
+	return s.cur.Load()
+}
+
+// Version starts at 1 and grows with every applied reload.
+func (s *Store) Version() int64 {
+	return s.version.Load()
+}
+
+// OnReload registers fn to be called with the new config after every applied reload.
+func (s *Store) OnReload(fn func(Cfg)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onReload = append(s.onReload, fn)
+}
+
+// Reload validates next and applies its reloadable settings. An invalid config is rejected and
+// the current one stays. It also returns the restart-only settings that differ in next and were
+// ignored.
+func (s *Store) Reload(next Cfg) (ignored []string, err error) {
+	err = next.Validate()
+	if err != nil {
+		return nil, errors.Wrap(err, "config rejected")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cur := *s.cur.Load()
+	applied := reloadable(cur, next)
+	ignored = restartOnlyChanges(applied, next)
+
+	s.cur.Store(&applied)
+	s.version.Add(1)
+	for _, fn := range s.onReload {
+		fn(applied)
+	}
+
+	return ignored, nil
+}
+
+// reloadable returns cur with the settings that can change at runtime taken from next.
+func reloadable(cur, next Cfg) Cfg {
+	cur.HTTP.Timeout = next.HTTP.Timeout
+	cur.HTTP.ExportTimeout = next.HTTP.ExportTimeout
+	cur.HTTP.ImportTimeout = next.HTTP.ImportTimeout
+	cur.Limits.PaginationLimit = next.Limits.PaginationLimit
+	cur.Limits.MaxPaginationLimit = next.Limits.MaxPaginationLimit
+	cur.Log.Level = next.Log.Level
+	return cur
+}
+
+func restartOnlyChanges(applied, next Cfg) []string {
+	var changed []string
+	add := func(differs bool, name string) {
+		if differs {
+			changed = append(changed, name)
+		}
+	}
+
+	add(applied.DB != next.DB, "db")
+	add(applied.HTTP.Port != next.HTTP.Port, "http_server.http_port")
+	add(applied.GRPC != next.GRPC, "grps")
+	add(applied.Prometheus != next.Prometheus, "prometheus")
+	add(applied.Auth != next.Auth, "auth")
+	add(applied.Limits.RejectOverCapacity != next.Limits.RejectOverCapacity, "limits.reject_over_capacity")
+	add(applied.Log.Format != next.Log.Format, "log.format")
+	add(applied.Tracing != next.Tracing, "tracing")
+	add(applied.Shutdown != next.Shutdown, "shutdown")
+
+	return changed
+}
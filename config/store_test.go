@@ -0,0 +1,144 @@
+package config_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"trainee-pvz/config"
+)
+
+func validConfig() config.Cfg {
+	cfg := config.Default()
+	cfg.DB.Connection = "postgres://test"
+	cfg.Auth.JWTSecret = "secret"
+	return cfg
+}
+
+func TestStore_Reload(t *testing.T) {
+	store := config.NewStore(validConfig())
+
+	var applied config.Cfg
+	store.OnReload(func(cfg config.Cfg) { applied = cfg })
+
+	next := validConfig()
+	next.Limits.PaginationLimit = 20
+	next.HTTP.Timeout = 5000
+	next.HTTP.Port = "8088"
+
+	ignored, err := store.Reload(next)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http_server.http_port"}, ignored)
+	assert.Equal(t, int64(2), store.Version())
+	assert.Equal(t, 20, store.Get().Limits.PaginationLimit)
+	assert.Equal(t, 5000, store.Get().HTTP.Timeout)
+	assert.Equal(t, "8080", store.Get().HTTP.Port, "restart-only setting is kept")
+	assert.Equal(t, *store.Get(), applied)
+}
+
+func TestStore_ReloadInvalid(t *testing.T) {
+	store := config.NewStore(validConfig())
+
+	next := validConfig()
+	next.Limits.PaginationLimit = 0
+
+	_, err := store.Reload(next)
+	assert.Error(t, err)
+	assert.Equal(t, int64(1), store.Version())
+	assert.Equal(t, 10, store.Get().Limits.PaginationLimit)
+}
+
+type fakeReloadMetrics struct {
+	mu      sync.Mutex
+	version int64
+	failed  int
+}
+
+func (f *fakeReloadMetrics) SetConfigVersion(version int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.version = version
+}
+
+func (f *fakeReloadMetrics) SaveConfigReload(ok bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !ok {
+		f.failed++
+	}
+}
+
+func (f *fakeReloadMetrics) state() (int64, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.version, f.failed
+}
+
+func TestWatch_FileChange(t *testing.T) {
+	path := writeFile(t, "config.yaml", testYAML)
+	load := func() (config.Cfg, error) {
+		cfg, _, err := config.Load([]string{"-config", path}, env(nil))
+		return cfg, err
+	}
+	cfg, err := load()
+	require.NoError(t, err)
+
+	store := config.NewStore(cfg)
+	m := &fakeReloadMetrics{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go config.Watch(ctx, store, load, m)
+
+	require.Eventually(t, func() bool { v, _ := m.state(); return v == 1 }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, os.WriteFile(path, []byte(testYAML+"\nlog:\n  level: debug\n"), 0o600))
+	require.Eventually(t, func() bool { v, _ := m.state(); return v == 2 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "debug", store.Get().Log.Level)
+
+	require.NoError(t, os.WriteFile(path, []byte(testYAML+"\nlog:\n  level: loud\n"), 0o600))
+	require.Eventually(t, func() bool { _, f := m.state(); return f == 1 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "debug", store.Get().Log.Level, "invalid reload keeps the current config")
+	assert.Equal(t, int64(2), store.Version())
+}
+
+// TestWatch_ConfigMapSwap mimics a mounted ConfigMap: config.yaml links to ..data/config.yaml
+// and an update points the ..data link to a new directory.
+func TestWatch_ConfigMapSwap(t *testing.T) {
+	dir := t.TempDir()
+	writeVersion := func(name, yaml string) {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, name), 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name, "config.yaml"), []byte(yaml), 0o600))
+	}
+	writeVersion("..v1", testYAML)
+	require.NoError(t, os.Symlink("..v1", filepath.Join(dir, "..data")))
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.Symlink(filepath.Join("..data", "config.yaml"), path))
+
+	load := func() (config.Cfg, error) {
+		cfg, _, err := config.Load([]string{"-config", path}, env(nil))
+		return cfg, err
+	}
+	cfg, err := load()
+	require.NoError(t, err)
+
+	store := config.NewStore(cfg)
+	m := &fakeReloadMetrics{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go config.Watch(ctx, store, load, m)
+
+	require.Eventually(t, func() bool { v, _ := m.state(); return v == 1 }, time.Second, 10*time.Millisecond)
+
+	writeVersion("..v2", testYAML+"\nlog:\n  level: debug\n")
+	require.NoError(t, os.Symlink("..v2", filepath.Join(dir, "..data_tmp")))
+	require.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+
+	require.Eventually(t, func() bool { v, _ := m.state(); return v == 2 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "debug", store.Get().Log.Level)
+}
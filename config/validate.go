@@ -0,0 +1,87 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// Validate checks the settings the service can't start without and reports every problem
+// at once, so a broken deploy is fixed in one go.
+func (c Cfg) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	ports := []struct{ name, port string }{
+		{"http_server.http_port", c.HTTP.Port},
+		{"grps.grps_port", c.GRPC.Port},
+		{"prometheus.prometheus_port", c.Prometheus.Port},
+	}
+	usedBy := map[string]string{}
+	for _, p := range ports {
+		n, err := strconv.Atoi(p.port)
+		check(err == nil && n > 0 && n <= 65535, "%s: invalid port %q", p.name, p.port)
+		if other, ok := usedBy[p.port]; ok && p.port != "" {
+			check(false, "%s: port %s is already used by %s", p.name, p.port, other)
+		}
+		usedBy[p.port] = p.name
+	}
+
+	errs = append(errs, c.ValidateDB())
+	check(c.Auth.JWTSecret != "", "auth.jwt_secret: required, set PVZ_AUTH_JWT_SECRET or PVZ_AUTH_JWT_SECRET_FILE")
+	check(c.Auth.JWTExpirationMinutes > 0, "auth.jwt_expiration_minutes: must be positive")
+
+	check(c.HTTP.Timeout > 0, "http_server.timeout_ms: must be positive")
+	check(c.HTTP.ExportTimeout > 0, "http_server.export_timeout_ms: must be positive")
+	check(c.HTTP.ImportTimeout > 0, "http_server.import_timeout_ms: must be positive")
+	check(c.Shutdown.ReadinessDelay >= 0, "shutdown.readiness_delay_ms: must not be negative")
+	check(c.Shutdown.DrainTimeout > 0, "shutdown.drain_timeout_ms: must be positive")
+
+	check(c.Limits.PaginationLimit > 0, "limits.pagination_limit: must be positive")
+	check(c.Limits.MaxPaginationLimit > 0, "limits.max_pagination_limit: must be positive")
+	check(c.Limits.PaginationLimit <= c.Limits.MaxPaginationLimit,
+		"limits.pagination_limit: %d is above max_pagination_limit %d", c.Limits.PaginationLimit, c.Limits.MaxPaginationLimit)
+
+	var level slog.Level
+	check(c.Log.Level == "" || level.UnmarshalText([]byte(strings.ToUpper(c.Log.Level))) == nil,
+		"log.level: unknown level %q", c.Log.Level)
+	check(c.Log.Format == "" || c.Log.Format == "json" || c.Log.Format == "text",
+		"log.format: unknown format %q", c.Log.Format)
+
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio: must be within [0, 1]")
+
+	return errors.Join(errs...)
+}
+
+// ValidateDB checks only the database settings. The maintenance commands (migrate,
+// backfill-intake, check-intake) need nothing else, so they run without server secrets.
+func (c Cfg) ValidateDB() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.DB.Driver == DriverSQLX || c.DB.Driver == DriverPGX,
+		"db.driver: unknown driver %q, expected %s or %s", c.DB.Driver, DriverSQLX, DriverPGX)
+	check(c.DB.Storage == StoragePostgres || c.DB.Storage == StorageMemory,
+		"db.storage: unknown storage %q, expected %s or %s", c.DB.Storage, StoragePostgres, StorageMemory)
+	check(c.DB.Connection != "" || c.DB.Storage == StorageMemory,
+		"db.connection: required, set PVZ_DB_CONNECTION or PVZ_DB_CONNECTION_FILE")
+	check(c.DB.MaxOpenConns > 0, "db.max_open_conns: must be positive")
+	check(c.DB.MaxIdleConns >= 0 && c.DB.MaxIdleConns <= c.DB.MaxOpenConns,
+		"db.max_idle_conns: must be within [0, max_open_conns]")
+	check(c.DB.ConnMaxLifetime >= 0, "db.conn_max_lifetime_ms: must not be negative")
+	check(c.DB.ConnMaxIdleTime >= 0, "db.conn_max_idle_time_ms: must not be negative")
+	check(c.DB.ConnectTimeout > 0, "db.connect_timeout_ms: must be positive")
+	check(c.DB.StatementCacheCapacity >= 0, "db.statement_cache_capacity: must not be negative")
+
+	return errors.Join(errs...)
+}
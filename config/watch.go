@@ -0,0 +1,102 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+)
+
+// reloadDebounce merges the bursts of events editors and ConfigMap updates produce for one save.
+const reloadDebounce = 200 * time.Millisecond
+
+type ReloadMetrics interface {
+	SetConfigVersion(version int64)
+	SaveConfigReload(ok bool)
+}
+
+// Watch reloads the config into store when the config file changes or the process gets SIGHUP,
+// until ctx is done. load must rebuild the config with all layers, so env and flag overrides
+// survive a reload.
+func Watch(ctx context.Context, store *Store, load func() (Cfg, error), m ReloadMetrics) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return errors.Wrap(err, "can't create file watcher")
+	}
+	defer watcher.Close()
+
+	// the directory is watched, as the file itself is often replaced rather than written
+	path := filepath.Clean(store.Get().File)
+	err = watcher.Add(filepath.Dir(path))
+	if err != nil {
+		return errors.Wrapf(err, "can't watch %s", path)
+	}
+
+	// a mounted ConfigMap swaps its ..data symlink to a new directory and never touches the
+	// config.yaml link itself, so where the path resolves to is compared on every event too
+	target, _ := filepath.EvalSymlinks(path)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	m.SetConfigVersion(store.Version())
+
+	debounce := time.NewTimer(0)
+	<-debounce.C
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event := <-watcher.Events:
+			if filepath.Clean(event.Name) == path && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				debounce.Reset(reloadDebounce)
+			}
+			if resolved, err := filepath.EvalSymlinks(path); err == nil && resolved != target {
+				target = resolved
+				debounce.Reset(reloadDebounce)
+			}
+
+		case err := <-watcher.Errors:
+			slog.Error("config watcher error", slog.Any("err", err))
+
+		case <-hup:
+			slog.Info("SIGHUP received, reloading config")
+			reload(store, load, m)
+
+		case <-debounce.C:
+			slog.Info("config file changed, reloading", slog.String("file", path))
+			reload(store, load, m)
+		}
+	}
+}
+
+func reload(store *Store, load func() (Cfg, error), m ReloadMetrics) {
+	next, err := load()
+	if err == nil {
+		var ignored []string
+		ignored, err = store.Reload(next)
+		if len(ignored) > 0 {
+			slog.Warn("changed settings need a restart and were not applied", slog.Any("settings", ignored))
+		}
+	}
+
+	if err != nil {
+		slog.Error("config reload rejected, keeping the current config",
+			slog.Int64("version", store.Version()), slog.Any("err", err))
+		m.SaveConfigReload(false)
+		return
+	}
+
+	slog.Info("config reloaded", slog.Int64("version", store.Version()))
+	m.SaveConfigReload(true)
+	m.SetConfigVersion(store.Version())
+}
@@ -0,0 +1,25 @@
+// Package audit carries the author of a request down to the repositories that write audit_log.
+package audit
+
+import "context"
+
+type actorCtxKey struct{}
+
+// Actor is who performed an action and where the request came from.
+// UserID is empty for dummy tokens and unauthenticated requests.
+type Actor struct {
+	UserID    string
+	Role      string
+	RequestID string
+	IP        string
+}
+
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorCtxKey{}, actor)
+}
+
+// ActorFromContext returns the actor stored by WithActor, zero Actor for background jobs.
+func ActorFromContext(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorCtxKey{}).(Actor)
+	return actor
+}
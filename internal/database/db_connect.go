@@ -2,17 +2,71 @@ package database
 
 import (
 	"context"
+	"log/slog"
+	"time"
 
+	"github.com/XSAM/otelsql"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	"github.com/pkg/errors"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"trainee-pvz/config"
+)
+
+const (
+	initialRetryDelay = 200 * time.Millisecond
+	maxRetryDelay     = 5 * time.Second
 )
 
-func ConnectDB(ctx context.Context, connString string) (*sqlx.DB, error) {
-	db, err := sqlx.ConnectContext(ctx, "postgres", connString)
+// ConnectDB opens the pool through otelsql, so every query gets a span under the caller's one.
+// Pool limits come from cfg; while Postgres is still starting the ping is retried with
+// exponential backoff for up to cfg.ConnectTimeout.
+func ConnectDB(ctx context.Context, connString string, cfg config.DbCfg) (*sqlx.DB, error) {
+	sqlDB, err := otelsql.Open("postgres", connString,
+		otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{OmitConnResetSession: true, OmitRows: true}),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't open DB")
+	}
+
+	db := sqlx.NewDb(sqlDB, "postgres")
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime) * time.Millisecond)
+	db.SetConnMaxIdleTime(time.Duration(cfg.ConnMaxIdleTime) * time.Millisecond)
+
+	pingCtx, cancel := context.WithTimeout(ctx, time.Duration(cfg.ConnectTimeout)*time.Millisecond)
+	defer cancel()
+
+	err = retry(pingCtx, initialRetryDelay, maxRetryDelay, db.PingContext)
 	if err != nil {
+		db.Close()
 		return nil, errors.Wrap(err, "can't connect to DB")
 	}
-	
+
 	return db, nil
 }
+
+// retry calls fn until it succeeds or ctx is done, doubling the pause up to maxDelay.
+// The last error of fn is returned.
+func retry(ctx context.Context, delay, maxDelay time.Duration, fn func(context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		slog.Warn("DB is not available yet, retrying",
+			slog.Int("attempt", attempt), slog.Duration("delay", delay), slog.Any("err", err))
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+
+		delay = min(delay*2, maxDelay)
+	}
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetry_SucceedsAfterFailures(t *testing.T) {
+	calls := 0
+	err := retry(context.Background(), time.Millisecond, 4*time.Millisecond, func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return errors.New("starting up")
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRetry_GivesUpOnDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	calls := 0
+	err := retry(ctx, time.Millisecond, 5*time.Millisecond, func(context.Context) error {
+		calls++
+		return errors.New("connection refused")
+	})
+
+	assert.EqualError(t, err, "connection refused")
+	assert.Greater(t, calls, 1)
+}
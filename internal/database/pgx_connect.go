@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"trainee-pvz/config"
+)
+
+// ConnectPool opens a pgx pool with the same limits and startup retry as ConnectDB. Queries use
+// the extended protocol with per-connection prepared statement caching unless
+// cfg.StatementCacheCapacity is 0, then every statement is described before it runs.
+func ConnectPool(ctx context.Context, connString string, cfg config.DbCfg) (*pgxpool.Pool, error) {
+	poolCfg, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't parse DB connection string")
+	}
+
+	poolCfg.MaxConns = int32(cfg.MaxOpenConns)
+	poolCfg.MaxConnLifetime = time.Duration(cfg.ConnMaxLifetime) * time.Millisecond
+	poolCfg.MaxConnIdleTime = time.Duration(cfg.ConnMaxIdleTime) * time.Millisecond
+	poolCfg.ConnConfig.Tracer = pgxTracer{tracer: otel.Tracer("trainee-pvz/internal/database")}
+	poolCfg.ConnConfig.StatementCacheCapacity = cfg.StatementCacheCapacity
+	poolCfg.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeCacheStatement
+	if cfg.StatementCacheCapacity == 0 {
+		poolCfg.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeDescribeExec
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't open DB")
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, time.Duration(cfg.ConnectTimeout)*time.Millisecond)
+	defer cancel()
+
+	err = retry(pingCtx, initialRetryDelay, maxRetryDelay, pool.Ping)
+	if err != nil {
+		pool.Close()
+		return nil, errors.Wrap(err, "can't connect to DB")
+	}
+
+	return pool, nil
+}
+
+// pgxTracer gives queries, batches and copies a span under the caller's one, like otelsql
+// does for the sqlx repositories.
+type pgxTracer struct {
+	tracer trace.Tracer
+}
+
+func (t pgxTracer) start(ctx context.Context, name, statement string) context.Context {
+	attrs := []attribute.KeyValue{semconv.DBSystemPostgreSQL}
+	if statement != "" {
+		attrs = append(attrs, semconv.DBQueryText(statement))
+	}
+	ctx, _ = t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return ctx
+}
+
+func end(ctx context.Context, err error) {
+	span := trace.SpanFromContext(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (t pgxTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return t.start(ctx, "pgx.query", data.SQL)
+}
+
+func (t pgxTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	end(ctx, data.Err)
+}
+
+func (t pgxTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceBatchStartData) context.Context {
+	return t.start(ctx, "pgx.batch", "")
+}
+
+func (t pgxTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	trace.SpanFromContext(ctx).AddEvent("query", trace.WithAttributes(semconv.DBQueryText(data.SQL)))
+}
+
+func (t pgxTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	end(ctx, data.Err)
+}
+
+func (t pgxTracer) TraceCopyFromStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	return t.start(ctx, "pgx.copy "+data.TableName.Sanitize(), "")
+}
+
+func (t pgxTracer) TraceCopyFromEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	end(ctx, data.Err)
+}
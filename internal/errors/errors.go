@@ -1,14 +1,147 @@
 package errors
 
 import (
-	"github.com/pkg/errors"
+	stderrors "errors"
+)
+
+// Code identifies an error for clients. Codes are part of the API and never change, unlike
+// messages.
+type Code string
+
+// Kind is the class of an error, the transports map it to their status codes.
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindInvalid
+	KindUnauthenticated
+	KindForbidden
+	KindNotFound
+	KindConflict // the entity already exists
+	KindFailedPrecondition
+	KindTooLarge
+)
+
+// Error is a domain error. Errors with the same code match with errors.Is, so wrapped errors
+// and ones built by Invalid still compare equal to the sentinels below.
+type Error struct {
+	Code    Code
+	Kind    Kind
+	Message string
+	Fields  []FieldError // what is wrong with each field of an invalid request
+}
+
+// FieldError describes an invalid field, Field is the dotted path to it in the request body.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func New(kind Kind, code Code, message string) *Error {
+	return &Error{Code: code, Kind: kind, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Invalid reports a malformed request with the message for the client.
+func Invalid(message string) *Error {
+	return New(KindInvalid, CodeInvalidRequest, message)
+}
+
+// InvalidFields reports a request with invalid fields.
+func InvalidFields(fields []FieldError) *Error {
+	e := Invalid("request validation failed")
+	e.Fields = fields
+	return e
+}
+
+// From returns the domain error in the chain of err, nil for other errors.
+func From(err error) *Error {
+	var e *Error
+	if stderrors.As(err, &e) {
+		return e
+	}
+	return nil
+}
+
+// KindOf returns the kind of err, KindInternal when it's not a domain error.
+func KindOf(err error) Kind {
+	if e := From(err); e != nil {
+		return e.Kind
+	}
+	return KindInternal
+}
+
+const (
+	CodeInternal           Code = "internal"
+	CodeInvalidRequest     Code = "invalid_request"
+	CodeRequestTooLarge    Code = "request_too_large"
+	CodeMissingToken       Code = "missing_token"
+	CodeInvalidToken       Code = "invalid_token"
+	CodeInvalidCredentials Code = "invalid_credentials"
+	CodeForbidden          Code = "forbidden"
+
+	CodeUserAlreadyExists      Code = "user_already_exists"
+	CodeUnsupportedCity        Code = "unsupported_city"
+	CodeReceptionAlreadyExists Code = "reception_already_exists"
+	CodeNoOpenReception        Code = "no_open_reception"
+	CodeNoProducts             Code = "no_products"
+	CodePVZNotFound            Code = "pvz_not_found"
+	CodePVZArchived            Code = "pvz_archived"
+	CodePVZNotArchived         Code = "pvz_not_archived"
+	CodePVZHasOpenReception    Code = "pvz_has_open_reception"
+	CodeInvalidCoordinates     Code = "invalid_coordinates"
+	CodeInvalidWorkingHours    Code = "invalid_working_hours"
+	CodeInvalidCapacity        Code = "invalid_capacity"
+	CodeInvalidRadius          Code = "invalid_radius"
+	CodePVZOverCapacity        Code = "pvz_over_capacity"
+	CodeProductNotFound        Code = "product_not_found"
+	CodeProductAlreadyIssued   Code = "product_already_issued"
+	CodeReceptionNotClosed     Code = "reception_not_closed"
+	CodeInvalidCursor          Code = "invalid_cursor"
+	CodeInvalidLimit           Code = "invalid_limit"
+	CodeInvalidReportQuery     Code = "invalid_report_query"
+	CodeInvalidExportQuery     Code = "invalid_export_query"
+	CodeInvalidImport          Code = "invalid_import"
+	CodeExternalCodeExists     Code = "external_code_exists"
 )
 
 var (
-	ErrUserAlreadyExists      = errors.New("user already exists")
-	ErrUnsupportedCity        = errors.New("unsupported city")
-	ErrReceptionAlreadyExists = errors.New("reception already exists")
-	ErrNoOpenReception        = errors.New("no open reception for pvz")
-	ErrNoProducts             = errors.New("no found any product")
-	ErrNoPVZ                  = errors.New("no found any PVZ")
+	ErrInvalidRequest     = Invalid("invalid request")
+	ErrRequestTooLarge    = New(KindTooLarge, CodeRequestTooLarge, "request body is too large")
+	ErrMissingToken       = New(KindUnauthenticated, CodeMissingToken, "missing token")
+	ErrInvalidToken       = New(KindUnauthenticated, CodeInvalidToken, "invalid token")
+	ErrInvalidCredentials = New(KindUnauthenticated, CodeInvalidCredentials, "invalid credentials")
+	ErrForbidden          = New(KindForbidden, CodeForbidden, "forbidden")
+
+	ErrUserAlreadyExists      = New(KindConflict, CodeUserAlreadyExists, "user already exists")
+	ErrUnsupportedCity        = New(KindInvalid, CodeUnsupportedCity, "unsupported city")
+	ErrReceptionAlreadyExists = New(KindConflict, CodeReceptionAlreadyExists, "reception already exists")
+	ErrNoOpenReception        = New(KindFailedPrecondition, CodeNoOpenReception, "no open reception for pvz")
+	ErrNoProducts             = New(KindFailedPrecondition, CodeNoProducts, "no products to delete")
+	ErrNoPVZ                  = New(KindNotFound, CodePVZNotFound, "pvz not found")
+	ErrPVZArchived            = New(KindFailedPrecondition, CodePVZArchived, "pvz is archived")
+	ErrPVZNotArchived         = New(KindFailedPrecondition, CodePVZNotArchived, "pvz is not archived")
+	ErrPVZHasOpenReception    = New(KindFailedPrecondition, CodePVZHasOpenReception, "pvz has open reception")
+	ErrInvalidCoordinates     = New(KindInvalid, CodeInvalidCoordinates, "invalid coordinates")
+	ErrInvalidWorkingHours    = New(KindInvalid, CodeInvalidWorkingHours, "invalid working hours")
+	ErrInvalidCapacity        = New(KindInvalid, CodeInvalidCapacity, "invalid capacity")
+	ErrInvalidRadius          = New(KindInvalid, CodeInvalidRadius, "invalid search radius")
+	ErrPVZOverCapacity        = New(KindFailedPrecondition, CodePVZOverCapacity, "pvz is over capacity")
+	ErrProductNotFound        = New(KindNotFound, CodeProductNotFound, "product not found")
+	ErrProductAlreadyIssued   = New(KindFailedPrecondition, CodeProductAlreadyIssued, "product already issued")
+	ErrReceptionNotClosed     = New(KindFailedPrecondition, CodeReceptionNotClosed, "reception is not closed")
+	ErrInvalidCursor          = New(KindInvalid, CodeInvalidCursor, "invalid cursor")
+	ErrInvalidLimit           = New(KindInvalid, CodeInvalidLimit, "invalid limit")
+	ErrInvalidReportQuery     = New(KindInvalid, CodeInvalidReportQuery, "invalid report query")
+	ErrInvalidExportQuery     = New(KindInvalid, CodeInvalidExportQuery, "invalid export query")
+	ErrInvalidImport          = New(KindInvalid, CodeInvalidImport, "invalid import file")
+	ErrExternalCodeExists     = New(KindConflict, CodeExternalCodeExists, "pvz with this external code already exists")
 )
@@ -0,0 +1,82 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"time"
+
+	"trainee-pvz/internal/models"
+)
+
+// utf8BOM makes Excel detect the encoding, otherwise Cyrillic city names are garbled.
+const utf8BOM = "\uFEFF"
+
+// csvWriter writes the BOM and the rows through one buffer, csv.Writer reuses it, so until
+// the buffer fills up the output can still be dropped.
+type csvWriter struct {
+	out           *bufio.Writer
+	csv           *csv.Writer
+	headerWritten bool
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	out := bufio.NewWriter(w)
+	return &csvWriter{out: out, csv: csv.NewWriter(out)}
+}
+
+func (c *csvWriter) Write(row models.ExportRow) error {
+	err := c.writeHeader()
+	if err != nil {
+		return err
+	}
+
+	// the buffer is flushed when it is full, rows are not accumulated
+	return c.csv.Write([]string{
+		row.ReceptionID,
+		formatTime(&row.ReceptionDateTime),
+		row.ReceptionStatus,
+		formatTime(row.ReceptionClosedAt),
+		row.City,
+		deref(row.EmployeeEmail),
+		deref(row.ProductID),
+		deref(row.ProductType),
+		formatTime(row.ProductDateTime),
+		formatTime(row.ProductIssuedAt),
+	})
+}
+
+func (c *csvWriter) Close() error {
+	err := c.writeHeader()
+	if err != nil {
+		return err
+	}
+
+	c.csv.Flush()
+	return c.csv.Error()
+}
+
+// Abort leaves the buffered rows unflushed.
+func (c *csvWriter) Abort() {}
+
+func (c *csvWriter) writeHeader() error {
+	if c.headerWritten {
+		return nil
+	}
+	c.headerWritten = true
+
+	_, err := io.WriteString(c.out, utf8BOM)
+	if err != nil {
+		return err
+	}
+
+	return c.csv.Write(header)
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,62 @@
+// Package export encodes reception export rows into spreadsheet formats.
+package export
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/models"
+)
+
+var header = []string{
+	"reception_id",
+	"reception_datetime",
+	"reception_status",
+	"reception_closed_at",
+	"city",
+	"employee",
+	"product_id",
+	"product_type",
+	"product_datetime",
+	"product_issued_at",
+}
+
+// Writer encodes rows to the underlying writer. Nothing reaches it before the first row is
+// written, Close flushes buffered data and Abort drops it when the rows couldn't be read.
+type Writer interface {
+	Write(row models.ExportRow) error
+	Close() error
+	Abort()
+}
+
+// NewWriter returns a writer for the format. On Close the header row is written even when there
+// are no rows.
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case models.ExportCSV:
+		return newCSVWriter(w), nil
+	case models.ExportXLSX:
+		return newXLSXWriter(w)
+	default:
+		return nil, errors.Wrapf(er.ErrInvalidExportQuery, "unknown format %q", format)
+	}
+}
+
+// ContentType returns the MIME type of the format.
+func ContentType(format string) string {
+	if format == models.ExportXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+
+	return "text/csv; charset=utf-8"
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
@@ -0,0 +1,114 @@
+package export
+
+import (
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/xuri/excelize/v2"
+
+	"trainee-pvz/internal/models"
+)
+
+const sheetName = "Sheet1"
+
+// xlsxWriter uses the excelize stream writer, which spills rows to a temporary file
+// instead of keeping the whole sheet in memory. The archive can only be assembled
+// after the last row, so the output is written on Close. The file is created with the
+// first row, an aborted export leaves nothing behind.
+type xlsxWriter struct {
+	out    io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	rowNum int
+}
+
+func newXLSXWriter(w io.Writer) (*xlsxWriter, error) {
+	return &xlsxWriter{out: w}, nil
+}
+
+func (x *xlsxWriter) start() error {
+	if x.file != nil {
+		return nil
+	}
+
+	file := excelize.NewFile()
+	stream, err := file.NewStreamWriter(sheetName)
+	if err != nil {
+		file.Close()
+		return errors.Wrap(err, "can't create xlsx stream")
+	}
+	x.file, x.stream = file, stream
+
+	values := make([]interface{}, 0, len(header))
+	for _, h := range header {
+		values = append(values, h)
+	}
+
+	return x.setRow(values)
+}
+
+func (x *xlsxWriter) Write(row models.ExportRow) error {
+	err := x.start()
+	if err != nil {
+		return err
+	}
+
+	return x.setRow([]interface{}{
+		row.ReceptionID,
+		row.ReceptionDateTime.UTC(),
+		row.ReceptionStatus,
+		timeCell(row.ReceptionClosedAt),
+		row.City,
+		deref(row.EmployeeEmail),
+		deref(row.ProductID),
+		deref(row.ProductType),
+		timeCell(row.ProductDateTime),
+		timeCell(row.ProductIssuedAt),
+	})
+}
+
+func (x *xlsxWriter) Close() error {
+	err := x.start()
+	if err != nil {
+		x.Abort()
+		return err
+	}
+	defer x.file.Close()
+
+	err = x.stream.Flush()
+	if err != nil {
+		return errors.Wrap(err, "can't flush xlsx stream")
+	}
+
+	_, err = x.file.WriteTo(x.out)
+	if err != nil {
+		return errors.Wrap(err, "can't write xlsx")
+	}
+
+	return nil
+}
+
+func (x *xlsxWriter) Abort() {
+	if x.file != nil {
+		x.file.Close()
+	}
+}
+
+func (x *xlsxWriter) setRow(values []interface{}) error {
+	x.rowNum++
+	cell, err := excelize.CoordinatesToCellName(1, x.rowNum)
+	if err != nil {
+		return errors.Wrap(err, "can't get xlsx cell")
+	}
+
+	return x.stream.SetRow(cell, values)
+}
+
+func timeCell(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+
+	return t.UTC()
+}
@@ -0,0 +1,39 @@
+package pvz_proto
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"trainee-pvz/internal/models"
+)
+
+type ReadinessChecker interface {
+	Readiness(ctx context.Context) models.Readiness
+}
+
+// WatchHealth keeps the overall status of the standard gRPC health service in sync with the
+// readiness checks. When ctx is done every service is switched to NOT_SERVING for good.
+func WatchHealth(ctx context.Context, checker ReadinessChecker, hs *health.Server, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, interval)
+		status := healthpb.HealthCheckResponse_NOT_SERVING
+		if checker.Readiness(checkCtx).Ready {
+			status = healthpb.HealthCheckResponse_SERVING
+		}
+		cancel()
+		hs.SetServingStatus("", status)
+
+		select {
+		case <-ctx.Done():
+			hs.Shutdown()
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package pvz_proto
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"trainee-pvz/internal/auth"
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/logger"
+	"trainee-pvz/internal/problem"
+	"trainee-pvz/internal/requestid"
+)
+
+const (
+	requestIDKey     = "x-request-id"
+	authorizationKey = "authorization"
+)
+
+// methodRoles lists the calls that need a token and the role they need; the rest are public.
+var methodRoles = map[string]string{
+	PVZService_GetIntakeStats_FullMethodName: "moderator",
+}
+
+type TokenParser interface {
+	Parse(token string) (*auth.Claims, error)
+}
+
+// AuthInterceptor checks the bearer token in authorization metadata for the calls in
+// methodRoles, the same way RequireAuth and RequireRole do over HTTP.
+func AuthInterceptor(tokens TokenParser) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		role, ok := methodRoles[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		var header string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(authorizationKey); len(values) > 0 {
+				header = values[0]
+			}
+		}
+		if !strings.HasPrefix(header, "Bearer ") {
+			return nil, er.ErrMissingToken
+		}
+
+		claims, err := tokens.Parse(strings.TrimPrefix(header, "Bearer "))
+		if err != nil {
+			return nil, er.ErrInvalidToken
+		}
+		if claims.Role != role {
+			return nil, er.ErrForbidden
+		}
+
+		ctx = logger.With(ctx, slog.String("user_id", claims.UserID), slog.String("role", claims.Role))
+		return handler(ctx, req)
+	}
+}
+
+// LoggingInterceptor gives every call a request ID (from x-request-id metadata or a new one),
+// returns it in the response header and logs the call with it.
+func LoggingInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDKey); len(values) > 0 {
+			id = values[0]
+		}
+	}
+	id = requestid.Accept(id)
+
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, id))
+	ctx = logger.With(ctx, slog.String("request_id", id), slog.String("grpc_method", info.FullMethod))
+
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	attrs := []any{slog.Duration("duration", time.Since(start))}
+	if err != nil {
+		attrs = append(attrs, slog.String("code", problem.GRPCStatus(err).Code().String()))
+		logger.FromContext(ctx).Error("gRPC call failed", append(attrs, slog.Any("err", err))...)
+	} else {
+		logger.FromContext(ctx).Info("gRPC call", append(attrs, slog.String("code", codes.OK.String()))...)
+	}
+
+	return resp, err
+}
+
+// ErrorInterceptor turns errors of the handlers into statuses with the error code in the
+// details. It runs inside the metrics interceptor and outside the logging one, so metrics count
+// the final code and the log keeps the original error.
+func ErrorInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return resp, problem.GRPCStatus(err).Err()
+	}
+	return resp, nil
+}
+
+type callMetrics interface {
+	SaveGRPCCall(timeSince time.Time, method, code string)
+}
+
+// MetricsInterceptor records the duration and status code of every unary call.
+func MetricsInterceptor(m callMetrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		m.SaveGRPCCall(start, info.FullMethod, status.Code(err).String())
+		return resp, err
+	}
+}
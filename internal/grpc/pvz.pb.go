@@ -73,8 +73,17 @@ type PVZ struct {
 	Id               string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
 	RegistrationDate *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=registration_date,json=registrationDate,proto3" json:"registration_date,omitempty"`
 	City             string                 `protobuf:"bytes,3,opt,name=city,proto3" json:"city,omitempty"`
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+	ArchivedAt       *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=archived_at,json=archivedAt,proto3" json:"archived_at,omitempty"`
+	Address          string                 `protobuf:"bytes,5,opt,name=address,proto3" json:"address,omitempty"`
+	Latitude         *float64               `protobuf:"fixed64,6,opt,name=latitude,proto3,oneof" json:"latitude,omitempty"`
+	Longitude        *float64               `protobuf:"fixed64,7,opt,name=longitude,proto3,oneof" json:"longitude,omitempty"`
+	// Weekday key ("mon".."sun") to opening hours, a missing day is a day off.
+	WorkingHours  map[string]*DayHours `protobuf:"bytes,8,rep,name=working_hours,json=workingHours,proto3" json:"working_hours,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Capacity      *int32               `protobuf:"varint,9,opt,name=capacity,proto3,oneof" json:"capacity,omitempty"`
+	StoredItems   int32                `protobuf:"varint,10,opt,name=stored_items,json=storedItems,proto3" json:"stored_items,omitempty"`
+	ExternalCode  *string              `protobuf:"bytes,11,opt,name=external_code,json=externalCode,proto3,oneof" json:"external_code,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *PVZ) Reset() {
@@ -128,15 +137,129 @@ func (x *PVZ) GetCity() string {
 	return ""
 }
 
-type GetPVZListRequest struct {
+func (x *PVZ) GetArchivedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ArchivedAt
+	}
+	return nil
+}
+
+func (x *PVZ) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *PVZ) GetLatitude() float64 {
+	if x != nil && x.Latitude != nil {
+		return *x.Latitude
+	}
+	return 0
+}
+
+func (x *PVZ) GetLongitude() float64 {
+	if x != nil && x.Longitude != nil {
+		return *x.Longitude
+	}
+	return 0
+}
+
+func (x *PVZ) GetWorkingHours() map[string]*DayHours {
+	if x != nil {
+		return x.WorkingHours
+	}
+	return nil
+}
+
+func (x *PVZ) GetCapacity() int32 {
+	if x != nil && x.Capacity != nil {
+		return *x.Capacity
+	}
+	return 0
+}
+
+func (x *PVZ) GetStoredItems() int32 {
+	if x != nil {
+		return x.StoredItems
+	}
+	return 0
+}
+
+func (x *PVZ) GetExternalCode() string {
+	if x != nil && x.ExternalCode != nil {
+		return *x.ExternalCode
+	}
+	return ""
+}
+
+type DayHours struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	Open          string                 `protobuf:"bytes,1,opt,name=open,proto3" json:"open,omitempty"`
+	Close         string                 `protobuf:"bytes,2,opt,name=close,proto3" json:"close,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DayHours) Reset() {
+	*x = DayHours{}
+	mi := &file_pvz_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DayHours) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DayHours) ProtoMessage() {}
+
+func (x *DayHours) ProtoReflect() protoreflect.Message {
+	mi := &file_pvz_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DayHours.ProtoReflect.Descriptor instead.
+func (*DayHours) Descriptor() ([]byte, []int) {
+	return file_pvz_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *DayHours) GetOpen() string {
+	if x != nil {
+		return x.Open
+	}
+	return ""
+}
+
+func (x *DayHours) GetClose() string {
+	if x != nil {
+		return x.Close
+	}
+	return ""
+}
+
+type GetPVZListRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	IncludeArchived bool                   `protobuf:"varint,1,opt,name=include_archived,json=includeArchived,proto3" json:"include_archived,omitempty"`
+	// Opaque next_cursor from the previous page, empty for the first page.
+	Cursor string `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	// Page size, server default when not set. A call without cursor returns the first page
+	// only, not every PVZ as before cursors: follow next_cursor to read them all.
+	Limit         int32 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *GetPVZListRequest) Reset() {
 	*x = GetPVZListRequest{}
-	mi := &file_pvz_proto_msgTypes[1]
+	mi := &file_pvz_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -148,7 +271,7 @@ func (x *GetPVZListRequest) String() string {
 func (*GetPVZListRequest) ProtoMessage() {}
 
 func (x *GetPVZListRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_pvz_proto_msgTypes[1]
+	mi := &file_pvz_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -161,19 +284,42 @@ func (x *GetPVZListRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetPVZListRequest.ProtoReflect.Descriptor instead.
 func (*GetPVZListRequest) Descriptor() ([]byte, []int) {
-	return file_pvz_proto_rawDescGZIP(), []int{1}
+	return file_pvz_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetPVZListRequest) GetIncludeArchived() bool {
+	if x != nil {
+		return x.IncludeArchived
+	}
+	return false
+}
+
+func (x *GetPVZListRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *GetPVZListRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
 }
 
 type GetPVZListResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Pvzs          []*PVZ                 `protobuf:"bytes,1,rep,name=pvzs,proto3" json:"pvzs,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Pvzs  []*PVZ                 `protobuf:"bytes,1,rep,name=pvzs,proto3" json:"pvzs,omitempty"`
+	// Empty on the last page.
+	NextCursor    string `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *GetPVZListResponse) Reset() {
 	*x = GetPVZListResponse{}
-	mi := &file_pvz_proto_msgTypes[2]
+	mi := &file_pvz_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -185,7 +331,7 @@ func (x *GetPVZListResponse) String() string {
 func (*GetPVZListResponse) ProtoMessage() {}
 
 func (x *GetPVZListResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_pvz_proto_msgTypes[2]
+	mi := &file_pvz_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -198,7 +344,7 @@ func (x *GetPVZListResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetPVZListResponse.ProtoReflect.Descriptor instead.
 func (*GetPVZListResponse) Descriptor() ([]byte, []int) {
-	return file_pvz_proto_rawDescGZIP(), []int{2}
+	return file_pvz_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *GetPVZListResponse) GetPvzs() []*PVZ {
@@ -208,25 +354,475 @@ func (x *GetPVZListResponse) GetPvzs() []*PVZ {
 	return nil
 }
 
+func (x *GetPVZListResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+type GetNearbyPVZRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Latitude  float64                `protobuf:"fixed64,1,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude float64                `protobuf:"fixed64,2,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	// Search radius, 10 km when not set.
+	RadiusKm float64 `protobuf:"fixed64,3,opt,name=radius_km,json=radiusKm,proto3" json:"radius_km,omitempty"`
+	// Maximum number of results, server default when not set.
+	Limit         int32 `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetNearbyPVZRequest) Reset() {
+	*x = GetNearbyPVZRequest{}
+	mi := &file_pvz_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetNearbyPVZRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetNearbyPVZRequest) ProtoMessage() {}
+
+func (x *GetNearbyPVZRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pvz_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetNearbyPVZRequest.ProtoReflect.Descriptor instead.
+func (*GetNearbyPVZRequest) Descriptor() ([]byte, []int) {
+	return file_pvz_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetNearbyPVZRequest) GetLatitude() float64 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *GetNearbyPVZRequest) GetLongitude() float64 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *GetNearbyPVZRequest) GetRadiusKm() float64 {
+	if x != nil {
+		return x.RadiusKm
+	}
+	return 0
+}
+
+func (x *GetNearbyPVZRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type NearbyPVZ struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pvz           *PVZ                   `protobuf:"bytes,1,opt,name=pvz,proto3" json:"pvz,omitempty"`
+	DistanceKm    float64                `protobuf:"fixed64,2,opt,name=distance_km,json=distanceKm,proto3" json:"distance_km,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NearbyPVZ) Reset() {
+	*x = NearbyPVZ{}
+	mi := &file_pvz_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NearbyPVZ) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NearbyPVZ) ProtoMessage() {}
+
+func (x *NearbyPVZ) ProtoReflect() protoreflect.Message {
+	mi := &file_pvz_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NearbyPVZ.ProtoReflect.Descriptor instead.
+func (*NearbyPVZ) Descriptor() ([]byte, []int) {
+	return file_pvz_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *NearbyPVZ) GetPvz() *PVZ {
+	if x != nil {
+		return x.Pvz
+	}
+	return nil
+}
+
+func (x *NearbyPVZ) GetDistanceKm() float64 {
+	if x != nil {
+		return x.DistanceKm
+	}
+	return 0
+}
+
+type GetNearbyPVZResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pvzs          []*NearbyPVZ           `protobuf:"bytes,1,rep,name=pvzs,proto3" json:"pvzs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetNearbyPVZResponse) Reset() {
+	*x = GetNearbyPVZResponse{}
+	mi := &file_pvz_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetNearbyPVZResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetNearbyPVZResponse) ProtoMessage() {}
+
+func (x *GetNearbyPVZResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pvz_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetNearbyPVZResponse.ProtoReflect.Descriptor instead.
+func (*GetNearbyPVZResponse) Descriptor() ([]byte, []int) {
+	return file_pvz_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetNearbyPVZResponse) GetPvzs() []*NearbyPVZ {
+	if x != nil {
+		return x.Pvzs
+	}
+	return nil
+}
+
+type GetIntakeStatsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Period start (inclusive), a week before "to" when not set.
+	From *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	// Period end (exclusive), now when not set.
+	To *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	// One of day, week, month; day when not set.
+	Bucket string `protobuf:"bytes,3,opt,name=bucket,proto3" json:"bucket,omitempty"`
+	// Any of pvz, city, type.
+	GroupBy       []string `protobuf:"bytes,4,rep,name=group_by,json=groupBy,proto3" json:"group_by,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetIntakeStatsRequest) Reset() {
+	*x = GetIntakeStatsRequest{}
+	mi := &file_pvz_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetIntakeStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetIntakeStatsRequest) ProtoMessage() {}
+
+func (x *GetIntakeStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pvz_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetIntakeStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetIntakeStatsRequest) Descriptor() ([]byte, []int) {
+	return file_pvz_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetIntakeStatsRequest) GetFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *GetIntakeStatsRequest) GetTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+func (x *GetIntakeStatsRequest) GetBucket() string {
+	if x != nil {
+		return x.Bucket
+	}
+	return ""
+}
+
+func (x *GetIntakeStatsRequest) GetGroupBy() []string {
+	if x != nil {
+		return x.GroupBy
+	}
+	return nil
+}
+
+type IntakeRow struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Bucket *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=bucket,proto3" json:"bucket,omitempty"`
+	// Dimensions are set only when requested in group_by.
+	PvzId         *string `protobuf:"bytes,2,opt,name=pvz_id,json=pvzId,proto3,oneof" json:"pvz_id,omitempty"`
+	City          *string `protobuf:"bytes,3,opt,name=city,proto3,oneof" json:"city,omitempty"`
+	Type          *string `protobuf:"bytes,4,opt,name=type,proto3,oneof" json:"type,omitempty"`
+	Products      int64   `protobuf:"varint,5,opt,name=products,proto3" json:"products,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IntakeRow) Reset() {
+	*x = IntakeRow{}
+	mi := &file_pvz_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IntakeRow) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IntakeRow) ProtoMessage() {}
+
+func (x *IntakeRow) ProtoReflect() protoreflect.Message {
+	mi := &file_pvz_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IntakeRow.ProtoReflect.Descriptor instead.
+func (*IntakeRow) Descriptor() ([]byte, []int) {
+	return file_pvz_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *IntakeRow) GetBucket() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Bucket
+	}
+	return nil
+}
+
+func (x *IntakeRow) GetPvzId() string {
+	if x != nil && x.PvzId != nil {
+		return *x.PvzId
+	}
+	return ""
+}
+
+func (x *IntakeRow) GetCity() string {
+	if x != nil && x.City != nil {
+		return *x.City
+	}
+	return ""
+}
+
+func (x *IntakeRow) GetType() string {
+	if x != nil && x.Type != nil {
+		return *x.Type
+	}
+	return ""
+}
+
+func (x *IntakeRow) GetProducts() int64 {
+	if x != nil {
+		return x.Products
+	}
+	return 0
+}
+
+type GetIntakeStatsResponse struct {
+	state                       protoimpl.MessageState `protogen:"open.v1"`
+	Rows                        []*IntakeRow           `protobuf:"bytes,1,rep,name=rows,proto3" json:"rows,omitempty"`
+	Receptions                  int64                  `protobuf:"varint,2,opt,name=receptions,proto3" json:"receptions,omitempty"`
+	AvgReceptionDurationSeconds float64                `protobuf:"fixed64,3,opt,name=avg_reception_duration_seconds,json=avgReceptionDurationSeconds,proto3" json:"avg_reception_duration_seconds,omitempty"`
+	AvgProductsPerReception     float64                `protobuf:"fixed64,4,opt,name=avg_products_per_reception,json=avgProductsPerReception,proto3" json:"avg_products_per_reception,omitempty"`
+	unknownFields               protoimpl.UnknownFields
+	sizeCache                   protoimpl.SizeCache
+}
+
+func (x *GetIntakeStatsResponse) Reset() {
+	*x = GetIntakeStatsResponse{}
+	mi := &file_pvz_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetIntakeStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetIntakeStatsResponse) ProtoMessage() {}
+
+func (x *GetIntakeStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pvz_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetIntakeStatsResponse.ProtoReflect.Descriptor instead.
+func (*GetIntakeStatsResponse) Descriptor() ([]byte, []int) {
+	return file_pvz_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetIntakeStatsResponse) GetRows() []*IntakeRow {
+	if x != nil {
+		return x.Rows
+	}
+	return nil
+}
+
+func (x *GetIntakeStatsResponse) GetReceptions() int64 {
+	if x != nil {
+		return x.Receptions
+	}
+	return 0
+}
+
+func (x *GetIntakeStatsResponse) GetAvgReceptionDurationSeconds() float64 {
+	if x != nil {
+		return x.AvgReceptionDurationSeconds
+	}
+	return 0
+}
+
+func (x *GetIntakeStatsResponse) GetAvgProductsPerReception() float64 {
+	if x != nil {
+		return x.AvgProductsPerReception
+	}
+	return 0
+}
+
 var File_pvz_proto protoreflect.FileDescriptor
 
 const file_pvz_proto_rawDesc = "" +
 	"\n" +
-	"\tpvz.proto\x12\x06pvz.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"r\n" +
+	"\tpvz.proto\x12\x06pvz.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xcc\x04\n" +
 	"\x03PVZ\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12G\n" +
 	"\x11registration_date\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x10registrationDate\x12\x12\n" +
-	"\x04city\x18\x03 \x01(\tR\x04city\"\x13\n" +
-	"\x11GetPVZListRequest\"5\n" +
+	"\x04city\x18\x03 \x01(\tR\x04city\x12;\n" +
+	"\varchived_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"archivedAt\x12\x18\n" +
+	"\aaddress\x18\x05 \x01(\tR\aaddress\x12\x1f\n" +
+	"\blatitude\x18\x06 \x01(\x01H\x00R\blatitude\x88\x01\x01\x12!\n" +
+	"\tlongitude\x18\a \x01(\x01H\x01R\tlongitude\x88\x01\x01\x12B\n" +
+	"\rworking_hours\x18\b \x03(\v2\x1d.pvz.v1.PVZ.WorkingHoursEntryR\fworkingHours\x12\x1f\n" +
+	"\bcapacity\x18\t \x01(\x05H\x02R\bcapacity\x88\x01\x01\x12!\n" +
+	"\fstored_items\x18\n" +
+	" \x01(\x05R\vstoredItems\x12(\n" +
+	"\rexternal_code\x18\v \x01(\tH\x03R\fexternalCode\x88\x01\x01\x1aQ\n" +
+	"\x11WorkingHoursEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12&\n" +
+	"\x05value\x18\x02 \x01(\v2\x10.pvz.v1.DayHoursR\x05value:\x028\x01B\v\n" +
+	"\t_latitudeB\f\n" +
+	"\n" +
+	"_longitudeB\v\n" +
+	"\t_capacityB\x10\n" +
+	"\x0e_external_code\"4\n" +
+	"\bDayHours\x12\x12\n" +
+	"\x04open\x18\x01 \x01(\tR\x04open\x12\x14\n" +
+	"\x05close\x18\x02 \x01(\tR\x05close\"l\n" +
+	"\x11GetPVZListRequest\x12)\n" +
+	"\x10include_archived\x18\x01 \x01(\bR\x0fincludeArchived\x12\x16\n" +
+	"\x06cursor\x18\x02 \x01(\tR\x06cursor\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\"V\n" +
 	"\x12GetPVZListResponse\x12\x1f\n" +
-	"\x04pvzs\x18\x01 \x03(\v2\v.pvz.v1.PVZR\x04pvzs*P\n" +
+	"\x04pvzs\x18\x01 \x03(\v2\v.pvz.v1.PVZR\x04pvzs\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\tR\n" +
+	"nextCursor\"\x82\x01\n" +
+	"\x13GetNearbyPVZRequest\x12\x1a\n" +
+	"\blatitude\x18\x01 \x01(\x01R\blatitude\x12\x1c\n" +
+	"\tlongitude\x18\x02 \x01(\x01R\tlongitude\x12\x1b\n" +
+	"\tradius_km\x18\x03 \x01(\x01R\bradiusKm\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x05R\x05limit\"K\n" +
+	"\tNearbyPVZ\x12\x1d\n" +
+	"\x03pvz\x18\x01 \x01(\v2\v.pvz.v1.PVZR\x03pvz\x12\x1f\n" +
+	"\vdistance_km\x18\x02 \x01(\x01R\n" +
+	"distanceKm\"=\n" +
+	"\x14GetNearbyPVZResponse\x12%\n" +
+	"\x04pvzs\x18\x01 \x03(\v2\x11.pvz.v1.NearbyPVZR\x04pvzs\"\xa6\x01\n" +
+	"\x15GetIntakeStatsRequest\x12.\n" +
+	"\x04from\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04from\x12*\n" +
+	"\x02to\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02to\x12\x16\n" +
+	"\x06bucket\x18\x03 \x01(\tR\x06bucket\x12\x19\n" +
+	"\bgroup_by\x18\x04 \x03(\tR\agroupBy\"\xc6\x01\n" +
+	"\tIntakeRow\x122\n" +
+	"\x06bucket\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x06bucket\x12\x1a\n" +
+	"\x06pvz_id\x18\x02 \x01(\tH\x00R\x05pvzId\x88\x01\x01\x12\x17\n" +
+	"\x04city\x18\x03 \x01(\tH\x01R\x04city\x88\x01\x01\x12\x17\n" +
+	"\x04type\x18\x04 \x01(\tH\x02R\x04type\x88\x01\x01\x12\x1a\n" +
+	"\bproducts\x18\x05 \x01(\x03R\bproductsB\t\n" +
+	"\a_pvz_idB\a\n" +
+	"\x05_cityB\a\n" +
+	"\x05_type\"\xe1\x01\n" +
+	"\x16GetIntakeStatsResponse\x12%\n" +
+	"\x04rows\x18\x01 \x03(\v2\x11.pvz.v1.IntakeRowR\x04rows\x12\x1e\n" +
+	"\n" +
+	"receptions\x18\x02 \x01(\x03R\n" +
+	"receptions\x12C\n" +
+	"\x1eavg_reception_duration_seconds\x18\x03 \x01(\x01R\x1bavgReceptionDurationSeconds\x12;\n" +
+	"\x1aavg_products_per_reception\x18\x04 \x01(\x01R\x17avgProductsPerReception*P\n" +
 	"\x0fReceptionStatus\x12 \n" +
 	"\x1cRECEPTION_STATUS_IN_PROGRESS\x10\x00\x12\x1b\n" +
-	"\x17RECEPTION_STATUS_CLOSED\x10\x012Q\n" +
+	"\x17RECEPTION_STATUS_CLOSED\x10\x012\xed\x01\n" +
 	"\n" +
 	"PVZService\x12C\n" +
 	"\n" +
-	"GetPVZList\x12\x19.pvz.v1.GetPVZListRequest\x1a\x1a.pvz.v1.GetPVZListResponseB\x1bZ\x19./internal/grpc/pvz.protob\x06proto3"
+	"GetPVZList\x12\x19.pvz.v1.GetPVZListRequest\x1a\x1a.pvz.v1.GetPVZListResponse\x12I\n" +
+	"\fGetNearbyPVZ\x12\x1b.pvz.v1.GetNearbyPVZRequest\x1a\x1c.pvz.v1.GetNearbyPVZResponse\x12O\n" +
+	"\x0eGetIntakeStats\x12\x1d.pvz.v1.GetIntakeStatsRequest\x1a\x1e.pvz.v1.GetIntakeStatsResponseB\x1bZ\x19./internal/grpc/pvz.protob\x06proto3"
 
 var (
 	file_pvz_proto_rawDescOnce sync.Once
@@ -241,24 +837,45 @@ func file_pvz_proto_rawDescGZIP() []byte {
 }
 
 var file_pvz_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_pvz_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_pvz_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
 var file_pvz_proto_goTypes = []any{
-	(ReceptionStatus)(0),          // 0: pvz.v1.ReceptionStatus
-	(*PVZ)(nil),                   // 1: pvz.v1.PVZ
-	(*GetPVZListRequest)(nil),     // 2: pvz.v1.GetPVZListRequest
-	(*GetPVZListResponse)(nil),    // 3: pvz.v1.GetPVZListResponse
-	(*timestamppb.Timestamp)(nil), // 4: google.protobuf.Timestamp
+	(ReceptionStatus)(0),           // 0: pvz.v1.ReceptionStatus
+	(*PVZ)(nil),                    // 1: pvz.v1.PVZ
+	(*DayHours)(nil),               // 2: pvz.v1.DayHours
+	(*GetPVZListRequest)(nil),      // 3: pvz.v1.GetPVZListRequest
+	(*GetPVZListResponse)(nil),     // 4: pvz.v1.GetPVZListResponse
+	(*GetNearbyPVZRequest)(nil),    // 5: pvz.v1.GetNearbyPVZRequest
+	(*NearbyPVZ)(nil),              // 6: pvz.v1.NearbyPVZ
+	(*GetNearbyPVZResponse)(nil),   // 7: pvz.v1.GetNearbyPVZResponse
+	(*GetIntakeStatsRequest)(nil),  // 8: pvz.v1.GetIntakeStatsRequest
+	(*IntakeRow)(nil),              // 9: pvz.v1.IntakeRow
+	(*GetIntakeStatsResponse)(nil), // 10: pvz.v1.GetIntakeStatsResponse
+	nil,                            // 11: pvz.v1.PVZ.WorkingHoursEntry
+	(*timestamppb.Timestamp)(nil),  // 12: google.protobuf.Timestamp
 }
 var file_pvz_proto_depIdxs = []int32{
-	4, // 0: pvz.v1.PVZ.registration_date:type_name -> google.protobuf.Timestamp
-	1, // 1: pvz.v1.GetPVZListResponse.pvzs:type_name -> pvz.v1.PVZ
-	2, // 2: pvz.v1.PVZService.GetPVZList:input_type -> pvz.v1.GetPVZListRequest
-	3, // 3: pvz.v1.PVZService.GetPVZList:output_type -> pvz.v1.GetPVZListResponse
-	3, // [3:4] is the sub-list for method output_type
-	2, // [2:3] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	12, // 0: pvz.v1.PVZ.registration_date:type_name -> google.protobuf.Timestamp
+	12, // 1: pvz.v1.PVZ.archived_at:type_name -> google.protobuf.Timestamp
+	11, // 2: pvz.v1.PVZ.working_hours:type_name -> pvz.v1.PVZ.WorkingHoursEntry
+	1,  // 3: pvz.v1.GetPVZListResponse.pvzs:type_name -> pvz.v1.PVZ
+	1,  // 4: pvz.v1.NearbyPVZ.pvz:type_name -> pvz.v1.PVZ
+	6,  // 5: pvz.v1.GetNearbyPVZResponse.pvzs:type_name -> pvz.v1.NearbyPVZ
+	12, // 6: pvz.v1.GetIntakeStatsRequest.from:type_name -> google.protobuf.Timestamp
+	12, // 7: pvz.v1.GetIntakeStatsRequest.to:type_name -> google.protobuf.Timestamp
+	12, // 8: pvz.v1.IntakeRow.bucket:type_name -> google.protobuf.Timestamp
+	9,  // 9: pvz.v1.GetIntakeStatsResponse.rows:type_name -> pvz.v1.IntakeRow
+	2,  // 10: pvz.v1.PVZ.WorkingHoursEntry.value:type_name -> pvz.v1.DayHours
+	3,  // 11: pvz.v1.PVZService.GetPVZList:input_type -> pvz.v1.GetPVZListRequest
+	5,  // 12: pvz.v1.PVZService.GetNearbyPVZ:input_type -> pvz.v1.GetNearbyPVZRequest
+	8,  // 13: pvz.v1.PVZService.GetIntakeStats:input_type -> pvz.v1.GetIntakeStatsRequest
+	4,  // 14: pvz.v1.PVZService.GetPVZList:output_type -> pvz.v1.GetPVZListResponse
+	7,  // 15: pvz.v1.PVZService.GetNearbyPVZ:output_type -> pvz.v1.GetNearbyPVZResponse
+	10, // 16: pvz.v1.PVZService.GetIntakeStats:output_type -> pvz.v1.GetIntakeStatsResponse
+	14, // [14:17] is the sub-list for method output_type
+	11, // [11:14] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
 }
 
 func init() { file_pvz_proto_init() }
@@ -266,13 +883,15 @@ func file_pvz_proto_init() {
 	if File_pvz_proto != nil {
 		return
 	}
+	file_pvz_proto_msgTypes[0].OneofWrappers = []any{}
+	file_pvz_proto_msgTypes[8].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pvz_proto_rawDesc), len(file_pvz_proto_rawDesc)),
 			NumEnums:      1,
-			NumMessages:   3,
+			NumMessages:   11,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
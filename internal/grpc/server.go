@@ -2,53 +2,176 @@ package pvz_proto
 
 import (
 	"context"
-	"net"
+	"time"
 
-	"github.com/pkg/errors"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 
 	"google.golang.org/protobuf/types/known/timestamppb"
 
-	"trainee-pvz/internal/repository"
+	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/pagination"
 )
 
+const (
+	defaultNearbyRadiusKm = 10
+	defaultStatsPeriod    = 7 * 24 * time.Hour
+)
+
+type PVZService interface {
+	ListPVZ(ctx context.Context, filter models.PVZFilter, cursor string, limit int) (models.PVZPage, error)
+	NearbyPVZ(ctx context.Context, q models.GeoQuery) ([]models.NearbyPVZ, error)
+}
+
+type StatsService interface {
+	IntakeReport(ctx context.Context, q models.IntakeQuery) (models.IntakeReport, error)
+}
+
 type PVZGRPCServer struct {
 	UnimplementedPVZServiceServer
-	repo repository.PVZRepository
+	service PVZService
+	stats   StatsService
+	limits  func() pagination.Limits
 }
 
-func NewPVZGRPCServer(repo repository.PVZRepository) PVZServiceServer {
-	return &PVZGRPCServer{repo: repo}
+// NewPVZGRPCServer takes limits as a func, so reloaded pagination settings apply to new calls.
+func NewPVZGRPCServer(service PVZService, stats StatsService, limits func() pagination.Limits) PVZServiceServer {
+	return &PVZGRPCServer{service: service, stats: stats, limits: limits}
 }
 
 func (s *PVZGRPCServer) GetPVZList(ctx context.Context, req *GetPVZListRequest) (*GetPVZListResponse, error) {
-	data, err := s.repo.List(ctx, nil, nil, 1, 100)
+	limit, err := s.limits().Resolve(int(req.GetLimit()))
+	if err != nil {
+		return nil, err
+	}
+
+	filter := models.PVZFilter{IncludeArchived: req.GetIncludeArchived()}
+	page, err := s.service.ListPVZ(ctx, filter, req.GetCursor(), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := GetPVZListResponse{NextCursor: page.NextCursor}
+	for _, item := range page.Items {
+		resp.Pvzs = append(resp.Pvzs, toProtoPVZ(item))
+	}
+
+	return &resp, nil
+}
+
+func (s *PVZGRPCServer) GetNearbyPVZ(ctx context.Context, req *GetNearbyPVZRequest) (*GetNearbyPVZResponse, error) {
+	q := models.GeoQuery{
+		Latitude:  req.GetLatitude(),
+		Longitude: req.GetLongitude(),
+		RadiusKm:  req.GetRadiusKm(),
+		Limit:     int(req.GetLimit()),
+	}
+	if q.RadiusKm == 0 {
+		q.RadiusKm = defaultNearbyRadiusKm
+	}
+
+	var err error
+	q.Limit, err = s.limits().Resolve(q.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.service.NearbyPVZ(ctx, q)
 	if err != nil {
 		return nil, err
 	}
 
-	var resp GetPVZListResponse
+	var resp GetNearbyPVZResponse
 	for _, item := range data {
-		resp.Pvzs = append(resp.Pvzs, &PVZ{
-			Id:               item.ID,
-			City:             item.City,
-			RegistrationDate: timestamppb.New(item.RegistrationDate),
+		resp.Pvzs = append(resp.Pvzs, &NearbyPVZ{
+			Pvz:        toProtoPVZ(item.PVZ),
+			DistanceKm: item.DistanceKm,
 		})
 	}
 
 	return &resp, nil
 }
 
-func StartGRPCServer(repo *repository.PVZRepository, port string) error {
-	lis, err := net.Listen("tcp", port)
+func (s *PVZGRPCServer) GetIntakeStats(ctx context.Context, req *GetIntakeStatsRequest) (*GetIntakeStatsResponse, error) {
+	q := models.IntakeQuery{
+		To:      time.Now().UTC(),
+		Bucket:  req.GetBucket(),
+		GroupBy: req.GetGroupBy(),
+	}
+	if req.GetTo() != nil {
+		q.To = req.GetTo().AsTime()
+	}
+	q.From = q.To.Add(-defaultStatsPeriod)
+	if req.GetFrom() != nil {
+		q.From = req.GetFrom().AsTime()
+	}
+	if q.Bucket == "" {
+		q.Bucket = models.BucketDay
+	}
+
+	report, err := s.stats.IntakeReport(ctx, q)
 	if err != nil {
-		return errors.Wrap(err, "can't listen port")
+		return nil, err
 	}
 
-	s := grpc.NewServer()
-	RegisterPVZServiceServer(s, NewPVZGRPCServer(*repo))
+	resp := GetIntakeStatsResponse{
+		Receptions:                  int64(report.Summary.Receptions),
+		AvgReceptionDurationSeconds: report.Summary.AvgDurationSeconds,
+		AvgProductsPerReception:     report.Summary.AvgProductsPerReception,
+	}
+	for _, row := range report.Rows {
+		resp.Rows = append(resp.Rows, &IntakeRow{
+			Bucket:   timestamppb.New(row.Bucket),
+			PvzId:    row.PVZID,
+			City:     row.City,
+			Type:     row.ProductType,
+			Products: int64(row.Products),
+		})
+	}
+
+	return &resp, nil
+}
+
+func toProtoPVZ(item models.PVZ) *PVZ {
+	pvz := &PVZ{
+		Id:               item.ID,
+		City:             item.City,
+		RegistrationDate: timestamppb.New(item.RegistrationDate),
+		Address:          item.Address,
+		Latitude:         item.Latitude,
+		Longitude:        item.Longitude,
+		StoredItems:      int32(item.StoredItems),
+		ExternalCode:     item.ExternalCode,
+	}
+	if item.ArchivedAt != nil {
+		pvz.ArchivedAt = timestamppb.New(*item.ArchivedAt)
+	}
+	if item.Capacity != nil {
+		capacity := int32(*item.Capacity)
+		pvz.Capacity = &capacity
+	}
+	if item.WorkingHours != nil {
+		pvz.WorkingHours = make(map[string]*DayHours, len(item.WorkingHours))
+		for day, h := range item.WorkingHours {
+			pvz.WorkingHours[day] = &DayHours{Open: h.Open, Close: h.Close}
+		}
+	}
+
+	return pvz
+}
+
+// NewGRPCServer builds the server with tracing, metrics, logging and auth; the caller owns
+// listening and shutdown.
+func NewGRPCServer(service PVZService, stats StatsService, limits func() pagination.Limits, tokens TokenParser, m callMetrics, hs healthpb.HealthServer) *grpc.Server {
+	s := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(MetricsInterceptor(m), ErrorInterceptor, LoggingInterceptor, AuthInterceptor(tokens)),
+	)
+	RegisterPVZServiceServer(s, NewPVZGRPCServer(service, stats, limits))
+	healthpb.RegisterHealthServer(s, hs)
 	reflection.Register(s)
 
-	return s.Serve(lis)
+	return s
 }
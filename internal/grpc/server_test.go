@@ -0,0 +1,120 @@
+package pvz_proto
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"trainee-pvz/internal/auth"
+	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/pagination"
+)
+
+type fakePVZService struct {
+	page   models.PVZPage
+	cursor string
+	limit  int
+}
+
+func (f *fakePVZService) ListPVZ(_ context.Context, _ models.PVZFilter, cursor string, limit int) (models.PVZPage, error) {
+	f.cursor, f.limit = cursor, limit
+	return f.page, nil
+}
+
+func (*fakePVZService) NearbyPVZ(context.Context, models.GeoQuery) ([]models.NearbyPVZ, error) {
+	return nil, nil
+}
+
+type fakeStatsService struct{}
+
+func (fakeStatsService) IntakeReport(context.Context, models.IntakeQuery) (models.IntakeReport, error) {
+	return models.IntakeReport{Summary: models.ReceptionSummary{Receptions: 3}}, nil
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) SaveGRPCCall(time.Time, string, string) {}
+
+func newTestClient(t *testing.T, pvz *fakePVZService, tokens TokenParser) PVZServiceClient {
+	t.Helper()
+	limits := func() pagination.Limits { return pagination.Limits{Default: 10, Max: 30} }
+	s := NewGRPCServer(pvz, fakeStatsService{}, limits, tokens, nopMetrics{}, health.NewServer())
+
+	lis := bufconn.Listen(1 << 20)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return NewPVZServiceClient(conn)
+}
+
+func TestGetIntakeStats_Auth(t *testing.T) {
+	tokens := auth.NewJWTManager("secret", 60)
+	client := newTestClient(t, &fakePVZService{}, tokens)
+
+	withToken := func(role string) context.Context {
+		token, err := tokens.Generate("user", role)
+		require.NoError(t, err)
+		return metadata.AppendToOutgoingContext(context.Background(), authorizationKey, "Bearer "+token)
+	}
+	foreign, err := auth.NewJWTManager("other", 60).Generate("user", "moderator")
+	require.NoError(t, err)
+
+	cases := []struct {
+		name string
+		ctx  context.Context
+		code codes.Code
+	}{
+		{"no token", context.Background(), codes.Unauthenticated},
+		{"not bearer", metadata.AppendToOutgoingContext(context.Background(), authorizationKey, "Basic abc"), codes.Unauthenticated},
+		{"foreign secret", metadata.AppendToOutgoingContext(context.Background(), authorizationKey, "Bearer "+foreign), codes.Unauthenticated},
+		{"employee", withToken("employee"), codes.PermissionDenied},
+		{"moderator", withToken("moderator"), codes.OK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := client.GetIntakeStats(tc.ctx, &GetIntakeStatsRequest{})
+			require.Equal(t, tc.code, status.Code(err), err)
+			if tc.code == codes.OK {
+				assert.EqualValues(t, 3, resp.GetReceptions())
+			}
+		})
+	}
+}
+
+func TestGetPVZList_Pages(t *testing.T) {
+	pvz := &fakePVZService{page: models.PVZPage{Items: []models.PVZ{{ID: "1"}}, NextCursor: "next"}}
+	client := newTestClient(t, pvz, auth.NewJWTManager("secret", 60))
+
+	// no token needed; without limit only the first page of the default size comes back
+	resp, err := client.GetPVZList(context.Background(), &GetPVZListRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, 10, pvz.limit)
+	assert.Empty(t, pvz.cursor)
+	require.Len(t, resp.GetPvzs(), 1)
+	assert.Equal(t, "next", resp.GetNextCursor())
+
+	_, err = client.GetPVZList(context.Background(), &GetPVZListRequest{Cursor: "next", Limit: 30})
+	require.NoError(t, err)
+	assert.Equal(t, "next", pvz.cursor)
+	assert.Equal(t, 30, pvz.limit)
+
+	_, err = client.GetPVZList(context.Background(), &GetPVZListRequest{Limit: 31})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
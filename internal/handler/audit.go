@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+
+	"trainee-pvz/internal/audit"
+	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/openapi"
+)
+
+// ActorMiddleware stores where the request came from for the audit log,
+// RequireAuth adds the user later.
+func (s *Server) ActorMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+
+		ctx := audit.WithActor(r.Context(), audit.Actor{
+			RequestID: requestIDFromContext(r.Context()),
+			IP:        ip,
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (s *Server) ListAuditHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.Get().HTTP.Timeout)*time.Millisecond)
+	defer cancel()
+
+	filter := models.AuditFilter{
+		ActorID:    q.Get("actorId"),
+		Action:     q.Get("action"),
+		EntityType: q.Get("entityType"),
+		EntityID:   q.Get("entityId"),
+	}
+
+	if filter.ActorID != "" {
+		if _, err := uuid.Parse(filter.ActorID); err != nil {
+			writeBadRequest(w, r, "invalid actorId")
+			return
+		}
+	}
+
+	if v := q.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeBadRequest(w, r, "invalid from date")
+			return
+		}
+		filter.From = &t
+	}
+
+	if v := q.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeBadRequest(w, r, "invalid to date")
+			return
+		}
+		filter.To = &t
+	}
+
+	limit, err := s.parseLimit(q)
+	if err != nil {
+		writeError(ctx, w, r, err, "invalid limit")
+		return
+	}
+
+	page, err := s.Service.Audit.ListAudit(ctx, filter, q.Get("cursor"), limit)
+	if err != nil {
+		writeError(ctx, w, r, err, "failed to list audit log")
+		return
+	}
+
+	resp := openapi.AuditPage{Items: make([]openapi.AuditEntry, 0, len(page.Items))}
+	for _, entry := range page.Items {
+		resp.Items = append(resp.Items, toOpenapiAuditEntry(entry))
+	}
+	if page.NextCursor != "" {
+		resp.NextCursor = &page.NextCursor
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func toOpenapiAuditEntry(entry models.AuditEntry) openapi.AuditEntry {
+	resp := openapi.AuditEntry{
+		Id:         entry.ID,
+		CreatedAt:  entry.CreatedAt,
+		ActorRole:  entry.ActorRole,
+		Action:     entry.Action,
+		EntityType: openapi.AuditEntryEntityType(entry.EntityType),
+		EntityId:   entry.EntityID,
+		RequestId:  entry.RequestID,
+		Ip:         entry.IP,
+	}
+	if entry.ActorID != nil {
+		id := openapi_types.UUID(uuid.MustParse(*entry.ActorID))
+		resp.ActorId = &id
+	}
+	if entry.Before != nil {
+		resp.Before = &entry.Before
+	}
+	if entry.After != nil {
+		resp.After = &entry.After
+	}
+
+	return resp
+}
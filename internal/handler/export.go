@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"trainee-pvz/internal/export"
+	"trainee-pvz/internal/logger"
+	"trainee-pvz/internal/models"
+)
+
+const defaultExportPeriod = 30 * 24 * time.Hour
+
+// writeTracker remembers whether the body has been started, after that the status can't be changed.
+type writeTracker struct {
+	w       io.Writer
+	written bool
+}
+
+func (t *writeTracker) Write(p []byte) (int, error) {
+	t.written = true
+	return t.w.Write(p)
+}
+
+func (s *Server) ExportReceptionsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.Get().HTTP.ExportTimeout)*time.Millisecond)
+	defer cancel()
+
+	query := models.ExportQuery{
+		PVZID:  q.Get("pvzId"),
+		To:     time.Now().UTC(),
+		Format: models.ExportCSV,
+	}
+
+	if v := q.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeBadRequest(w, r, "invalid to date")
+			return
+		}
+		query.To = t
+	}
+
+	query.From = query.To.Add(-defaultExportPeriod)
+	if v := q.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeBadRequest(w, r, "invalid from date")
+			return
+		}
+		query.From = t
+	}
+
+	if v := q.Get("format"); v != "" {
+		query.Format = v
+	}
+
+	filename := fmt.Sprintf("receptions_%s_%s_%s.%s",
+		query.PVZID, query.From.Format("20060102"), query.To.Format("20060102"), query.Format)
+	w.Header().Set("Content-Type", export.ContentType(query.Format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	body := &writeTracker{w: w}
+	err := s.Service.Export.ExportReceptions(ctx, query, body)
+	if err != nil && !body.written {
+		w.Header().Del("Content-Disposition")
+		writeError(ctx, w, r, err, "failed to export receptions")
+		return
+	}
+	if err != nil {
+		// the status is already sent, the client sees a truncated file
+		logger.FromContext(ctx).Error("export broke off", slog.Any("err", err))
+	}
+}
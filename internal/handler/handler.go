@@ -3,9 +3,11 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"io"
 
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 
@@ -14,14 +16,17 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	openapi_types "github.com/oapi-codegen/runtime/types"
-	"github.com/pkg/errors"
 	"golang.org/x/crypto/bcrypt"
 
 	"trainee-pvz/config"
 	"trainee-pvz/internal/auth"
 	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/logger"
 	"trainee-pvz/internal/models"
 	"trainee-pvz/internal/openapi"
+	"trainee-pvz/internal/pagination"
+	"trainee-pvz/internal/problem"
+	"trainee-pvz/internal/validation"
 )
 
 type UserServiceInterface interface {
@@ -30,26 +35,52 @@ type UserServiceInterface interface {
 }
 
 type ProductServiceInterface interface {
-	AddProduct(ctx context.Context, p models.Product) error
+	AddProduct(ctx context.Context, p models.Product) (models.StorageUsage, error)
+	AddProducts(ctx context.Context, receptionID string, products []models.Product) (models.StorageUsage, error)
 	DeleteLastProduct(ctx context.Context, receptionID string) error
+	IssueProduct(ctx context.Context, productID string) error
 }
 
 type ReceptionServiceInterface interface {
 	CreateReception(ctx context.Context, rec models.Reception) error
 	CloseReception(ctx context.Context, id string) error
-	GetLastReceptionID(ctx context.Context, pvzID string) (string, error)
 	GetOpenReceptionID(ctx context.Context, pvzID string) (string, error)
 }
 
 type PVZServiceInterface interface {
 	CreatePVZ(ctx context.Context, pvz models.PVZ) error
-	ListPVZ(ctx context.Context, start, end *time.Time, page, limit int) ([]models.PVZ, error)
+	ListPVZ(ctx context.Context, filter models.PVZFilter, cursor string, limit int) (models.PVZPage, error)
+	ListPVZByPage(ctx context.Context, filter models.PVZFilter, page, limit int) ([]models.PVZ, error)
+	NearbyPVZ(ctx context.Context, q models.GeoQuery) ([]models.NearbyPVZ, error)
+	UpdatePVZ(ctx context.Context, id string, upd models.PVZUpdate) (models.PVZ, error)
+	ArchivePVZ(ctx context.Context, id string) (models.PVZ, error)
+	RestorePVZ(ctx context.Context, id string) (models.PVZ, error)
+	ImportPVZ(ctx context.Context, r io.Reader, dryRun bool) (models.PVZImportResult, error)
 }
 
+type StatsServiceInterface interface {
+	IntakeReport(ctx context.Context, q models.IntakeQuery) (models.IntakeReport, error)
+}
+
+type ExportServiceInterface interface {
+	ExportReceptions(ctx context.Context, q models.ExportQuery, w io.Writer) error
+}
+
+type AuditServiceInterface interface {
+	ListAudit(ctx context.Context, filter models.AuditFilter, cursor string, limit int) (models.AuditPage, error)
+}
+
+type HealthServiceInterface interface {
+	Readiness(ctx context.Context) models.Readiness
+}
+
+const defaultNearbyRadiusKm = 10
+
 type Server struct {
 	Service    Services
 	JWTManager *auth.JWTManager
-	Cfg        config.Cfg
+	Validator  *validation.Validator
+	Cfg        *config.Store
 	metrics    metrics
 }
 
@@ -58,16 +89,24 @@ type Services struct {
 	Product   ProductServiceInterface
 	Reception ReceptionServiceInterface
 	PVZ       PVZServiceInterface
+	Stats     StatsServiceInterface
+	Export    ExportServiceInterface
+	Audit     AuditServiceInterface
+	Health    HealthServiceInterface
 }
 
 type metrics interface {
 	SaveHTTPDuration(timeSince time.Time, path string, code int, method string)
+	SaveHTTPSizes(path, method string, requestSize int64, responseSize int)
+	IncHTTPInFlight()
+	DecHTTPInFlight()
 }
 
-func NewServer(service Services, jwt *auth.JWTManager, cfg config.Cfg, m metrics) *Server {
+func NewServer(service Services, jwt *auth.JWTManager, v *validation.Validator, cfg *config.Store, m metrics) *Server {
 	return &Server{
 		Service:    service,
 		JWTManager: jwt,
+		Validator:  v,
 		Cfg:        cfg,
 		metrics:    m,
 	}
@@ -75,20 +114,19 @@ func NewServer(service Services, jwt *auth.JWTManager, cfg config.Cfg, m metrics
 
 func (s *Server) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	var req openapi.PostRegisterJSONBody
-	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.HTTP.Timeout)*time.Millisecond)
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.Get().HTTP.Timeout)*time.Millisecond)
 	defer cancel()
 
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		slog.Error("failed to decode register request", slog.Any("err", err))
-		http.Error(w, `{"message":"invalid json"}`, http.StatusBadRequest)
+		logger.FromContext(ctx).Error("failed to decode register request", slog.Any("err", err))
+		writeBadRequest(w, r, "invalid json")
 		return
 	}
 
 	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		slog.Error("failed to hash password", slog.Any("err", err))
-		http.Error(w, `{"message":"internal error"}`, http.StatusInternalServerError)
+		writeError(ctx, w, r, err, "failed to hash password")
 		return
 	}
 
@@ -101,19 +139,13 @@ func (s *Server) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 
 	err = s.Service.User.Register(ctx, user)
 	if err != nil {
-		if errors.Is(err, er.ErrUserAlreadyExists) {
-			http.Error(w, `{"message":"user already exists"}`, http.StatusBadRequest)
-			return
-		}
-		slog.Error("failed to create user", slog.Any("err", err))
-		http.Error(w, `{"message":"internal error"}`, http.StatusInternalServerError)
+		writeError(ctx, w, r, err, "failed to create user")
 		return
 	}
 
 	token, err := s.JWTManager.Generate(user.ID, user.Role)
 	if err != nil {
-		slog.Error("failed to generate jwt token", slog.Any("err", err))
-		http.Error(w, `{"message":"internal error"}`, http.StatusInternalServerError)
+		writeError(ctx, w, r, err, "failed to generate jwt token")
 		return
 	}
 
@@ -126,34 +158,33 @@ func (s *Server) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 func (s *Server) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	var req openapi.PostLoginJSONBody
 
-	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.HTTP.Timeout)*time.Millisecond)
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.Get().HTTP.Timeout)*time.Millisecond)
 	defer cancel()
 
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		slog.Error("failed to decode login request", slog.Any("err", err))
-		http.Error(w, `{"message":"invalid json"}`, http.StatusBadRequest)
+		logger.FromContext(ctx).Error("failed to decode login request", slog.Any("err", err))
+		writeBadRequest(w, r, "invalid json")
 		return
 	}
 
 	user, err := s.Service.User.Login(ctx, string(req.Email))
 	if err != nil {
-		slog.Error("user not found", slog.Any("err", err))
-		http.Error(w, `{"message":"invalid credentials"}`, http.StatusUnauthorized)
+		logger.FromContext(ctx).Error("user not found", slog.Any("err", err))
+		problem.Write(w, r, er.ErrInvalidCredentials)
 		return
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
 	if err != nil {
-		slog.Warn("password mismatch", slog.String("email", user.Email))
-		http.Error(w, `{"message":"invalid credentials"}`, http.StatusUnauthorized)
+		logger.FromContext(ctx).Warn("password mismatch", slog.String("email", user.Email))
+		problem.Write(w, r, er.ErrInvalidCredentials)
 		return
 	}
 
 	token, err := s.JWTManager.Generate(user.ID, user.Role)
 	if err != nil {
-		slog.Error("failed to generate token", slog.Any("err", err))
-		http.Error(w, `{"message":"internal error"}`, http.StatusInternalServerError)
+		writeError(ctx, w, r, err, "failed to generate token")
 		return
 	}
 
@@ -165,17 +196,17 @@ func (s *Server) LoginHandler(w http.ResponseWriter, r *http.Request) {
 func (s *Server) DummyLoginHandler(w http.ResponseWriter, r *http.Request) {
 	var req openapi.PostDummyLoginJSONBody
 
-	_, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.HTTP.Timeout)*time.Millisecond)
+	_, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.Get().HTTP.Timeout)*time.Millisecond)
 	defer cancel()
 
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		slog.Error("invalid dummy login body", slog.Any("err", err))
-		http.Error(w, `{"message":"invalid request"}`, http.StatusBadRequest)
+		logger.FromContext(r.Context()).Error("invalid dummy login body", slog.Any("err", err))
+		writeBadRequest(w, r, "invalid request")
 		return
 	}
 
-	token := s.Cfg.Auth.DummyTokenPrefix + string(req.Role)
+	token := s.Cfg.Get().Auth.DummyTokenPrefix + string(req.Role)
 
 	resp := map[string]string{"token": token}
 	w.Header().Set("Content-Type", "application/json")
@@ -185,88 +216,120 @@ func (s *Server) DummyLoginHandler(w http.ResponseWriter, r *http.Request) {
 func (s *Server) CreatePVZHandler(w http.ResponseWriter, r *http.Request) {
 	var req openapi.PostPvzJSONRequestBody
 
-	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.HTTP.Timeout)*time.Millisecond)
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.Get().HTTP.Timeout)*time.Millisecond)
 	defer cancel()
 
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		slog.Error("invalid pvz json", slog.Any("err", err))
-		http.Error(w, `{"message":"invalid request"}`, http.StatusBadRequest)
+		logger.FromContext(ctx).Error("invalid pvz json", slog.Any("err", err))
+		writeBadRequest(w, r, "invalid request")
 		return
 	}
 
 	city := string(req.City)
 	id := uuid.New()
+	ctx = logger.With(ctx, slog.String("pvz_id", id.String()))
 	now := time.Now().UTC()
 
 	pvz := models.PVZ{
 		ID:               id.String(),
 		RegistrationDate: now,
 		City:             city,
+		Latitude:         req.Latitude,
+		Longitude:        req.Longitude,
+		WorkingHours:     toModelWorkingHours(req.WorkingHours),
+		Capacity:         req.Capacity,
+		ExternalCode:     req.ExternalCode,
+	}
+	if req.Address != nil {
+		pvz.Address = *req.Address
 	}
 
 	err = s.Service.PVZ.CreatePVZ(ctx, pvz)
-	if errors.Is(err, er.ErrUnsupportedCity) {
-		http.Error(w, `{"message":"unsupported city"}`, http.StatusBadRequest)
+	if err != nil {
+		writeError(ctx, w, r, err, "failed to create pvz")
 		return
 	}
 
+	logger.FromContext(ctx).Info("pvz has been created", slog.Any("info:", pvz))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toOpenapiPVZ(pvz))
+}
+
+func (s *Server) UpdatePVZHandler(w http.ResponseWriter, r *http.Request) {
+	var req openapi.PatchPvzPvzIdJSONRequestBody
+
+	pvzID := chi.URLParam(r, "pvzId")
+	if _, err := uuid.Parse(pvzID); err != nil {
+		writeBadRequest(w, r, "invalid pvz id")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.Get().HTTP.Timeout)*time.Millisecond)
+	defer cancel()
+	ctx = logger.With(ctx, slog.String("pvz_id", pvzID))
+
+	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		slog.Error("failed to create pvz", slog.Any("err", err))
-		http.Error(w, `{"message":"internal error"}`, http.StatusInternalServerError)
+		logger.FromContext(ctx).Error("invalid pvz update json", slog.Any("err", err))
+		writeBadRequest(w, r, "invalid request")
 		return
 	}
 
-	slog.Info("pvz has been created", slog.Any("info:", pvz))
+	upd := models.PVZUpdate{
+		Address:      req.Address,
+		Latitude:     req.Latitude,
+		Longitude:    req.Longitude,
+		WorkingHours: toModelWorkingHours(req.WorkingHours),
+		Capacity:     req.Capacity,
+	}
 
-	openapiUUID := openapi_types.UUID(id)
-	resp := openapi.PVZ{
-		Id:               &openapiUUID,
-		RegistrationDate: &now,
-		City:             openapi.PVZCity(city),
+	pvz, err := s.Service.PVZ.UpdatePVZ(ctx, pvzID, upd)
+	if err != nil {
+		writeError(ctx, w, r, err, "failed to update pvz")
+		return
 	}
 
+	logger.FromContext(ctx).Info("pvz has been updated", slog.Any("info:", pvz))
+
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(resp)
+	json.NewEncoder(w).Encode(toOpenapiPVZ(pvz))
 }
 
 func (s *Server) CreateReceptionHandler(w http.ResponseWriter, r *http.Request) {
 	var req openapi.PostReceptionsJSONRequestBody
 
-	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.HTTP.Timeout)*time.Millisecond)
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.Get().HTTP.Timeout)*time.Millisecond)
 	defer cancel()
 
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		slog.Error("invalid reception json", slog.Any("err", err))
-		http.Error(w, `{"message":"invalid request"}`, http.StatusBadRequest)
+		logger.FromContext(ctx).Error("invalid reception json", slog.Any("err", err))
+		writeBadRequest(w, r, "invalid request")
 		return
 	}
 
 	pvzID := req.PvzId.String()
+	ctx = logger.With(ctx, slog.String("pvz_id", pvzID))
 	now := time.Now().UTC()
 	id := uuid.New()
 
 	reception := models.Reception{
-		ID:       id.String(),
-		DateTime: now,
-		PVZID:    pvzID,
-		Status:   string(openapi.InProgress),
+		ID:        id.String(),
+		DateTime:  now,
+		PVZID:     pvzID,
+		Status:    string(openapi.InProgress),
+		CreatedBy: userIDFromContext(r.Context()),
 	}
 
 	err = s.Service.Reception.CreateReception(ctx, reception)
-	if errors.Is(err, er.ErrReceptionAlreadyExists) {
-		http.Error(w, `{"message":"can't create one more reception"}`, http.StatusBadRequest)
-		return
-	}
-
 	if err != nil {
-		slog.Error("failed to create reception", slog.Any("err", err))
-		http.Error(w, `{"message":"internal error"}`, http.StatusInternalServerError)
+		writeError(ctx, w, r, err, "failed to create reception")
 		return
 	}
-	slog.Info("reception has been created", slog.Any("info:", reception))
+	logger.FromContext(ctx).Info("reception has been created", slog.Any("info:", reception))
 
 	openapiID := openapi_types.UUID(id)
 	resp := openapi.Reception{
@@ -284,26 +347,22 @@ func (s *Server) CreateReceptionHandler(w http.ResponseWriter, r *http.Request)
 func (s *Server) AddProductHandler(w http.ResponseWriter, r *http.Request) {
 	var req openapi.PostProductsJSONRequestBody
 
-	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.HTTP.Timeout)*time.Millisecond)
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.Get().HTTP.Timeout)*time.Millisecond)
 	defer cancel()
 
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		slog.Error("invalid product json", slog.Any("err", err))
-		http.Error(w, `{"message":"invalid request"}`, http.StatusBadRequest)
+		logger.FromContext(ctx).Error("invalid product json", slog.Any("err", err))
+		writeBadRequest(w, r, "invalid request")
 		return
 	}
 
 	pvzID := req.PvzId.String()
+	ctx = logger.With(ctx, slog.String("pvz_id", pvzID))
 
-	receptionID, err := s.Service.Reception.GetLastReceptionID(ctx, pvzID)
-	if errors.Is(err, er.ErrNoOpenReception) {
-		slog.Error("no active reception", slog.Any("err", err))
-		http.Error(w, `{"message":"no open reception"}`, http.StatusBadRequest)
-	}
+	receptionID, err := s.Service.Reception.GetOpenReceptionID(ctx, pvzID)
 	if err != nil {
-		slog.Error("failed to get reception", slog.Any("err", err))
-		http.Error(w, `{"message":"internal error"}`, http.StatusInternalServerError)
+		writeError(ctx, w, r, err, "failed to get reception")
 		return
 	}
 
@@ -317,13 +376,12 @@ func (s *Server) AddProductHandler(w http.ResponseWriter, r *http.Request) {
 		ReceptionID: receptionID,
 	}
 
-	err = s.Service.Product.AddProduct(r.Context(), product)
+	usage, err := s.Service.Product.AddProduct(ctx, product)
 	if err != nil {
-		slog.Error("failed to add product", slog.Any("err", err))
-		http.Error(w, `{"message":"internal error"}`, http.StatusInternalServerError)
+		writeError(ctx, w, r, err, "failed to add product")
 		return
 	}
-	slog.Info("product has been created", slog.Any("info:", product))
+	logger.FromContext(ctx).Info("product has been created", slog.Any("info:", product))
 
 	openapiID := openapi_types.UUID(productID)
 	resp := openapi.Product{
@@ -333,6 +391,77 @@ func (s *Server) AddProductHandler(w http.ResponseWriter, r *http.Request) {
 		ReceptionId: req.PvzId,
 	}
 
+	if usage.OverCapacity() {
+		w.Header().Set("Warning", `199 - "pvz is over capacity"`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// AddProductsHandler adds the products to the open reception of the PVZ at once, either all of
+// them or none.
+func (s *Server) AddProductsHandler(w http.ResponseWriter, r *http.Request) {
+	var req openapi.PostProductsBatchJSONRequestBody
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.Get().HTTP.Timeout)*time.Millisecond)
+	defer cancel()
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		logger.FromContext(ctx).Error("invalid products json", slog.Any("err", err))
+		writeBadRequest(w, r, "invalid request")
+		return
+	}
+
+	pvzID := req.PvzId.String()
+	ctx = logger.With(ctx, slog.String("pvz_id", pvzID))
+
+	receptionID, err := s.Service.Reception.GetOpenReceptionID(ctx, pvzID)
+	if err != nil {
+		writeError(ctx, w, r, err, "failed to get reception")
+		return
+	}
+
+	receptionUUID, err := uuid.Parse(receptionID)
+	if err != nil {
+		writeError(ctx, w, r, err, "failed to get reception")
+		return
+	}
+
+	// Postgres keeps microseconds, the response shows the stored datetimes
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	products := make([]models.Product, 0, len(req.Products))
+	resp := make([]openapi.Product, 0, len(req.Products))
+	for i, item := range req.Products {
+		// a microsecond apart, so the products are deleted last first in the order of the request
+		dateTime := now.Add(time.Duration(i) * time.Microsecond)
+		productID := uuid.New()
+
+		products = append(products, models.Product{
+			ID:          productID.String(),
+			DateTime:    dateTime,
+			Type:        string(item.Type),
+			ReceptionID: receptionID,
+		})
+		resp = append(resp, openapi.Product{
+			Id:          &productID,
+			DateTime:    &dateTime,
+			Type:        openapi.ProductType(item.Type),
+			ReceptionId: receptionUUID,
+		})
+	}
+
+	usage, err := s.Service.Product.AddProducts(ctx, receptionID, products)
+	if err != nil {
+		writeError(ctx, w, r, err, "failed to add products")
+		return
+	}
+	logger.FromContext(ctx).Info("products have been created", slog.Int("products", len(products)))
+
+	if usage.OverCapacity() {
+		w.Header().Set("Warning", `199 - "pvz is over capacity"`)
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(resp)
@@ -341,28 +470,23 @@ func (s *Server) AddProductHandler(w http.ResponseWriter, r *http.Request) {
 func (s *Server) CloseReceptionHandler(w http.ResponseWriter, r *http.Request) {
 	pvzID := chi.URLParam(r, "pvzId")
 
-	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.HTTP.Timeout)*time.Millisecond)
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.Get().HTTP.Timeout)*time.Millisecond)
 	defer cancel()
+	ctx = logger.With(ctx, slog.String("pvz_id", pvzID))
 
 	receptionID, err := s.Service.Reception.GetOpenReceptionID(ctx, pvzID)
-	if errors.Is(err, er.ErrNoOpenReception) {
-		slog.Error("no active reception to close", slog.Any("err", err))
-		http.Error(w, `{"message":"no open reception to close"}`, http.StatusBadRequest)
-	}
 	if err != nil {
-		slog.Error("failed to get reception ID ", slog.Any("err", err))
-		http.Error(w, `{"message":"internal error"}`, http.StatusInternalServerError)
+		writeError(ctx, w, r, err, "failed to get reception ID")
 		return
 	}
 
 	err = s.Service.Reception.CloseReception(ctx, receptionID)
 	if err != nil {
-		slog.Error("failed to close reception", slog.Any("err", err))
-		http.Error(w, `{"message":"internal error"}`, http.StatusInternalServerError)
+		writeError(ctx, w, r, err, "failed to close reception")
 		return
 	}
 
-	slog.Info("reception has been deteted", slog.Any("info:", receptionID))
+	logger.FromContext(ctx).Info("reception has been deteted", slog.Any("info:", receptionID))
 
 	openapiID := openapi_types.UUID(uuid.MustParse(receptionID))
 	parsedPvzID := openapi_types.UUID(uuid.MustParse(pvzID))
@@ -383,25 +507,38 @@ func (s *Server) CloseReceptionHandler(w http.ResponseWriter, r *http.Request) {
 func (s *Server) DeleteLastProductHandler(w http.ResponseWriter, r *http.Request) {
 	pvzID := chi.URLParam(r, "pvzId")
 
-	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.HTTP.Timeout)*time.Millisecond)
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.Get().HTTP.Timeout)*time.Millisecond)
 	defer cancel()
+	ctx = logger.With(ctx, slog.String("pvz_id", pvzID))
 
 	err := s.Service.Product.DeleteLastProduct(ctx, pvzID)
-	if errors.Is(err, er.ErrNoProducts) {
-		http.Error(w, `{"message":"nothing to delete"}`, http.StatusBadRequest)
+	if err != nil {
+		writeError(ctx, w, r, err, "failed to delete product")
 		return
 	}
-	if errors.Is(err, er.ErrNoOpenReception) {
-		http.Error(w, `{"message":"no open reception for deliting product"}`, http.StatusBadRequest)
+
+	logger.FromContext(ctx).Info("last product has been deteted", slog.Any("info:", pvzID))
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) IssueProductHandler(w http.ResponseWriter, r *http.Request) {
+	productID := chi.URLParam(r, "productId")
+	if _, err := uuid.Parse(productID); err != nil {
+		writeBadRequest(w, r, "invalid product id")
 		return
 	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.Get().HTTP.Timeout)*time.Millisecond)
+	defer cancel()
+
+	err := s.Service.Product.IssueProduct(ctx, productID)
 	if err != nil {
-		slog.Error("failed to delete product", slog.Any("err", err))
-		http.Error(w, `{"message":"internal error"}`, http.StatusInternalServerError)
+		writeError(ctx, w, r, err, "failed to issue product")
 		return
 	}
 
-	slog.Info("last product has been deteted", slog.Any("info:", pvzID))
+	logger.FromContext(ctx).Info("product has been issued", slog.Any("info:", productID))
 
 	w.WriteHeader(http.StatusOK)
 }
@@ -409,57 +546,71 @@ func (s *Server) DeleteLastProductHandler(w http.ResponseWriter, r *http.Request
 func (s *Server) ListPVZHandler(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
 
-	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.HTTP.Timeout)*time.Millisecond)
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.Get().HTTP.Timeout)*time.Millisecond)
 	defer cancel()
 
-	var (
-		start, end *time.Time
-		page       = 1
-		limit      = s.Cfg.Limits.PaginationLimit
-	)
+	var filter models.PVZFilter
 
 	if v := q.Get("startDate"); v != "" {
 		t, err := time.Parse(time.RFC3339, v)
 		if err != nil {
-			slog.Error("start date is not parsed", slog.Any("err", err))
-			http.Error(w, `{"message":"invalid start date"}`, http.StatusBadRequest)
+			logger.FromContext(ctx).Error("start date is not parsed", slog.Any("err", err))
+			writeBadRequest(w, r, "invalid start date")
 			return
 		}
-		start = &t
+		filter.StartDate = &t
 	}
 
 	if v := q.Get("endDate"); v != "" {
 		t, err := time.Parse(time.RFC3339, v)
 		if err != nil {
-			slog.Error("end date is not parsed", slog.Any("err", err))
-			http.Error(w, `{"message":"invalid end date"}`, http.StatusBadRequest)
+			logger.FromContext(ctx).Error("end date is not parsed", slog.Any("err", err))
+			writeBadRequest(w, r, "invalid end date")
 			return
 		}
-		end = &t
+		filter.EndDate = &t
 	}
 
-	if v := q.Get("page"); v != "" {
-		p, err := strconv.Atoi(v)
-		if err == nil && p > 0 {
-			page = p
+	if v := q.Get("includeArchived"); v != "" {
+		includeArchived, err := strconv.ParseBool(v)
+		if err != nil {
+			logger.FromContext(ctx).Error("includeArchived is not parsed", slog.Any("err", err))
+			writeBadRequest(w, r, "invalid includeArchived")
+			return
 		}
+		filter.IncludeArchived = includeArchived
 	}
 
-	pvzs, err := s.Service.PVZ.ListPVZ(ctx, start, end, page, limit)
+	limit, err := s.parseLimit(q)
 	if err != nil {
-		slog.Error("failed to list filtered pvz", slog.Any("err", err))
-		http.Error(w, `{"message":"internal error"}`, http.StatusInternalServerError)
+		writeError(ctx, w, r, err, "invalid limit")
 		return
 	}
 
-	var resp []openapi.PVZ
-	for _, pvz := range pvzs {
-		id := uuid.MustParse(pvz.ID)
-		resp = append(resp, openapi.PVZ{
-			Id:               (*openapi_types.UUID)(&id),
-			City:             openapi.PVZCity(pvz.City),
-			RegistrationDate: &pvz.RegistrationDate,
-		})
+	if q.Has("page") && q.Has("cursor") {
+		writeBadRequest(w, r, "page and cursor can't be used together, use cursor")
+		return
+	}
+
+	// without cursor the old response shape is kept for one more release, clients opt in to
+	// pages with an empty cursor
+	if !q.Has("cursor") {
+		s.listPVZByPage(ctx, w, r, filter, limit)
+		return
+	}
+
+	page, err := s.Service.PVZ.ListPVZ(ctx, filter, q.Get("cursor"), limit)
+	if err != nil {
+		writeError(ctx, w, r, err, "failed to list filtered pvz")
+		return
+	}
+
+	resp := openapi.PVZPage{Items: make([]openapi.PVZ, 0, len(page.Items))}
+	for _, pvz := range page.Items {
+		resp.Items = append(resp.Items, toOpenapiPVZ(pvz))
+	}
+	if page.NextCursor != "" {
+		resp.NextCursor = &page.NextCursor
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -467,28 +618,265 @@ func (s *Server) ListPVZHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// listPVZByPage serves requests without cursor in the old response shape, a bare array of
+// PVZs of the page (the first one when page is not given), and marks the response as deprecated.
+func (s *Server) listPVZByPage(ctx context.Context, w http.ResponseWriter, r *http.Request, filter models.PVZFilter, limit int) {
+	page := 1
+	if q := r.URL.Query(); q.Has("page") {
+		var err error
+		page, err = strconv.Atoi(q.Get("page"))
+		if err != nil {
+			writeBadRequest(w, r, "page must be a positive number, use cursor instead")
+			return
+		}
+	}
+
+	items, err := s.Service.PVZ.ListPVZByPage(ctx, filter, page, limit)
+	if err != nil {
+		writeError(ctx, w, r, err, "failed to list filtered pvz")
+		return
+	}
+
+	resp := make([]openapi.PVZ, 0, len(items))
+	for _, pvz := range items {
+		resp = append(resp, toOpenapiPVZ(pvz))
+	}
+
+	w.Header().Set("Deprecation", "true")
+	w.Header().Set("Warning", `299 - "listing without cursor is deprecated and will return a page object, pass cursor (empty for the first page)"`)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// parseLimit reads the optional "limit" query param bounded by limits config.
+func (s *Server) parseLimit(q url.Values) (int, error) {
+	var requested int
+	if v := q.Get("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l <= 0 {
+			return 0, er.ErrInvalidLimit
+		}
+		requested = l
+	}
+
+	cfg := s.Cfg.Get().Limits
+	limits := pagination.Limits{Default: cfg.PaginationLimit, Max: cfg.MaxPaginationLimit}
+	return limits.Resolve(requested)
+}
+
+func (s *Server) NearbyPVZHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.Get().HTTP.Timeout)*time.Millisecond)
+	defer cancel()
+
+	geo := models.GeoQuery{RadiusKm: defaultNearbyRadiusKm}
+
+	lat, err := strconv.ParseFloat(q.Get("lat"), 64)
+	if err != nil {
+		writeBadRequest(w, r, "invalid lat")
+		return
+	}
+	geo.Latitude = lat
+
+	lon, err := strconv.ParseFloat(q.Get("lon"), 64)
+	if err != nil {
+		writeBadRequest(w, r, "invalid lon")
+		return
+	}
+	geo.Longitude = lon
+
+	if v := q.Get("radiusKm"); v != "" {
+		radius, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			writeBadRequest(w, r, "invalid radiusKm")
+			return
+		}
+		geo.RadiusKm = radius
+	}
+
+	geo.Limit, err = s.parseLimit(q)
+	if err != nil {
+		writeError(ctx, w, r, err, "invalid limit")
+		return
+	}
+
+	nearby, err := s.Service.PVZ.NearbyPVZ(ctx, geo)
+	if err != nil {
+		writeError(ctx, w, r, err, "failed to find nearby pvz")
+		return
+	}
+
+	resp := make([]openapi.NearbyPVZ, 0, len(nearby))
+	for _, item := range nearby {
+		resp = append(resp, openapi.NearbyPVZ{
+			Pvz:        toOpenapiPVZ(item.PVZ),
+			DistanceKm: item.DistanceKm,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) ArchivePVZHandler(w http.ResponseWriter, r *http.Request) {
+	pvzID := chi.URLParam(r, "pvzId")
+	if _, err := uuid.Parse(pvzID); err != nil {
+		writeBadRequest(w, r, "invalid pvz id")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.Get().HTTP.Timeout)*time.Millisecond)
+	defer cancel()
+	ctx = logger.With(ctx, slog.String("pvz_id", pvzID))
+
+	pvz, err := s.Service.PVZ.ArchivePVZ(ctx, pvzID)
+	if err != nil {
+		writeError(ctx, w, r, err, "failed to archive pvz")
+		return
+	}
+
+	logger.FromContext(ctx).Info("pvz has been archived", slog.Any("info:", pvzID))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toOpenapiPVZ(pvz))
+}
+
+func (s *Server) RestorePVZHandler(w http.ResponseWriter, r *http.Request) {
+	pvzID := chi.URLParam(r, "pvzId")
+	if _, err := uuid.Parse(pvzID); err != nil {
+		writeBadRequest(w, r, "invalid pvz id")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.Get().HTTP.Timeout)*time.Millisecond)
+	defer cancel()
+	ctx = logger.With(ctx, slog.String("pvz_id", pvzID))
+
+	pvz, err := s.Service.PVZ.RestorePVZ(ctx, pvzID)
+	if err != nil {
+		writeError(ctx, w, r, err, "failed to restore pvz")
+		return
+	}
+
+	logger.FromContext(ctx).Info("pvz has been restored", slog.Any("info:", pvzID))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toOpenapiPVZ(pvz))
+}
+
+func toOpenapiPVZ(pvz models.PVZ) openapi.PVZ {
+	id := openapi_types.UUID(uuid.MustParse(pvz.ID))
+	resp := openapi.PVZ{
+		Id:               &id,
+		City:             openapi.PVZCity(pvz.City),
+		RegistrationDate: &pvz.RegistrationDate,
+		ArchivedAt:       pvz.ArchivedAt,
+		Latitude:         pvz.Latitude,
+		Longitude:        pvz.Longitude,
+		WorkingHours:     toOpenapiWorkingHours(pvz.WorkingHours),
+		Capacity:         pvz.Capacity,
+		StoredItems:      &pvz.StoredItems,
+		ExternalCode:     pvz.ExternalCode,
+	}
+	if pvz.Address != "" {
+		resp.Address = &pvz.Address
+	}
+
+	return resp
+}
+
+func toModelWorkingHours(h *openapi.WorkingHours) models.WorkingHours {
+	if h == nil {
+		return nil
+	}
+
+	days := map[string]*openapi.DayHours{
+		"mon": h.Mon, "tue": h.Tue, "wed": h.Wed, "thu": h.Thu, "fri": h.Fri, "sat": h.Sat, "sun": h.Sun,
+	}
+
+	res := models.WorkingHours{}
+	for day, dh := range days {
+		if dh != nil {
+			res[day] = models.DayHours{Open: dh.Open, Close: dh.Close}
+		}
+	}
+
+	return res
+}
+
+func toOpenapiWorkingHours(h models.WorkingHours) *openapi.WorkingHours {
+	if h == nil {
+		return nil
+	}
+
+	day := func(key string) *openapi.DayHours {
+		dh, ok := h[key]
+		if !ok {
+			return nil
+		}
+		return &openapi.DayHours{Open: dh.Open, Close: dh.Close}
+	}
+
+	return &openapi.WorkingHours{
+		Mon: day("mon"), Tue: day("tue"), Wed: day("wed"), Thu: day("thu"),
+		Fri: day("fri"), Sat: day("sat"), Sun: day("sun"),
+	}
+}
+
+// writeError sends err as problem details. Errors without a code are unexpected, they are
+// logged with msg and answered with 500.
+func writeError(ctx context.Context, w http.ResponseWriter, r *http.Request, err error, msg string) {
+	if er.KindOf(err) == er.KindInternal {
+		logger.FromContext(ctx).Error(msg, slog.Any("err", err))
+	}
+	problem.Write(w, r, err)
+}
+
+func writeBadRequest(w http.ResponseWriter, r *http.Request, message string) {
+	problem.Write(w, r, er.Invalid(message))
+}
+
 func (s *Server) Routes() *chi.Mux {
 	router := chi.NewRouter()
+	router.Use(s.TracingMiddleware)
+	router.Use(s.RequestIDMiddleware)
+	router.Use(s.ActorMiddleware)
 	router.Use(s.LoggingMiddleware)
 	router.Use(s.PrometheusMiddleware)
 
+	router.Get("/healthz", s.LivenessHandler)
+	router.Get("/readyz", s.ReadinessHandler)
 	router.Mount("/swagger", api.Routes(router))
-	router.Post("/register", s.RegisterHandler)
-	router.Post("/login", s.LoginHandler)
-	router.Post("/dummyLogin", s.DummyLoginHandler)
+
+	public := router.With(s.ValidateBody)
+	public.Post("/register", s.RegisterHandler)
+	public.Post("/login", s.LoginHandler)
+	public.Post("/dummyLogin", s.DummyLoginHandler)
 
 	router.Group(func(protected chi.Router) {
 		protected.Use(s.RequireAuth)
 		protected.Get("/pvz", s.ListPVZHandler)
+		protected.Get("/pvz/nearby", s.NearbyPVZHandler)
 
-		employee := protected.With(RequireRole("employee"))
+		employee := protected.With(RequireRole("employee"), s.ValidateBody)
 		employee.Post("/products", s.AddProductHandler)
+		employee.Post("/products/batch", s.AddProductsHandler)
+		employee.Post("/products/{productId}/issue", s.IssueProductHandler)
 		employee.Post("/pvz/{pvzId}/close_last_reception", s.CloseReceptionHandler)
 		employee.Post("/pvz/{pvzId}/delete_last_product", s.DeleteLastProductHandler)
 		employee.Post("/receptions", s.CreateReceptionHandler)
 
-		moderator := protected.With(RequireRole("moderator"))
+		moderator := protected.With(RequireRole("moderator"), s.ValidateBody)
 		moderator.Post("/pvz", s.CreatePVZHandler)
+		moderator.Post("/pvz/import", s.ImportPVZHandler)
+		moderator.Patch("/pvz/{pvzId}", s.UpdatePVZHandler)
+		moderator.Post("/pvz/{pvzId}/archive", s.ArchivePVZHandler)
+		moderator.Post("/pvz/{pvzId}/restore", s.RestorePVZHandler)
+		moderator.Get("/stats/intake", s.IntakeStatsHandler)
+		moderator.Get("/export/receptions", s.ExportReceptionsHandler)
+		moderator.Get("/audit", s.ListAuditHandler)
 	})
 
 	return router
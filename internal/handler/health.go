@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"trainee-pvz/internal/openapi"
+)
+
+const readinessTimeout = 2 * time.Second
+
+// LivenessHandler only reports that the process serves HTTP; dependencies are checked by /readyz.
+func (s *Server) LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(openapi.Health{Status: openapi.Ok})
+}
+
+func (s *Server) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	result := s.Service.Health.Readiness(ctx)
+
+	resp := openapi.Health{Status: openapi.Ok, Checks: &result.Checks}
+	status := http.StatusOK
+	if !result.Ready {
+		resp.Status = openapi.NotReady
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
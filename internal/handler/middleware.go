@@ -1,23 +1,47 @@
 package handler
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
-	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/v5"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"trainee-pvz/internal/audit"
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/logger"
+	"trainee-pvz/internal/problem"
+	"trainee-pvz/internal/requestid"
 )
 
 type contextKey string
 
-const userCtxKey = contextKey("role")
+const (
+	userCtxKey      = contextKey("role")
+	userIDCtxKey    = contextKey("user_id")
+	requestIDCtxKey = contextKey("request_id")
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	unmatchedRoute  = "unmatched" // metrics path label of requests no route matched
+
+	maxBodyBytes = 1 << 20 // JSON bodies, the CSV import has its own limit
+)
 
 type statusRecorder struct {
 	http.ResponseWriter
 	Status       int
 	ResponseBody string
+	Size         int
 }
 
 func (r *statusRecorder) WriteHeader(status int) {
@@ -27,43 +51,84 @@ func (r *statusRecorder) WriteHeader(status int) {
 
 func (r *statusRecorder) Write(body []byte) (int, error) {
 	r.ResponseBody = string(body)
-	return r.ResponseWriter.Write(body)
+	n, err := r.ResponseWriter.Write(body)
+	r.Size += n
+	return n, err
+}
+
+// RequestIDMiddleware takes the request ID from X-Request-ID or generates a new one, returns it
+// in the response and attaches it to the request logger.
+func (s *Server) RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := requestid.Accept(r.Header.Get(requestIDHeader))
+		w.Header().Set(requestIDHeader, id)
+
+		ctx := context.WithValue(r.Context(), requestIDCtxKey, id)
+		ctx = logger.With(ctx, slog.String("request_id", id))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDCtxKey).(string)
+	return id
 }
 
 func (s *Server) RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if !strings.HasPrefix(authHeader, "Bearer ") {
-			http.Error(w, `{"message":"missing token"}`, http.StatusUnauthorized)
+			problem.Write(w, r, er.ErrMissingToken)
 			return
 		}
 
 		token := strings.TrimPrefix(authHeader, "Bearer ")
 
-		if strings.HasPrefix(token, s.Cfg.Auth.DummyTokenPrefix) {
-			role := strings.TrimPrefix(token, s.Cfg.Auth.DummyTokenPrefix)
+		if strings.HasPrefix(token, s.Cfg.Get().Auth.DummyTokenPrefix) {
+			role := strings.TrimPrefix(token, s.Cfg.Get().Auth.DummyTokenPrefix)
 			ctx := context.WithValue(r.Context(), userCtxKey, role)
+			ctx = withAuditUser(ctx, "", role)
+			ctx = logger.With(ctx, slog.String("role", role))
 			next.ServeHTTP(w, r.WithContext(ctx))
 			return
 		}
 
 		claims, err := s.JWTManager.Parse(token)
 		if err != nil {
-			http.Error(w, `{"message":"invalid token"}`, http.StatusUnauthorized)
+			problem.Write(w, r, er.ErrInvalidToken)
 			return
 		}
 
 		ctx := context.WithValue(r.Context(), userCtxKey, claims.Role)
+		ctx = context.WithValue(ctx, userIDCtxKey, claims.UserID)
+		ctx = withAuditUser(ctx, claims.UserID, claims.Role)
+		ctx = logger.With(ctx, slog.String("user_id", claims.UserID), slog.String("role", claims.Role))
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+func withAuditUser(ctx context.Context, userID, role string) context.Context {
+	actor := audit.ActorFromContext(ctx)
+	actor.UserID, actor.Role = userID, role
+	return audit.WithActor(ctx, actor)
+}
+
+// userIDFromContext returns the authenticated user, nil for dummy tokens.
+func userIDFromContext(ctx context.Context) *string {
+	userID, ok := ctx.Value(userIDCtxKey).(string)
+	if !ok || userID == "" {
+		return nil
+	}
+
+	return &userID
+}
+
 func RequireRole(role string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ctxRole, _ := r.Context().Value(userCtxKey).(string)
 			if ctxRole != role {
-				http.Error(w, `{"message":"forbidden"}`, http.StatusForbidden)
+				problem.Write(w, r, er.ErrForbidden)
 				return
 			}
 			next.ServeHTTP(w, r)
@@ -71,6 +136,37 @@ func RequireRole(role string) func(http.Handler) http.Handler {
 	}
 }
 
+// ValidateBody checks JSON bodies against the API spec before the handler decodes them. It
+// goes after the auth middlewares, so clients without access get 401 and 403 rather than 400.
+func (s *Server) ValidateBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pattern := chi.RouteContext(r.Context()).RoutePattern()
+		if s.Validator == nil || !s.Validator.HasBody(r.Method, pattern) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			problem.Write(w, r, er.ErrRequestTooLarge)
+			return
+		}
+		if err != nil {
+			writeBadRequest(w, r, "failed to read body")
+			return
+		}
+
+		if err := s.Validator.Validate(r.Method, pattern, data); err != nil {
+			writeError(r.Context(), w, r, err, "failed to validate body")
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(data))
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (s *Server) LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -78,7 +174,7 @@ func (s *Server) LoggingMiddleware(next http.Handler) http.Handler {
 		ww := &statusRecorder{ResponseWriter: w, Status: http.StatusOK}
 		next.ServeHTTP(ww, r)
 
-		slog.Info("HTTP Request",
+		logger.FromContext(r.Context()).Info("HTTP Request",
 			slog.String("method", r.Method),
 			slog.String("path", r.URL.Path),
 			slog.Int("status", ww.Status),
@@ -87,21 +183,49 @@ func (s *Server) LoggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// TracingMiddleware starts the server span from the incoming W3C trace context and names it
+// after the matched chi route once routing is done.
+func (s *Server) TracingMiddleware(next http.Handler) http.Handler {
+	named := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+
+		if rc := chi.RouteContext(r.Context()); rc != nil {
+			if pattern := rc.RoutePattern(); pattern != "" {
+				span := trace.SpanFromContext(r.Context())
+				span.SetName(r.Method + " " + pattern)
+				span.SetAttributes(semconv.HTTPRoute(pattern))
+			}
+		}
+	})
+
+	return otelhttp.NewHandler(named, "http", otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return r.Method
+	}))
+}
+
 func (s *Server) PrometheusMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		recorder := &statusRecorder{
 			ResponseWriter: w,
 			Status:         200,
 		}
-		path := r.URL.Path // fallback
+
+		s.metrics.IncHTTPInFlight()
+		defer s.metrics.DecHTTPInFlight()
+
+		start := time.Now()
+		next.ServeHTTP(recorder, r)
+
+		// the route pattern is only known once chi has matched the request; raw paths of
+		// unmatched requests (404s, scanners) would give every one its own series
+		path := unmatchedRoute
 		if rc := chi.RouteContext(r.Context()); rc != nil {
 			if p := rc.RoutePattern(); p != "" {
 				path = p
 			}
 		}
 
-		start := time.Now()
-		next.ServeHTTP(recorder, r)
 		s.metrics.SaveHTTPDuration(start, path, recorder.Status, r.Method)
+		s.metrics.SaveHTTPSizes(path, r.Method, r.ContentLength, recorder.Size)
 	})
 }
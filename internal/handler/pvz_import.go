@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/logger"
+	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/openapi"
+	"trainee-pvz/internal/problem"
+)
+
+const maxImportBodyBytes = 10 << 20
+
+func (s *Server) ImportPVZHandler(w http.ResponseWriter, r *http.Request) {
+	dryRun := false
+	if v := r.URL.Query().Get("dryRun"); v != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(v)
+		if err != nil {
+			writeBadRequest(w, r, "invalid dryRun")
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.Get().HTTP.ImportTimeout)*time.Millisecond)
+	defer cancel()
+
+	body := http.MaxBytesReader(w, r.Body, maxImportBodyBytes)
+	result, err := s.Service.PVZ.ImportPVZ(ctx, body, dryRun)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		problem.Write(w, r, er.ErrRequestTooLarge)
+		return
+	}
+	if err != nil {
+		writeError(ctx, w, r, err, "failed to import pvz")
+		return
+	}
+
+	status := http.StatusCreated
+	switch {
+	case len(result.Errors) > 0:
+		status = http.StatusUnprocessableEntity
+	case dryRun:
+		status = http.StatusOK
+	default:
+		logger.FromContext(ctx).Info("pvz have been imported", slog.Int("count", result.Imported))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(toOpenapiImportResult(result))
+}
+
+func toOpenapiImportResult(result models.PVZImportResult) openapi.PVZImportResult {
+	resp := openapi.PVZImportResult{
+		DryRun:   result.DryRun,
+		Imported: result.Imported,
+		Items:    make([]openapi.PVZ, 0, len(result.Items)),
+		Errors:   make([]openapi.PVZImportRowError, 0, len(result.Errors)),
+	}
+
+	for _, pvz := range result.Items {
+		resp.Items = append(resp.Items, toOpenapiPVZ(pvz))
+	}
+
+	for _, e := range result.Errors {
+		resp.Errors = append(resp.Errors, openapi.PVZImportRowError{Row: e.Row, Message: e.Message})
+	}
+
+	return resp
+}
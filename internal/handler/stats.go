@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	openapi_types "github.com/oapi-codegen/runtime/types"
+
+	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/openapi"
+)
+
+const defaultStatsPeriod = 7 * 24 * time.Hour
+
+func (s *Server) IntakeStatsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(s.Cfg.Get().HTTP.Timeout)*time.Millisecond)
+	defer cancel()
+
+	query := models.IntakeQuery{
+		To:     time.Now().UTC(),
+		Bucket: models.BucketDay,
+	}
+
+	if v := q.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeBadRequest(w, r, "invalid to date")
+			return
+		}
+		query.To = t
+	}
+
+	query.From = query.To.Add(-defaultStatsPeriod)
+	if v := q.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeBadRequest(w, r, "invalid from date")
+			return
+		}
+		query.From = t
+	}
+
+	if v := q.Get("bucket"); v != "" {
+		query.Bucket = v
+	}
+
+	if v := q.Get("groupBy"); v != "" {
+		query.GroupBy = strings.Split(v, ",")
+	}
+
+	report, err := s.Service.Stats.IntakeReport(ctx, query)
+	if err != nil {
+		writeError(ctx, w, r, err, "failed to build intake report")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toOpenapiIntakeReport(report))
+}
+
+func toOpenapiIntakeReport(report models.IntakeReport) openapi.IntakeReport {
+	resp := openapi.IntakeReport{
+		From:                        report.Query.From,
+		To:                          report.Query.To,
+		Bucket:                      openapi.IntakeReportBucket(report.Query.Bucket),
+		GroupBy:                     make([]openapi.IntakeReportGroupBy, 0, len(report.Query.GroupBy)),
+		Rows:                        make([]openapi.IntakeRow, 0, len(report.Rows)),
+		Receptions:                  report.Summary.Receptions,
+		AvgReceptionDurationSeconds: report.Summary.AvgDurationSeconds,
+		AvgProductsPerReception:     report.Summary.AvgProductsPerReception,
+	}
+
+	for _, dim := range report.Query.GroupBy {
+		resp.GroupBy = append(resp.GroupBy, openapi.IntakeReportGroupBy(dim))
+	}
+
+	for _, row := range report.Rows {
+		item := openapi.IntakeRow{
+			Bucket:   row.Bucket,
+			City:     row.City,
+			Type:     row.ProductType,
+			Products: row.Products,
+		}
+		if row.PVZID != nil {
+			id := openapi_types.UUID(uuid.MustParse(*row.PVZID))
+			item.PvzId = &id
+		}
+		resp.Rows = append(resp.Rows, item)
+	}
+
+	return resp
+}
@@ -0,0 +1,129 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/metadata"
+
+	"trainee-pvz/api"
+	"trainee-pvz/config"
+	"trainee-pvz/internal/auth"
+	proto_pvz "trainee-pvz/internal/grpc"
+	"trainee-pvz/internal/handler"
+	"trainee-pvz/internal/pagination"
+	"trainee-pvz/internal/service"
+	"trainee-pvz/internal/validation"
+	"trainee-pvz/migrations"
+)
+
+// testApp is the service wired like cmd does over a fresh schema: HTTP served by httptest
+// and gRPC on a local port, both stopped when the test ends.
+type testApp struct {
+	t      *testing.T
+	url    string
+	grpc   proto_pvz.PVZServiceClient
+	tokens *auth.JWTManager
+}
+
+func newTestApp(ctx context.Context, t *testing.T, driver string) *testApp {
+	t.Helper()
+	cfg := postgresConfig(ctx, t)
+	repos := postgresRepositories(ctx, t, driver, migratedSchema(ctx, t, cfg), cfg.DB)
+
+	schemaVersion, err := migrations.LatestVersion()
+	require.NoError(t, err)
+
+	m := &fakeMetrics{}
+	pvzService := service.NewPVZService(repos.PVZ, m)
+	statsService := service.NewStatsService(repos.Stats)
+	services := handler.Services{
+		User:      service.NewUserService(repos.User),
+		Product:   service.NewProductService(repos.Product, m, cfg.Limits.RejectOverCapacity),
+		Reception: service.NewReceptionService(repos.Reception, m),
+		PVZ:       pvzService,
+		Stats:     statsService,
+		Export:    service.NewExportService(repos.Export),
+		Audit:     service.NewAuditService(repos.Audit),
+		Health:    service.NewHealthService(repos.Health, schemaVersion),
+	}
+
+	validator, err := validation.New(api.Spec)
+	require.NoError(t, err)
+
+	// without JWT, dummy tokens carry the role
+	s := handler.NewServer(services, nil, validator, config.NewStore(cfg), m)
+	srv := httptest.NewServer(s.Routes())
+	t.Cleanup(srv.Close)
+
+	limits := func() pagination.Limits {
+		return pagination.Limits{Default: cfg.Limits.PaginationLimit, Max: cfg.Limits.MaxPaginationLimit}
+	}
+	// gRPC has no dummy tokens, its calls are signed with the configured secret
+	tokens := auth.NewJWTManager(cfg.Auth.JWTSecret, cfg.Auth.JWTExpirationMinutes)
+	grpcServer := proto_pvz.NewGRPCServer(pvzService, statsService, limits, tokens, m, health.NewServer())
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return &testApp{t: t, url: srv.URL, grpc: proto_pvz.NewPVZServiceClient(conn), tokens: tokens}
+}
+
+// as returns ctx with a bearer token of the role in the outgoing gRPC metadata.
+func (a *testApp) as(ctx context.Context, role string) context.Context {
+	a.t.Helper()
+	token, err := a.tokens.Generate("", role)
+	require.NoError(a.t, err)
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+// do sends body as JSON with a dummy token of the role and decodes a successful response
+// into out when it's not nil. It returns the status code, 0 when the request failed. do only
+// reports failures, so it can be called from other goroutines.
+func (a *testApp) do(method, path, role string, body, out any) int {
+	a.t.Helper()
+
+	var payload io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if !assert.NoError(a.t, err) {
+			return 0
+		}
+		payload = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, a.url+path, payload)
+	if !assert.NoError(a.t, err) {
+		return 0
+	}
+	req.Header.Set("Authorization", "Bearer dummy-token-for-"+role)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(a.t, err) {
+		return 0
+	}
+	defer resp.Body.Close()
+
+	if out != nil && resp.StatusCode < http.StatusBadRequest {
+		assert.NoError(a.t, json.NewDecoder(resp.Body).Decode(out))
+	}
+
+	return resp.StatusCode
+}
@@ -0,0 +1,154 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"trainee-pvz/config"
+	proto_pvz "trainee-pvz/internal/grpc"
+	"trainee-pvz/internal/openapi"
+)
+
+// parallel runs fn n times at once and counts the returned status codes.
+func parallel(n int, fn func(i int) int) map[int]int {
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		codes = map[int]int{}
+	)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			code := fn(i)
+
+			mu.Lock()
+			codes[code]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return codes
+}
+
+// TestConcurrentReceptionFlow races employees of one PVZ: only one reception gets opened, the
+// capacity holds and concurrent deletions remove every product exactly once.
+func TestConcurrentReceptionFlow(t *testing.T) {
+	const capacity, workers = 10, 20
+
+	for _, driver := range []string{config.DriverSQLX, config.DriverPGX} {
+		t.Run(driver, func(t *testing.T) {
+			ctx := context.Background()
+			app := newTestApp(ctx, t, driver)
+
+			var pvz openapi.PVZ
+			code := app.do(http.MethodPost, "/pvz", "moderator", map[string]any{"city": "Москва", "capacity": capacity}, &pvz)
+			require.Equal(t, http.StatusCreated, code)
+			pvzID := pvz.Id.String()
+
+			codes := parallel(workers, func(int) int {
+				return app.do(http.MethodPost, "/receptions", "employee", map[string]string{"pvzId": pvzID}, nil)
+			})
+			assert.Equal(t, map[int]int{http.StatusCreated: 1, http.StatusBadRequest: workers - 1}, codes)
+
+			codes = parallel(workers, func(int) int {
+				return app.do(http.MethodPost, "/products", "employee", map[string]any{"pvzId": pvzID, "type": "обувь"}, nil)
+			})
+			assert.Equal(t, map[int]int{http.StatusCreated: capacity, http.StatusBadRequest: workers - capacity}, codes)
+
+			codes = parallel(capacity, func(int) int {
+				return app.do(http.MethodPost, "/pvz/"+pvzID+"/delete_last_product", "employee", nil, nil)
+			})
+			assert.Equal(t, map[int]int{http.StatusOK: capacity}, codes)
+			code = app.do(http.MethodPost, "/pvz/"+pvzID+"/delete_last_product", "employee", nil, nil)
+			assert.Equal(t, http.StatusBadRequest, code, "nothing left to delete")
+
+			list, err := app.grpc.GetPVZList(ctx, &proto_pvz.GetPVZListRequest{})
+			require.NoError(t, err)
+			require.Len(t, list.GetPvzs(), 1)
+			assert.Zero(t, list.GetPvzs()[0].GetStoredItems())
+		})
+	}
+}
+
+// TestGRPCReadsDuringWrites serves gRPC reads while PVZs and products are written over HTTP,
+// then checks that the reads see everything once the writes are done.
+func TestGRPCReadsDuringWrites(t *testing.T) {
+	const pvzs, products = 5, 10
+
+	for _, driver := range []string{config.DriverSQLX, config.DriverPGX} {
+		t.Run(driver, func(t *testing.T) {
+			ctx := context.Background()
+			app := newTestApp(ctx, t, driver)
+			from := time.Now().Add(-time.Hour)
+
+			done := make(chan struct{})
+			var readers sync.WaitGroup
+			for range 4 {
+				readers.Add(1)
+				go func() {
+					defer readers.Done()
+					for {
+						select {
+						case <-done:
+							return
+						default:
+						}
+						_, err := app.grpc.GetPVZList(ctx, &proto_pvz.GetPVZListRequest{})
+						assert.NoError(t, err)
+						_, err = app.grpc.GetNearbyPVZ(ctx, &proto_pvz.GetNearbyPVZRequest{Latitude: 55.75, Longitude: 37.62})
+						assert.NoError(t, err)
+					}
+				}()
+			}
+
+			codes := parallel(pvzs, func(i int) int {
+				var pvz openapi.PVZ
+				code := app.do(http.MethodPost, "/pvz", "moderator", map[string]any{
+					"city":      "Москва",
+					"latitude":  55.75 + float64(i)/1000,
+					"longitude": 37.62,
+				}, &pvz)
+				if code != http.StatusCreated {
+					return code
+				}
+				id := pvz.Id.String()
+				if code = app.do(http.MethodPost, "/receptions", "employee", map[string]string{"pvzId": id}, nil); code != http.StatusCreated {
+					return code
+				}
+				for range products {
+					if code = app.do(http.MethodPost, "/products", "employee", map[string]any{"pvzId": id, "type": "одежда"}, nil); code != http.StatusCreated {
+						return code
+					}
+				}
+				return app.do(http.MethodPost, "/pvz/"+id+"/close_last_reception", "employee", nil, nil)
+			})
+			close(done)
+			readers.Wait()
+			require.Equal(t, map[int]int{http.StatusOK: pvzs}, codes)
+
+			nearby, err := app.grpc.GetNearbyPVZ(ctx, &proto_pvz.GetNearbyPVZRequest{Latitude: 55.75, Longitude: 37.62})
+			require.NoError(t, err)
+			assert.Len(t, nearby.GetPvzs(), pvzs)
+
+			stats, err := app.grpc.GetIntakeStats(app.as(ctx, "moderator"), &proto_pvz.GetIntakeStatsRequest{
+				From:    timestamppb.New(from),
+				To:      timestamppb.New(time.Now().Add(24 * time.Hour)),
+				GroupBy: []string{"city", "type"},
+			})
+			require.NoError(t, err)
+			require.Len(t, stats.GetRows(), 1)
+			assert.Equal(t, "Москва", stats.GetRows()[0].GetCity())
+			assert.EqualValues(t, pvzs*products, stats.GetRows()[0].GetProducts())
+			assert.EqualValues(t, pvzs, stats.GetReceptions())
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package integration
+
+import (
+	"os"
+	"testing"
+
+	"trainee-pvz/internal/repository/pgtest"
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	pgtest.Stop()
+	os.Exit(code)
+}
@@ -0,0 +1,177 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+
+	"trainee-pvz/internal/repository"
+)
+
+// defaultPlanProducts is the data volume the hot queries are checked on, override it with
+// PVZ_BENCH_PRODUCTS. Every reception gets productsPerPlanReception products and every PVZ
+// has one open reception, the rest are closed.
+const (
+	defaultPlanProducts      = 2_000_000
+	productsPerPlanReception = 100
+	planPVZs                 = 2_000
+)
+
+// hotQuery is a query on the request path with the index its plan has to use.
+type hotQuery struct {
+	name  string
+	sql   string
+	index string
+	args  func(s planSample) []any
+}
+
+var hotQueries = []hotQuery{
+	{"OpenReceptionID", repository.OpenReceptionIDQuery, "receptions_pvz_open_idx",
+		func(s planSample) []any { return []any{s.pvzID} }},
+	{"HasOpenReception", repository.HasOpenReceptionQuery, "receptions_pvz_open_idx",
+		func(s planSample) []any { return []any{s.pvzID} }},
+	{"LastProduct", repository.LastProductQuery, "products_reception_datetime_idx",
+		func(s planSample) []any { return []any{s.receptionID} }},
+	{"Export", repository.ExportReceptionsQuery, "receptions_pvz_datetime_idx",
+		func(s planSample) []any { return []any{s.pvzID, s.from, s.to} }},
+	{"ReceptionSummary", repository.ReceptionSummaryQuery, "receptions_datetime_idx",
+		func(s planSample) []any { return []any{s.to.Add(-time.Hour), s.to} }},
+}
+
+// planSample is a PVZ with an open reception from the seeded data.
+type planSample struct {
+	pvzID       string
+	receptionID string
+	from, to    time.Time
+}
+
+// BenchmarkHotQueries seeds a throwaway schema with millions of products, checks with EXPLAIN
+// that every hot query is served by its index and no receptions or products are scanned
+// sequentially, then measures the queries:
+//
+//	go test ./internal/integration -run '^$' -bench HotQueries -benchtime 2000x
+func BenchmarkHotQueries(b *testing.B) {
+	ctx := context.Background()
+	pool, sample := seedPlanSchema(ctx, b)
+
+	for _, q := range hotQueries {
+		plan := explain(ctx, b, pool, q.sql, q.args(sample)...)
+		require.Contains(b, plan.indexes, q.index, "%s plan:\n%s", q.name, plan.raw)
+		require.NotContains(b, plan.seqScans, "receptions", "%s plan:\n%s", q.name, plan.raw)
+		require.NotContains(b, plan.seqScans, "products", "%s plan:\n%s", q.name, plan.raw)
+	}
+
+	for _, q := range hotQueries {
+		b.Run(q.name, func(b *testing.B) {
+			args := q.args(sample)
+			for range b.N {
+				rows, err := pool.Query(ctx, q.sql, args...)
+				require.NoError(b, err)
+				rows.Close()
+				require.NoError(b, rows.Err())
+			}
+		})
+	}
+}
+
+// seedPlanSchema fills a freshly migrated schema with generated data. It skips the benchmark
+// when Postgres is not available.
+func seedPlanSchema(ctx context.Context, b *testing.B) (*pgxpool.Pool, planSample) {
+	cfg := postgresConfig(ctx, b)
+
+	products := defaultPlanProducts
+	if v := os.Getenv("PVZ_BENCH_PRODUCTS"); v != "" {
+		var err error
+		products, err = strconv.Atoi(v)
+		require.NoError(b, err)
+	}
+	receptions := max(products/productsPerPlanReception, planPVZs)
+
+	connString := migratedSchema(ctx, b, cfg)
+	pool, err := pgxpool.New(ctx, connString)
+	require.NoError(b, err)
+	b.Cleanup(pool.Close)
+
+	b.Logf("seeding %d PVZ, %d receptions, %d products", planPVZs, receptions, products)
+	seed := []struct {
+		sql  string
+		args []any
+	}{
+		{`INSERT INTO pvz (id, registration_date, city)
+			SELECT gen_random_uuid(), now() - g * interval '1 hour', (ARRAY['Москва', 'Санкт-Петербург', 'Казань'])[g % 3 + 1]
+			FROM generate_series(1, $1) g`, []any{planPVZs}},
+		// the newest reception of every PVZ stays open
+		{`INSERT INTO receptions (id, datetime, pvz_id, status, closed_at)
+			SELECT gen_random_uuid(), now() - g * interval '1 minute', p.id,
+				CASE WHEN g <= $1 THEN 'in_progress' ELSE 'close' END,
+				CASE WHEN g <= $1 THEN NULL ELSE now() - g * interval '1 minute' + interval '30 minutes' END
+			FROM generate_series(1, $2) g
+			JOIN (SELECT id, row_number() OVER (ORDER BY id) - 1 AS n FROM pvz) p ON p.n = g % $1`,
+			[]any{planPVZs, receptions}},
+		{`INSERT INTO products (id, datetime, type, reception_id)
+			SELECT gen_random_uuid(), r.datetime + g * interval '1 second', (ARRAY['электроника', 'одежда', 'обувь'])[g % 3 + 1], r.id
+			FROM receptions r CROSS JOIN generate_series(1, $1) g`, []any{productsPerPlanReception}},
+		{`ANALYZE`, nil},
+	}
+	for _, s := range seed {
+		_, err = pool.Exec(ctx, s.sql, s.args...)
+		require.NoError(b, err)
+	}
+
+	var sample planSample
+	err = pool.QueryRow(ctx, `
+		SELECT pvz_id, id, datetime - interval '30 days', datetime + interval '1 day'
+		FROM receptions WHERE status = 'in_progress' LIMIT 1
+	`).Scan(&sample.pvzID, &sample.receptionID, &sample.from, &sample.to)
+	require.NoError(b, err)
+
+	return pool, sample
+}
+
+type queryPlan struct {
+	raw      string
+	indexes  []string
+	seqScans []string
+}
+
+// explain returns the indexes and sequentially scanned tables of the query plan.
+func explain(ctx context.Context, b *testing.B, pool *pgxpool.Pool, query string, args ...any) queryPlan {
+	var raw []byte
+	err := pool.QueryRow(ctx, `EXPLAIN (FORMAT JSON) `+query, args...).Scan(&raw)
+	require.NoError(b, err)
+
+	var root []struct {
+		Plan planNode `json:"Plan"`
+	}
+	require.NoError(b, json.Unmarshal(raw, &root))
+
+	plan := queryPlan{raw: string(raw)}
+	var walk func(n planNode)
+	walk = func(n planNode) {
+		if n.IndexName != "" {
+			plan.indexes = append(plan.indexes, n.IndexName)
+		}
+		if n.NodeType == "Seq Scan" {
+			plan.seqScans = append(plan.seqScans, n.RelationName)
+		}
+		for _, child := range n.Plans {
+			walk(child)
+		}
+	}
+	walk(root[0].Plan)
+
+	return plan
+}
+
+type planNode struct {
+	NodeType     string     `json:"Node Type"`
+	RelationName string     `json:"Relation Name"`
+	IndexName    string     `json:"Index Name"`
+	Plans        []planNode `json:"Plans"`
+}
@@ -0,0 +1,52 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"trainee-pvz/config"
+	"trainee-pvz/internal/database"
+	"trainee-pvz/internal/repository"
+	"trainee-pvz/internal/repository/pgxrepo"
+	"trainee-pvz/internal/repository/repotest"
+)
+
+// postgresRepositories connects to conn with the driver and builds the repositories the way
+// cmd does, the connection is closed when the test ends.
+func postgresRepositories(ctx context.Context, t *testing.T, driver, conn string, cfg config.DbCfg) repotest.Repositories {
+	t.Helper()
+
+	if driver == config.DriverPGX {
+		pool, err := database.ConnectPool(ctx, conn, cfg)
+		require.NoError(t, err)
+		t.Cleanup(pool.Close)
+
+		return repotest.Repositories{
+			User:      pgxrepo.NewUserRepository(pool),
+			Reception: pgxrepo.NewReceptionRepository(pool),
+			Product:   pgxrepo.NewProductRepository(pool),
+			PVZ:       pgxrepo.NewPVZRepository(pool, pool),
+			Stats:     pgxrepo.NewStatsRepository(pool, pool),
+			Export:    pgxrepo.NewExportRepository(pool),
+			Audit:     pgxrepo.NewAuditRepository(pool),
+			Health:    pgxrepo.NewHealthRepository(pool),
+		}
+	}
+
+	db, err := database.ConnectDB(ctx, conn, cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return repotest.Repositories{
+		User:      repository.NewUserRepository(db),
+		Reception: repository.NewReceptionRepository(db),
+		Product:   repository.NewProductRepository(db),
+		PVZ:       repository.NewPVZRepository(db, db),
+		Stats:     repository.NewStatsRepository(db, db),
+		Export:    repository.NewExportRepository(db),
+		Audit:     repository.NewAuditRepository(db),
+		Health:    repository.NewHealthRepository(db),
+	}
+}
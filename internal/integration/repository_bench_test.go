@@ -0,0 +1,92 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"trainee-pvz/config"
+	"trainee-pvz/internal/database"
+	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/repository"
+	"trainee-pvz/internal/repository/pgxrepo"
+	"trainee-pvz/internal/service"
+)
+
+const productsPerReception = 10
+
+type flowRepos struct {
+	pvz       service.PVZRepository
+	reception service.ReceptionRepository
+	product   service.ProductRepository
+}
+
+// BenchmarkReceptionFlow runs a whole reception (create PVZ, open reception, add products,
+// delete the last one, close) on both drivers, to compare the sqlx and pgx repositories:
+//
+//	go test ./internal/integration -run '^$' -bench ReceptionFlow -benchmem
+func BenchmarkReceptionFlow(b *testing.B) {
+	ctx := context.Background()
+	cfg := postgresConfig(ctx, b)
+	conn := migratedSchema(ctx, b, cfg)
+
+	db, err := database.ConnectDB(ctx, conn, cfg.DB)
+	require.NoError(b, err)
+	defer db.Close()
+
+	pool, err := database.ConnectPool(ctx, conn, cfg.DB)
+	require.NoError(b, err)
+	defer pool.Close()
+
+	drivers := []struct {
+		name  string
+		repos flowRepos
+	}{
+		{config.DriverSQLX, flowRepos{
+			pvz:       repository.NewPVZRepository(db, db),
+			reception: repository.NewReceptionRepository(db),
+			product:   repository.NewProductRepository(db),
+		}},
+		{config.DriverPGX, flowRepos{
+			pvz:       pgxrepo.NewPVZRepository(pool, pool),
+			reception: pgxrepo.NewReceptionRepository(pool),
+			product:   pgxrepo.NewProductRepository(pool),
+		}},
+	}
+
+	for _, d := range drivers {
+		b.Run(d.name, func(b *testing.B) {
+			for range b.N {
+				runReceptionFlow(ctx, b, d.repos)
+			}
+		})
+	}
+}
+
+func runReceptionFlow(ctx context.Context, b *testing.B, repos flowRepos) {
+	pvzID := uuid.NewString()
+	err := repos.pvz.Create(ctx, models.PVZ{ID: pvzID, City: cities[0], RegistrationDate: time.Now()})
+	require.NoError(b, err)
+
+	receptionID := uuid.NewString()
+	err = repos.reception.Create(ctx, models.Reception{
+		ID: receptionID, DateTime: time.Now(), PVZID: pvzID, Status: "in_progress",
+	})
+	require.NoError(b, err)
+
+	for i := range productsPerReception {
+		_, err = repos.product.Add(ctx, models.Product{
+			ID: uuid.NewString(), DateTime: time.Now(), Type: productTypes[i%len(productTypes)], ReceptionID: receptionID,
+		}, false)
+		require.NoError(b, err)
+	}
+
+	_, err = repos.product.DeleteLast(ctx, pvzID)
+	require.NoError(b, err)
+
+	err = repos.reception.Close(ctx, receptionID)
+	require.NoError(b, err)
+}
@@ -0,0 +1,30 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"trainee-pvz/config"
+	"trainee-pvz/internal/repository/pgtest"
+)
+
+// postgresConfig returns the local config pointed at the test Postgres and skips the test
+// when it can't be started.
+func postgresConfig(ctx context.Context, tb testing.TB) config.Cfg {
+	tb.Helper()
+	cfg, err := config.GetConfig("../../config.yaml")
+	require.NoError(tb, err)
+	cfg.DB.ConnectTimeout = 1000
+	cfg.DB.Connection = pgtest.Connection(ctx, tb)
+	cfg.Auth.JWTSecret = "test-secret"
+
+	return cfg
+}
+
+// migratedSchema returns a connection string to a freshly migrated schema of the test Postgres.
+func migratedSchema(ctx context.Context, tb testing.TB, cfg config.Cfg) string {
+	tb.Helper()
+	return pgtest.Schema(ctx, tb, cfg.DB.Connection)
+}
@@ -0,0 +1,198 @@
+// Package lifecycle starts the servers and background workers of the process and stops them
+// together: on a signal, or as soon as one of the servers fails.
+package lifecycle
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+// Server is a listener-based server; *http.Server satisfies it, gRPC servers are wrapped by GRPC.
+type Server interface {
+	Serve(lis net.Listener) error
+	Shutdown(ctx context.Context) error
+}
+
+type server struct {
+	name string
+	addr string
+	srv  Server
+	lis  net.Listener
+}
+
+type worker struct {
+	name string
+	run  func(ctx context.Context)
+}
+
+type Manager struct {
+	drainTimeout   time.Duration
+	readinessDelay time.Duration
+	servers        []*server
+	workers        []worker
+	hooks          []func()
+}
+
+// New creates a manager. On a signal the servers keep accepting connections for readinessDelay
+// after the shutdown hooks, then get drainTimeout to finish in-flight requests.
+func New(drainTimeout, readinessDelay time.Duration) *Manager {
+	return &Manager{drainTimeout: drainTimeout, readinessDelay: readinessDelay}
+}
+
+// AddServer registers a server that will listen on addr (e.g. ":8080").
+func (m *Manager) AddServer(name, addr string, srv Server) {
+	m.servers = append(m.servers, &server{name: name, addr: addr, srv: srv})
+}
+
+// AddWorker registers a background job; its context is cancelled when shutdown starts.
+func (m *Manager) AddWorker(name string, run func(ctx context.Context)) {
+	m.workers = append(m.workers, worker{name: name, run: run})
+}
+
+// OnShutdown registers a hook called first thing on shutdown, a readiness delay before the
+// servers drain (e.g. to fail readiness checks).
+func (m *Manager) OnShutdown(fn func()) {
+	m.hooks = append(m.hooks, fn)
+}
+
+// Run binds all listeners, so a busy port fails the start right away, then serves until ctx
+// is done or a server fails, and drains everything within the drain timeout. The returned error
+// is the server failure, if any, or the shutdown error.
+func (m *Manager) Run(ctx context.Context) error {
+	err := m.listen()
+	if err != nil {
+		return err
+	}
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	var workersWG sync.WaitGroup
+	for _, w := range m.workers {
+		workersWG.Add(1)
+		go func() {
+			defer workersWG.Done()
+			w.run(workerCtx)
+			slog.Debug("worker stopped", slog.String("worker", w.name))
+		}()
+	}
+
+	failed := make(chan error, len(m.servers))
+	for _, s := range m.servers {
+		go func() {
+			slog.Info("server started", slog.String("server", s.name), slog.String("address", s.lis.Addr().String()))
+			err := s.srv.Serve(s.lis)
+			if err != nil && !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, grpc.ErrServerStopped) {
+				failed <- errors.Wrapf(err, "%s server failed", s.name)
+			}
+		}()
+	}
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		slog.Info("shutdown signal received, draining")
+	case runErr = <-failed:
+		slog.Error("server failed, shutting down", slog.Any("err", runErr))
+	}
+
+	for _, hook := range m.hooks {
+		hook()
+	}
+	stopWorkers()
+
+	// load balancers see readiness fail only on their next probe, until then new connections
+	// still come and must not be refused; a failed server has nothing to wait for
+	if runErr == nil && m.readinessDelay > 0 {
+		slog.Info("waiting for load balancers to notice readiness", slog.Duration("delay", m.readinessDelay))
+		time.Sleep(m.readinessDelay)
+	}
+
+	shutdownErr := m.shutdown()
+	workersWG.Wait()
+
+	if runErr != nil {
+		return runErr
+	}
+	return shutdownErr
+}
+
+func (m *Manager) listen() error {
+	for i, s := range m.servers {
+		lis, err := net.Listen("tcp", s.addr)
+		if err != nil {
+			for _, started := range m.servers[:i] {
+				started.lis.Close()
+			}
+			return errors.Wrapf(err, "can't listen %s for %s server", s.addr, s.name)
+		}
+		s.lis = lis
+	}
+
+	return nil
+}
+
+// shutdown drains all servers in parallel and returns the first error.
+func (m *Manager) shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.drainTimeout)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for _, s := range m.servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := s.srv.Shutdown(ctx)
+			if err != nil {
+				slog.Error("server shutdown failed", slog.String("server", s.name), slog.Any("err", err))
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = errors.Wrapf(err, "%s server shutdown", s.name)
+				}
+				mu.Unlock()
+				return
+			}
+			slog.Info("server stopped", slog.String("server", s.name))
+		}()
+	}
+	wg.Wait()
+
+	return firstErr
+}
+
+type grpcServer struct {
+	*grpc.Server
+}
+
+// GRPC adapts a gRPC server: Shutdown waits for in-flight calls with GracefulStop and falls back
+// to Stop when ctx expires.
+func GRPC(s *grpc.Server) Server {
+	return grpcServer{Server: s}
+}
+
+func (s grpcServer) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.Stop()
+		return errors.Wrap(ctx.Err(), "grpc calls not drained in time")
+	}
+}
@@ -0,0 +1,133 @@
+package lifecycle_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"trainee-pvz/internal/lifecycle"
+)
+
+func TestManager_BusyPort(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+
+	app := lifecycle.New(time.Second, 0)
+	app.AddServer("http", lis.Addr().String(), &http.Server{})
+
+	err = app.Run(context.Background())
+	assert.ErrorContains(t, err, "http server")
+}
+
+func TestManager_DrainsInFlightRequests(t *testing.T) {
+	addr := freeAddr(t)
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	var hookCalled, workerStopped bool
+	app := lifecycle.New(time.Second, 0)
+	app.AddServer("http", addr, &http.Server{Handler: handler})
+	app.OnShutdown(func() { hookCalled = true })
+	app.AddWorker("worker", func(ctx context.Context) {
+		<-ctx.Done()
+		workerStopped = true
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- app.Run(ctx) }()
+
+	respCh := make(chan *http.Response)
+	go func() {
+		require.Eventually(t, func() bool {
+			conn, err := net.Dial("tcp", addr)
+			if err == nil {
+				conn.Close()
+			}
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+		resp, err := http.Get("http://" + addr)
+		if err != nil {
+			respCh <- nil
+			return
+		}
+		respCh <- resp
+	}()
+
+	<-started
+	cancel()
+
+	resp := <-respCh
+	require.NotNil(t, resp, "in-flight request must be served")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp.Body.Close()
+
+	assert.NoError(t, <-done)
+	assert.True(t, hookCalled)
+	assert.True(t, workerStopped)
+}
+
+func TestManager_ReadinessDelay(t *testing.T) {
+	addr := freeAddr(t)
+	grpcServer := grpc.NewServer()
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, hs)
+
+	app := lifecycle.New(time.Second, 300*time.Millisecond)
+	app.AddServer("grpc", addr, lifecycle.GRPC(grpcServer))
+	app.OnShutdown(hs.Shutdown)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- app.Run(ctx) }()
+
+	check := func() (healthpb.HealthCheckResponse_ServingStatus, error) {
+		// a new connection each time, as a load balancer probe would open
+		conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			return 0, err
+		}
+		defer conn.Close()
+
+		checkCtx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		resp, err := healthpb.NewHealthClient(conn).Check(checkCtx, &healthpb.HealthCheckRequest{})
+		return resp.GetStatus(), err
+	}
+	require.Eventually(t, func() bool {
+		status, err := check()
+		return err == nil && status == healthpb.HealthCheckResponse_SERVING
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.Eventually(t, func() bool {
+		status, err := check()
+		require.NoError(t, err, "the listener accepts connections during the delay")
+		return status == healthpb.HealthCheckResponse_NOT_SERVING
+	}, 200*time.Millisecond, 10*time.Millisecond)
+
+	assert.NoError(t, <-done)
+	_, err := check()
+	assert.Error(t, err, "the listener is closed after the delay")
+}
+
+func freeAddr(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+	return lis.Addr().String()
+}
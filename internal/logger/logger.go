@@ -0,0 +1,87 @@
+// Package logger builds the process logger and carries request-scoped loggers in context.
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Output formats accepted in config.
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+type ctxKey struct{}
+
+// level is shared by all loggers built with New, so SetLevel applies to the running process.
+var level = new(slog.LevelVar)
+
+// New returns a logger writing to w in the given format ("json" or "text") starting at level
+// ("debug", "info", "warn" or "error"). Empty values mean text and info.
+func New(w io.Writer, format, lvl string) (*slog.Logger, error) {
+	if format != FormatJSON && format != FormatText && format != "" {
+		return nil, errors.Errorf("unknown log format %q", format)
+	}
+
+	err := SetLevel(lvl)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	if format == FormatJSON {
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return slog.New(slog.NewTextHandler(w, opts)), nil
+}
+
+// SetLevel changes the level of every logger built with New; empty means info.
+func SetLevel(lvl string) error {
+	var parsed slog.Level
+	if lvl != "" {
+		err := parsed.UnmarshalText([]byte(strings.ToUpper(lvl)))
+		if err != nil {
+			return errors.Wrapf(err, "unknown log level %q", lvl)
+		}
+	}
+
+	level.Set(parsed)
+	return nil
+}
+
+// WithLogger stores l in ctx.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the request logger, slog.Default() outside of requests.
+// IDs of the current span are added when ctx is traced.
+func FromContext(ctx context.Context) *slog.Logger {
+	l := stored(ctx)
+
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		l = l.With(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+
+	return l
+}
+
+// With adds attributes to the logger in ctx, e.g. the PVZ ID once it's known.
+func With(ctx context.Context, args ...any) context.Context {
+	return WithLogger(ctx, stored(ctx).With(args...))
+}
+
+// stored returns the logger without span IDs, they change with every span and are added on use.
+func stored(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return l
+	}
+
+	return slog.Default()
+}
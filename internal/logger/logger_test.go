@@ -0,0 +1,79 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"trainee-pvz/internal/logger"
+)
+
+func TestNew_JSONWithContextAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := logger.New(&buf, logger.FormatJSON, "warn")
+	require.NoError(t, err)
+
+	ctx := logger.WithLogger(context.Background(), l)
+	ctx = logger.With(ctx, slog.String("request_id", "req-1"))
+
+	logger.FromContext(ctx).Info("skipped")
+	logger.FromContext(ctx).Warn("kept", slog.String("pvz_id", "pvz-1"))
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "kept", record["msg"])
+	assert.Equal(t, "req-1", record["request_id"])
+	assert.Equal(t, "pvz-1", record["pvz_id"])
+}
+
+func TestFromContext_TraceIDs(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := logger.New(&buf, logger.FormatJSON, "")
+	require.NoError(t, err)
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID})
+
+	ctx := logger.WithLogger(context.Background(), l)
+	ctx = trace.ContextWithSpanContext(ctx, sc)
+	logger.FromContext(logger.With(ctx, slog.String("pvz_id", "pvz-1"))).Info("traced")
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, traceID.String(), record["trace_id"])
+	assert.Equal(t, spanID.String(), record["span_id"])
+}
+
+func TestNew_Invalid(t *testing.T) {
+	_, err := logger.New(&bytes.Buffer{}, "xml", "")
+	assert.Error(t, err)
+
+	_, err = logger.New(&bytes.Buffer{}, logger.FormatText, "loud")
+	assert.Error(t, err)
+}
+
+func TestFromContext_Default(t *testing.T) {
+	assert.Equal(t, slog.Default(), logger.FromContext(context.Background()))
+}
+
+func TestSetLevel(t *testing.T) {
+	var buf bytes.Buffer
+	log, err := logger.New(&buf, logger.FormatText, "info")
+	require.NoError(t, err)
+	defer logger.SetLevel("info")
+
+	log.Debug("hidden")
+	require.NoError(t, logger.SetLevel("debug"))
+	log.Debug("shown")
+
+	assert.NotContains(t, buf.String(), "hidden")
+	assert.Contains(t, buf.String(), "shown")
+	assert.Error(t, logger.SetLevel("verbose"))
+}
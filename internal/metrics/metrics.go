@@ -1,11 +1,18 @@
 package metrics
 
 import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 const AppName = "pvz_service"
@@ -16,40 +23,175 @@ const (
 	labelCode   = "code"
 	labelMethod = "method"
 	labelEntity = "entity"
+	labelCity   = "city"
+	labelResult = "result"
 )
 
+// cityGaugeTimeout bounds the DB query made on every scrape.
+const cityGaugeTimeout = 2 * time.Second
+
+var sizeBuckets = prometheus.ExponentialBuckets(64, 4, 8)
+
 type Metrics struct {
-	httpDurationSummary *prometheus.SummaryVec
-	entityCount         *prometheus.CounterVec
+	registry *prometheus.Registry
+
+	httpDuration     *prometheus.HistogramVec
+	httpInFlight     prometheus.Gauge
+	httpRequestSize  *prometheus.HistogramVec
+	httpResponseSize *prometheus.HistogramVec
+
+	grpcHandled  *prometheus.CounterVec
+	grpcDuration *prometheus.HistogramVec
+
+	entityCount *prometheus.CounterVec
+
+	configVersion *prometheus.GaugeVec
+	configReloads *prometheus.CounterVec
 }
 
+// InitMetrics registers all collectors on a private registry, so the metrics can be built more
+// than once (e.g. in tests) without duplicate registration panics.
 func InitMetrics() *Metrics {
-	m := &Metrics{}
-	m.httpDurationSummary = promauto.NewSummaryVec(prometheus.SummaryOpts{
-		Name:       "http_request_duration_summary",
-		Help:       "Duration of HTTP requests Summary.",
-		Objectives: map[float64]float64{0.5: 0.5, 0.9: 0.9, 1: 1},
-		AgeBuckets: 3,
-		MaxAge:     120 * time.Second,
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	factory := promauto.With(reg)
+
+	m := &Metrics{registry: reg}
+	m.httpDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:                            "http_request_duration_seconds",
+		Help:                            "Duration of HTTP requests.",
+		Buckets:                         prometheus.DefBuckets,
+		NativeHistogramBucketFactor:     1.1,
+		NativeHistogramMaxBucketNumber:  100,
+		NativeHistogramMinResetDuration: time.Hour,
 	}, []string{labelApp, labelPath, labelCode, labelMethod})
-	prometheus.Register(m.httpDurationSummary)
 
-	m.entityCount = promauto.NewCounterVec(prometheus.CounterOpts{
+	m.httpInFlight = factory.NewGauge(prometheus.GaugeOpts{
+		Name:        "http_requests_in_flight",
+		Help:        "Number of HTTP requests being served.",
+		ConstLabels: prometheus.Labels{labelApp: AppName},
+	})
+
+	m.httpRequestSize = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_size_bytes",
+		Help:    "Size of HTTP request bodies.",
+		Buckets: sizeBuckets,
+	}, []string{labelApp, labelPath, labelMethod})
+
+	m.httpResponseSize = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_response_size_bytes",
+		Help:    "Size of HTTP response bodies.",
+		Buckets: sizeBuckets,
+	}, []string{labelApp, labelPath, labelMethod})
+
+	m.grpcHandled = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_handled_total",
+		Help: "Count of completed gRPC calls by method and status code.",
+	}, []string{labelApp, labelMethod, labelCode})
+
+	m.grpcDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:                            "grpc_server_handling_seconds",
+		Help:                            "Duration of gRPC calls.",
+		Buckets:                         prometheus.DefBuckets,
+		NativeHistogramBucketFactor:     1.1,
+		NativeHistogramMaxBucketNumber:  100,
+		NativeHistogramMinResetDuration: time.Hour,
+	}, []string{labelApp, labelMethod})
+
+	m.entityCount = factory.NewCounterVec(prometheus.CounterOpts{
 		Name: "created_entity_count",
 		Help: "Count of created business entities.",
 	}, []string{labelApp, labelEntity})
-	prometheus.Register(m.entityCount)
+
+	m.configVersion = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "config_version",
+		Help: "Version of the applied config, grows with every successful reload.",
+	}, []string{labelApp})
+
+	m.configReloads = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "config_reloads_total",
+		Help: "Count of config reload attempts by result.",
+	}, []string{labelApp, labelResult})
 
 	return m
 }
 
+// Registry returns the registry all service metrics are registered on.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RegisterDBStats exports sql.DBStats of a connection pool under the db_name label.
+func (m *Metrics) RegisterDBStats(db *sql.DB, name string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterPoolStats exports pgxpool.Stat of a pgx pool under the db_name label, the pgx
+// counterpart of RegisterDBStats.
+func (m *Metrics) RegisterPoolStats(pool *pgxpool.Pool, name string) {
+	m.registry.MustRegister(newPoolStatsCollector(pool.Stat, name))
+}
+
+// RegisterOpenReceptions exports the number of in-progress receptions per city, read on every scrape.
+func (m *Metrics) RegisterOpenReceptions(count func(ctx context.Context) (map[string]int64, error)) {
+	m.registry.MustRegister(&cityGaugeCollector[int64]{desc: openReceptionsDesc, read: count})
+}
+
+// RegisterCapacityUtilisation exports the share of storage capacity taken per city, read on
+// every scrape. It is kept per city rather than per PVZ, so the number of series stays small.
+func (m *Metrics) RegisterCapacityUtilisation(read func(ctx context.Context) (map[string]float64, error)) {
+	m.registry.MustRegister(&cityGaugeCollector[float64]{desc: capacityUtilisationDesc, read: read})
+}
+
 func (m *Metrics) SaveHTTPDuration(timeSince time.Time, path string, code int, method string) {
-	m.httpDurationSummary.With(map[string]string{
+	m.httpDuration.With(map[string]string{
 		labelApp:    AppName,
 		labelPath:   path,
 		labelCode:   strconv.Itoa(code),
 		labelMethod: method,
-	}).Observe(float64(time.Since(timeSince).Seconds()))
+	}).Observe(time.Since(timeSince).Seconds())
+}
+
+func (m *Metrics) IncHTTPInFlight() {
+	m.httpInFlight.Inc()
+}
+
+func (m *Metrics) DecHTTPInFlight() {
+	m.httpInFlight.Dec()
+}
+
+// SaveHTTPSizes records request and response body sizes; a negative request size (unknown
+// Content-Length) is skipped.
+func (m *Metrics) SaveHTTPSizes(path, method string, requestSize int64, responseSize int) {
+	labels := map[string]string{
+		labelApp:    AppName,
+		labelPath:   path,
+		labelMethod: method,
+	}
+	if requestSize >= 0 {
+		m.httpRequestSize.With(labels).Observe(float64(requestSize))
+	}
+	m.httpResponseSize.With(labels).Observe(float64(responseSize))
+}
+
+func (m *Metrics) SaveGRPCCall(timeSince time.Time, method, code string) {
+	m.grpcHandled.With(map[string]string{
+		labelApp:    AppName,
+		labelMethod: method,
+		labelCode:   code,
+	}).Inc()
+	m.grpcDuration.With(map[string]string{
+		labelApp:    AppName,
+		labelMethod: method,
+	}).Observe(time.Since(timeSince).Seconds())
 }
 
 func (m *Metrics) SaveEntityCount(value float64, entity string) {
@@ -58,3 +200,58 @@ func (m *Metrics) SaveEntityCount(value float64, entity string) {
 		labelEntity: entity,
 	}).Add(value)
 }
+
+func (m *Metrics) SetConfigVersion(version int64) {
+	m.configVersion.With(map[string]string{labelApp: AppName}).Set(float64(version))
+}
+
+func (m *Metrics) SaveConfigReload(ok bool) {
+	result := "success"
+	if !ok {
+		result = "failure"
+	}
+	m.configReloads.With(map[string]string{
+		labelApp:    AppName,
+		labelResult: result,
+	}).Inc()
+}
+
+var openReceptionsDesc = prometheus.NewDesc(
+	"pvz_open_receptions",
+	"Number of in-progress receptions per city.",
+	[]string{labelCity},
+	prometheus.Labels{labelApp: AppName},
+)
+
+var capacityUtilisationDesc = prometheus.NewDesc(
+	"pvz_capacity_utilisation",
+	"Share of storage capacity taken by stored products per city, over active PVZs with a capacity.",
+	[]string{labelCity},
+	prometheus.Labels{labelApp: AppName},
+)
+
+// cityGaugeCollector exports a gauge per city read from the database on every scrape.
+type cityGaugeCollector[V int64 | float64] struct {
+	desc *prometheus.Desc
+	read func(ctx context.Context) (map[string]V, error)
+}
+
+func (c *cityGaugeCollector[V]) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *cityGaugeCollector[V]) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), cityGaugeTimeout)
+	defer cancel()
+
+	values, err := c.read(ctx)
+	if err != nil {
+		slog.Error("failed to collect city gauge", slog.String("metric", c.desc.String()), slog.Any("err", err))
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+
+	for city, v := range values {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(v), city)
+	}
+}
@@ -0,0 +1,95 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"trainee-pvz/internal/metrics"
+)
+
+func TestInitMetrics_Twice(t *testing.T) {
+	assert.NotPanics(t, func() {
+		metrics.InitMetrics()
+		metrics.InitMetrics()
+	})
+}
+
+func TestMetrics_HTTP(t *testing.T) {
+	m := metrics.InitMetrics()
+
+	m.IncHTTPInFlight()
+	m.SaveHTTPDuration(time.Now(), "/pvz", 200, "GET")
+	m.SaveHTTPSizes("/pvz", "GET", -1, 512)
+
+	count, err := testutil.GatherAndCount(m.Registry(),
+		"http_request_duration_seconds", "http_requests_in_flight", "http_request_size_bytes", "http_response_size_bytes")
+	require.NoError(t, err)
+	assert.Equal(t, 3, count, "unknown request size must not be observed")
+}
+
+func TestMetrics_OpenReceptions(t *testing.T) {
+	m := metrics.InitMetrics()
+	m.RegisterOpenReceptions(func(ctx context.Context) (map[string]int64, error) {
+		return map[string]int64{"Москва": 2, "Казань": 1}, nil
+	})
+
+	expected := `
+# HELP pvz_open_receptions Number of in-progress receptions per city.
+# TYPE pvz_open_receptions gauge
+pvz_open_receptions{app="pvz_service",city="Казань"} 1
+pvz_open_receptions{app="pvz_service",city="Москва"} 2
+`
+	err := testutil.GatherAndCompare(m.Registry(), strings.NewReader(expected), "pvz_open_receptions")
+	assert.NoError(t, err)
+}
+
+func TestMetrics_CapacityUtilisation(t *testing.T) {
+	m := metrics.InitMetrics()
+	m.RegisterCapacityUtilisation(func(ctx context.Context) (map[string]float64, error) {
+		return map[string]float64{"Москва": 0.75}, nil
+	})
+
+	expected := `
+# HELP pvz_capacity_utilisation Share of storage capacity taken by stored products per city, over active PVZs with a capacity.
+# TYPE pvz_capacity_utilisation gauge
+pvz_capacity_utilisation{app="pvz_service",city="Москва"} 0.75
+`
+	err := testutil.GatherAndCompare(m.Registry(), strings.NewReader(expected), "pvz_capacity_utilisation")
+	assert.NoError(t, err)
+}
+
+func TestMetrics_OpenReceptionsError(t *testing.T) {
+	m := metrics.InitMetrics()
+	m.RegisterOpenReceptions(func(ctx context.Context) (map[string]int64, error) {
+		return nil, errors.New("db down")
+	})
+
+	_, err := m.Registry().Gather()
+	assert.ErrorContains(t, err, "db down")
+}
+
+func TestMetrics_PoolStats(t *testing.T) {
+	// the pool connects lazily, so the stats are readable without a database
+	pool, err := pgxpool.New(context.Background(), "postgres://localhost:1/pvz?pool_max_conns=7")
+	require.NoError(t, err)
+	defer pool.Close()
+
+	m := metrics.InitMetrics()
+	m.RegisterPoolStats(pool, "primary")
+
+	expected := `
+# HELP pgxpool_max_conns Maximum size of the pool.
+# TYPE pgxpool_max_conns gauge
+pgxpool_max_conns{db_name="primary"} 7
+`
+	err = testutil.GatherAndCompare(m.Registry(), strings.NewReader(expected), "pgxpool_max_conns")
+	assert.NoError(t, err)
+}
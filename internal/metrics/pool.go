@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type poolStatsCollector struct {
+	stat func() *pgxpool.Stat
+
+	maxConns         *prometheus.Desc
+	totalConns       *prometheus.Desc
+	idleConns        *prometheus.Desc
+	acquiredConns    *prometheus.Desc
+	acquires         *prometheus.Desc
+	emptyAcquires    *prometheus.Desc
+	acquireDurations *prometheus.Desc
+}
+
+func newPoolStatsCollector(stat func() *pgxpool.Stat, name string) *poolStatsCollector {
+	labels := prometheus.Labels{"db_name": name}
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc("pgxpool_"+metric, help, nil, labels)
+	}
+
+	return &poolStatsCollector{
+		stat:             stat,
+		maxConns:         desc("max_conns", "Maximum size of the pool."),
+		totalConns:       desc("total_conns", "Number of connections in the pool, including the ones being opened."),
+		idleConns:        desc("idle_conns", "Number of idle connections."),
+		acquiredConns:    desc("acquired_conns", "Number of connections in use."),
+		acquires:         desc("acquires_total", "Count of successful connection acquires."),
+		emptyAcquires:    desc("empty_acquires_total", "Count of acquires that had to wait for a connection."),
+		acquireDurations: desc("acquire_duration_seconds_total", "Total time spent acquiring connections."),
+	}
+}
+
+func (c *poolStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxConns
+	ch <- c.totalConns
+	ch <- c.idleConns
+	ch <- c.acquiredConns
+	ch <- c.acquires
+	ch <- c.emptyAcquires
+	ch <- c.acquireDurations
+}
+
+func (c *poolStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stat()
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquires, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquires, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDurations, prometheus.CounterValue, s.AcquireDuration().Seconds())
+}
@@ -1,29 +1,19 @@
 package metrics
 
 import (
-	"fmt"
-
-	"github.com/go-chi/chi/v5"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
-
-	"log/slog"
 	"net/http"
 	"time"
+
+	"github.com/go-chi/chi/v5"
 )
 
-func RunMetricServer(listenAddress string) {
+// NewServer serves handler on /metrics; listening and shutdown are up to the caller.
+func NewServer(handler http.Handler) *http.Server {
 	mh := chi.NewRouter()
-	mh.HandleFunc("/metrics", promhttp.Handler().ServeHTTP)
-	srv := &http.Server{
-		Addr:        fmt.Sprintf(":%s", listenAddress),
+	mh.HandleFunc("/metrics", handler.ServeHTTP)
+
+	return &http.Server{
 		Handler:     mh,
 		ReadTimeout: 1 * time.Second,
 	}
-
-	slog.Info("starting Metric exporter server: listening on", slog.String("address", listenAddress))
-
-	err := srv.ListenAndServe()
-	if err != http.ErrServerClosed {
-		slog.Error("failed to listen promhandler server", "error", err)
-	}
 }
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audited entities.
+const (
+	AuditEntityUser      = "user"
+	AuditEntityPVZ       = "pvz"
+	AuditEntityReception = "reception"
+	AuditEntityProduct   = "product"
+)
+
+// Audited actions, named <entity>.<verb>.
+const (
+	AuditUserRegister    = "user.register"
+	AuditPVZCreate       = "pvz.create"
+	AuditPVZImport       = "pvz.import"
+	AuditPVZUpdate       = "pvz.update"
+	AuditPVZArchive      = "pvz.archive"
+	AuditPVZRestore      = "pvz.restore"
+	AuditReceptionCreate = "reception.create"
+	AuditReceptionClose  = "reception.close"
+	AuditProductAdd      = "product.add"
+	AuditProductDelete   = "product.delete"
+	AuditProductIssue    = "product.issue"
+)
+
+// AuditEntry is a state change with the entity row before and after it, Before is empty
+// for created entities and After for deleted ones.
+type AuditEntry struct {
+	ID         int64           `db:"id"`
+	CreatedAt  time.Time       `db:"created_at"`
+	ActorID    *string         `db:"actor_id"`
+	ActorRole  *string         `db:"actor_role"`
+	Action     string          `db:"action"`
+	EntityType string          `db:"entity_type"`
+	EntityID   string          `db:"entity_id"`
+	Before     json.RawMessage `db:"before"`
+	After      json.RawMessage `db:"after"`
+	RequestID  *string         `db:"request_id"`
+	IP         *string         `db:"ip"`
+}
+
+// AuditFilter narrows the audit log, empty fields are not applied.
+type AuditFilter struct {
+	ActorID    string
+	Action     string
+	EntityType string
+	EntityID   string
+	From       *time.Time
+	To         *time.Time
+}
+
+type AuditPage struct {
+	Items      []AuditEntry
+	NextCursor string
+}
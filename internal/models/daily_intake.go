@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// DailyIntakeMismatch is a day/PVZ/type whose stored daily_intake counter differs from the
+// number of products actually received.
+type DailyIntakeMismatch struct {
+	Day         time.Time `db:"day"`
+	PVZID       string    `db:"pvz_id"`
+	ProductType string    `db:"type"`
+	Stored      int       `db:"stored"`
+	Actual      int       `db:"actual"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Export formats accepted by the receptions export.
+const (
+	ExportCSV  = "csv"
+	ExportXLSX = "xlsx"
+)
+
+// ExportQuery selects receptions of one PVZ opened in [From, To).
+type ExportQuery struct {
+	PVZID  string
+	From   time.Time
+	To     time.Time
+	Format string
+}
+
+// ExportRow is one product of a reception, product fields are nil for receptions without products.
+type ExportRow struct {
+	ReceptionID       string     `db:"reception_id"`
+	ReceptionDateTime time.Time  `db:"reception_datetime"`
+	ReceptionStatus   string     `db:"reception_status"`
+	ReceptionClosedAt *time.Time `db:"reception_closed_at"`
+	City              string     `db:"city"`
+	EmployeeEmail     *string    `db:"employee_email"`
+	ProductID         *string    `db:"product_id"`
+	ProductType       *string    `db:"product_type"`
+	ProductDateTime   *time.Time `db:"product_datetime"`
+	ProductIssuedAt   *time.Time `db:"product_issued_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+const (
+	CheckDatabase   = "database"
+	CheckMigrations = "migrations"
+	CheckShutdown   = "shutdown"
+
+	CheckOK = "ok"
+)
+
+// Readiness is the result of the readiness checks: check name to "ok" or the failure reason.
+type Readiness struct {
+	Ready  bool
+	Checks map[string]string
+}
@@ -0,0 +1,17 @@
+package models
+
+// PVZImportRowError describes why a line of the import file was rejected.
+// Row is the line number in the file, the header is line 1.
+type PVZImportRowError struct {
+	Row     int
+	Message string
+}
+
+// PVZImportResult is the outcome of a bulk import. Nothing is created when there are errors
+// or in dry-run mode, Items are the PVZs that were (or would be) created.
+type PVZImportResult struct {
+	DryRun   bool
+	Imported int
+	Items    []PVZ
+	Errors   []PVZImportRowError
+}
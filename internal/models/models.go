@@ -10,21 +10,76 @@ type User struct {
 }
 
 type PVZ struct {
-	ID               string    `db:"id"`
-	RegistrationDate time.Time `db:"registration_date"`
-	City             string    `db:"city"`
+	ID               string       `db:"id"`
+	RegistrationDate time.Time    `db:"registration_date"`
+	City             string       `db:"city"`
+	ArchivedAt       *time.Time   `db:"archived_at"`
+	Address          string       `db:"address"`
+	Latitude         *float64     `db:"latitude"`
+	Longitude        *float64     `db:"longitude"`
+	WorkingHours     WorkingHours `db:"working_hours"`
+	Capacity         *int         `db:"capacity"`
+	StoredItems      int          `db:"stored_items"`
+	ExternalCode     *string      `db:"external_code"`
+}
+
+// PVZUpdate holds editable PVZ profile fields, nil means "leave as is".
+type PVZUpdate struct {
+	Address      *string
+	Latitude     *float64
+	Longitude    *float64
+	WorkingHours WorkingHours
+	Capacity     *int
+}
+
+type GeoQuery struct {
+	Latitude  float64
+	Longitude float64
+	RadiusKm  float64
+	Limit     int
+}
+
+type NearbyPVZ struct {
+	PVZ
+	DistanceKm float64 `db:"distance_km"`
+}
+
+type PVZFilter struct {
+	StartDate       *time.Time
+	EndDate         *time.Time
+	IncludeArchived bool
+}
+
+// PVZPage is one page of PVZ list, NextCursor is empty on the last page.
+type PVZPage struct {
+	Items      []PVZ
+	NextCursor string
 }
 
 type Reception struct {
-	ID       string    `db:"id"`
-	DateTime time.Time `db:"datetime"`
-	PVZID    string    `db:"pvz_id"`
-	Status   string    `db:"status"`
+	ID        string     `db:"id"`
+	DateTime  time.Time  `db:"datetime"`
+	PVZID     string     `db:"pvz_id"`
+	Status    string     `db:"status"`
+	ClosedAt  *time.Time `db:"closed_at"`
+	CreatedBy *string    `db:"created_by"`
 }
 
 type Product struct {
-	ID          string    `db:"id"`
-	DateTime    time.Time `db:"datetime"`
-	Type        string    `db:"type"`
-	ReceptionID string    `db:"reception_id"`
+	ID          string     `db:"id"`
+	DateTime    time.Time  `db:"datetime"`
+	Type        string     `db:"type"`
+	ReceptionID string     `db:"reception_id"`
+	IssuedAt    *time.Time `db:"issued_at"`
+}
+
+// StorageUsage is the live number of products kept in a PVZ against its capacity.
+type StorageUsage struct {
+	PVZID       string `db:"id"`
+	StoredItems int    `db:"stored_items"`
+	Capacity    *int   `db:"capacity"`
+}
+
+func (u StorageUsage) OverCapacity() bool {
+	return u.Capacity != nil && u.StoredItems > *u.Capacity
 }
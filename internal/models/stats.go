@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Dimensions and buckets accepted by the intake report.
+const (
+	GroupByPVZ  = "pvz"
+	GroupByCity = "city"
+	GroupByType = "type"
+
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+)
+
+// IntakeQuery selects products received in [From, To) grouped by time bucket and GroupBy dimensions.
+type IntakeQuery struct {
+	From    time.Time
+	To      time.Time
+	Bucket  string
+	GroupBy []string
+}
+
+// IntakeRow is a number of received products in one bucket, dimensions that were not
+// requested in GroupBy are nil.
+type IntakeRow struct {
+	Bucket      time.Time `db:"bucket"`
+	PVZID       *string   `db:"pvz_id"`
+	City        *string   `db:"city"`
+	ProductType *string   `db:"type"`
+	Products    int       `db:"products"`
+}
+
+type ReceptionSummary struct {
+	Receptions              int     `db:"receptions"`
+	AvgDurationSeconds      float64 `db:"avg_duration_seconds"`
+	AvgProductsPerReception float64 `db:"avg_products"`
+}
+
+type IntakeReport struct {
+	Query   IntakeQuery
+	Rows    []IntakeRow
+	Summary ReceptionSummary
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// Weekdays lists the keys allowed in WorkingHours, starting from Monday.
+var Weekdays = []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+
+type DayHours struct {
+	Open  string `json:"open"`
+	Close string `json:"close"`
+}
+
+// WorkingHours maps a weekday key ("mon".."sun") to opening hours in "HH:MM" format.
+// A missing day means the PVZ is closed that day. Stored as JSONB.
+type WorkingHours map[string]DayHours
+
+func (h WorkingHours) Value() (driver.Value, error) {
+	if h == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(h)
+}
+
+func (h *WorkingHours) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*h = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, h)
+	case string:
+		return json.Unmarshal([]byte(v), h)
+	default:
+		return errors.Errorf("can't scan %T into WorkingHours", src)
+	}
+}
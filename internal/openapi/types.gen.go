@@ -4,6 +4,7 @@
 package openapi
 
 import (
+	"encoding/json"
 	"time"
 
 	openapi_types "github.com/oapi-codegen/runtime/types"
@@ -13,6 +14,34 @@ const (
 	BearerAuthScopes = "bearerAuth.Scopes"
 )
 
+// Defines values for AuditEntryEntityType.
+const (
+	AuditEntryEntityTypeProduct   AuditEntryEntityType = "product"
+	AuditEntryEntityTypePvz       AuditEntryEntityType = "pvz"
+	AuditEntryEntityTypeReception AuditEntryEntityType = "reception"
+	AuditEntryEntityTypeUser      AuditEntryEntityType = "user"
+)
+
+// Defines values for HealthStatus.
+const (
+	NotReady HealthStatus = "not ready"
+	Ok       HealthStatus = "ok"
+)
+
+// Defines values for IntakeReportBucket.
+const (
+	IntakeReportBucketDay   IntakeReportBucket = "day"
+	IntakeReportBucketMonth IntakeReportBucket = "month"
+	IntakeReportBucketWeek  IntakeReportBucket = "week"
+)
+
+// Defines values for IntakeReportGroupBy.
+const (
+	IntakeReportGroupByCity IntakeReportGroupBy = "city"
+	IntakeReportGroupByPvz  IntakeReportGroupBy = "pvz"
+	IntakeReportGroupByType IntakeReportGroupBy = "type"
+)
+
 // Defines values for PVZCity.
 const (
 	Казань         PVZCity = "Казань"
@@ -39,12 +68,26 @@ const (
 	UserRoleModerator UserRole = "moderator"
 )
 
+// Defines values for GetAuditParamsEntityType.
+const (
+	GetAuditParamsEntityTypeProduct   GetAuditParamsEntityType = "product"
+	GetAuditParamsEntityTypePvz       GetAuditParamsEntityType = "pvz"
+	GetAuditParamsEntityTypeReception GetAuditParamsEntityType = "reception"
+	GetAuditParamsEntityTypeUser      GetAuditParamsEntityType = "user"
+)
+
 // Defines values for PostDummyLoginJSONBodyRole.
 const (
 	PostDummyLoginJSONBodyRoleEmployee  PostDummyLoginJSONBodyRole = "employee"
 	PostDummyLoginJSONBodyRoleModerator PostDummyLoginJSONBodyRole = "moderator"
 )
 
+// Defines values for GetExportReceptionsParamsFormat.
+const (
+	Csv  GetExportReceptionsParamsFormat = "csv"
+	Xlsx GetExportReceptionsParamsFormat = "xlsx"
+)
+
 // Defines values for PostProductsJSONBodyType.
 const (
 	PostProductsJSONBodyTypeОбувь       PostProductsJSONBodyType = "обувь"
@@ -52,31 +95,214 @@ const (
 	PostProductsJSONBodyTypeЭлектроника PostProductsJSONBodyType = "электроника"
 )
 
+// Defines values for PostProductsBatchJSONBodyProductsType.
+const (
+	PostProductsBatchJSONBodyProductsTypeОбувь       PostProductsBatchJSONBodyProductsType = "обувь"
+	PostProductsBatchJSONBodyProductsTypeОдежда      PostProductsBatchJSONBodyProductsType = "одежда"
+	PostProductsBatchJSONBodyProductsTypeЭлектроника PostProductsBatchJSONBodyProductsType = "электроника"
+)
+
 // Defines values for PostRegisterJSONBodyRole.
 const (
 	Employee  PostRegisterJSONBodyRole = "employee"
 	Moderator PostRegisterJSONBodyRole = "moderator"
 )
 
-// Error defines model for Error.
+// Defines values for GetStatsIntakeParamsGroupBy.
+const (
+	GetStatsIntakeParamsGroupByCity GetStatsIntakeParamsGroupBy = "city"
+	GetStatsIntakeParamsGroupByPvz  GetStatsIntakeParamsGroupBy = "pvz"
+	GetStatsIntakeParamsGroupByType GetStatsIntakeParamsGroupBy = "type"
+)
+
+// Defines values for GetStatsIntakeParamsBucket.
+const (
+	GetStatsIntakeParamsBucketDay   GetStatsIntakeParamsBucket = "day"
+	GetStatsIntakeParamsBucketMonth GetStatsIntakeParamsBucket = "month"
+	GetStatsIntakeParamsBucketWeek  GetStatsIntakeParamsBucket = "week"
+)
+
+// AuditEntry defines model for AuditEntry.
+type AuditEntry struct {
+	Action string `json:"action"`
+
+	// ActorId Отсутствует для dummy-токенов
+	ActorId   *openapi_types.UUID `json:"actorId,omitempty"`
+	ActorRole *string             `json:"actorRole,omitempty"`
+
+	// After Строка сущности после изменения, отсутствует при удалении
+	After *json.RawMessage `json:"after,omitempty"`
+
+	// Before Строка сущности до изменения, отсутствует при создании
+	Before     *json.RawMessage     `json:"before,omitempty"`
+	CreatedAt  time.Time            `json:"createdAt"`
+	EntityId   string               `json:"entityId"`
+	EntityType AuditEntryEntityType `json:"entityType"`
+	Id         int64                `json:"id"`
+	Ip         *string              `json:"ip,omitempty"`
+	RequestId  *string              `json:"requestId,omitempty"`
+}
+
+// AuditEntryEntityType defines model for AuditEntry.EntityType.
+type AuditEntryEntityType string
+
+// AuditPage defines model for AuditPage.
+type AuditPage struct {
+	Items []AuditEntry `json:"items"`
+
+	// NextCursor Курсор следующей страницы, отсутствует на последней странице
+	NextCursor *string `json:"nextCursor,omitempty"`
+}
+
+// DayHours defines model for DayHours.
+type DayHours struct {
+	Close string `json:"close"`
+	Open  string `json:"open"`
+}
+
+// Error Problem details (RFC 7807), отдаются с Content-Type application/problem+json
 type Error struct {
+	// Code Стабильный код ошибки, по нему клиент выбирает реакцию
+	Code   string `json:"code"`
+	Detail string `json:"detail"`
+
+	// Errors Ошибки в полях тела запроса, если оно не прошло проверку по схеме
+	Errors *[]FieldError `json:"errors,omitempty"`
+
+	// Instance Путь запроса
+	Instance *string `json:"instance,omitempty"`
+
+	// Message То же, что detail, для старых клиентов
+	Message string `json:"message"`
+	Status  int    `json:"status"`
+	Title   string `json:"title"`
+	Type    string `json:"type"`
+}
+
+// FieldError defines model for FieldError.
+type FieldError struct {
+	// Field Путь к полю через точку, например workingHours.mon.open
+	Field   string `json:"field"`
 	Message string `json:"message"`
 }
 
+// Health defines model for Health.
+type Health struct {
+	// Checks Результат каждой проверки, "ok" или причина отказа
+	Checks *map[string]string `json:"checks,omitempty"`
+	Status HealthStatus       `json:"status"`
+}
+
+// HealthStatus defines model for Health.Status.
+type HealthStatus string
+
+// IntakeReport defines model for IntakeReport.
+type IntakeReport struct {
+	AvgProductsPerReception float64 `json:"avgProductsPerReception"`
+
+	// AvgReceptionDurationSeconds Средняя длительность закрытых приемок
+	AvgReceptionDurationSeconds float64               `json:"avgReceptionDurationSeconds"`
+	Bucket                      IntakeReportBucket    `json:"bucket"`
+	From                        time.Time             `json:"from"`
+	GroupBy                     []IntakeReportGroupBy `json:"groupBy"`
+
+	// Receptions Количество приемок, начатых в периоде
+	Receptions int         `json:"receptions"`
+	Rows       []IntakeRow `json:"rows"`
+	To         time.Time   `json:"to"`
+}
+
+// IntakeReportBucket defines model for IntakeReport.Bucket.
+type IntakeReportBucket string
+
+// IntakeReportGroupBy defines model for IntakeReport.GroupBy.
+type IntakeReportGroupBy string
+
+// IntakeRow Количество принятых товаров в интервале. Поля измерений, не указанных в groupBy, отсутствуют
+type IntakeRow struct {
+	Bucket   time.Time           `json:"bucket"`
+	City     *string             `json:"city,omitempty"`
+	Products int                 `json:"products"`
+	PvzId    *openapi_types.UUID `json:"pvzId,omitempty"`
+	Type     *string             `json:"type,omitempty"`
+}
+
+// NearbyPVZ defines model for NearbyPVZ.
+type NearbyPVZ struct {
+	DistanceKm float64 `json:"distanceKm"`
+	Pvz        PVZ     `json:"pvz"`
+}
+
 // PVZ defines model for PVZ.
 type PVZ struct {
-	City             PVZCity             `json:"city"`
+	Address    *string    `json:"address,omitempty"`
+	ArchivedAt *time.Time `json:"archivedAt,omitempty"`
+
+	// Capacity Максимальное количество товаров на хранении
+	Capacity *int    `json:"capacity,omitempty"`
+	City     PVZCity `json:"city"`
+
+	// ExternalCode Уникальный код ПВЗ во внешней системе
+	ExternalCode     *string             `json:"externalCode,omitempty"`
 	Id               *openapi_types.UUID `json:"id,omitempty"`
+	Latitude         *float64            `json:"latitude,omitempty"`
+	Longitude        *float64            `json:"longitude,omitempty"`
 	RegistrationDate *time.Time          `json:"registrationDate,omitempty"`
+
+	// StoredItems Количество товаров на хранении (принятые и еще не выданные)
+	StoredItems *int `json:"storedItems,omitempty"`
+
+	// WorkingHours Часы работы по дням недели, отсутствующий день — выходной
+	WorkingHours *WorkingHours `json:"workingHours,omitempty"`
 }
 
 // PVZCity defines model for PVZ.City.
 type PVZCity string
 
+// PVZImportResult defines model for PVZImportResult.
+type PVZImportResult struct {
+	DryRun bool                `json:"dryRun"`
+	Errors []PVZImportRowError `json:"errors"`
+
+	// Imported Количество созданных ПВЗ, 0 при ошибках и в режиме dryRun
+	Imported int `json:"imported"`
+
+	// Items ПВЗ, которые созданы (или были бы созданы в режиме dryRun)
+	Items []PVZ `json:"items"`
+}
+
+// PVZImportRowError defines model for PVZImportRowError.
+type PVZImportRowError struct {
+	Message string `json:"message"`
+
+	// Row Номер строки файла, заголовок - строка 1
+	Row int `json:"row"`
+}
+
+// PVZPage defines model for PVZPage.
+type PVZPage struct {
+	Items []PVZ `json:"items"`
+
+	// NextCursor Курсор следующей страницы, отсутствует на последней странице
+	NextCursor *string `json:"nextCursor,omitempty"`
+}
+
+// PVZUpdate defines model for PVZUpdate.
+type PVZUpdate struct {
+	Address   *string  `json:"address,omitempty"`
+	Capacity  *int     `json:"capacity,omitempty"`
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+
+	// WorkingHours Часы работы по дням недели, отсутствующий день — выходной
+	WorkingHours *WorkingHours `json:"workingHours,omitempty"`
+}
+
 // Product defines model for Product.
 type Product struct {
 	DateTime    *time.Time          `json:"dateTime,omitempty"`
 	Id          *openapi_types.UUID `json:"id,omitempty"`
+	IssuedAt    *time.Time          `json:"issuedAt,omitempty"`
 	ReceptionId openapi_types.UUID  `json:"receptionId"`
 	Type        ProductType         `json:"type"`
 }
@@ -108,6 +334,38 @@ type User struct {
 // UserRole defines model for User.Role.
 type UserRole string
 
+// WorkingHours Часы работы по дням недели, отсутствующий день — выходной
+type WorkingHours struct {
+	Fri *DayHours `json:"fri,omitempty"`
+	Mon *DayHours `json:"mon,omitempty"`
+	Sat *DayHours `json:"sat,omitempty"`
+	Sun *DayHours `json:"sun,omitempty"`
+	Thu *DayHours `json:"thu,omitempty"`
+	Tue *DayHours `json:"tue,omitempty"`
+	Wed *DayHours `json:"wed,omitempty"`
+}
+
+// GetAuditParams defines parameters for GetAudit.
+type GetAuditParams struct {
+	ActorId    *openapi_types.UUID       `form:"actorId,omitempty" json:"actorId,omitempty"`
+	Action     *string                   `form:"action,omitempty" json:"action,omitempty"`
+	EntityType *GetAuditParamsEntityType `form:"entityType,omitempty" json:"entityType,omitempty"`
+	EntityId   *string                   `form:"entityId,omitempty" json:"entityId,omitempty"`
+
+	// From Начало периода (включительно)
+	From *time.Time `form:"from,omitempty" json:"from,omitempty"`
+
+	// To Конец периода (не включительно)
+	To *time.Time `form:"to,omitempty" json:"to,omitempty"`
+
+	// Cursor Курсор из nextCursor предыдущей страницы
+	Cursor *string `form:"cursor,omitempty" json:"cursor,omitempty"`
+	Limit  *int    `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// GetAuditParamsEntityType defines parameters for GetAudit.
+type GetAuditParamsEntityType string
+
 // PostDummyLoginJSONBody defines parameters for PostDummyLogin.
 type PostDummyLoginJSONBody struct {
 	Role PostDummyLoginJSONBodyRole `json:"role"`
@@ -116,6 +374,21 @@ type PostDummyLoginJSONBody struct {
 // PostDummyLoginJSONBodyRole defines parameters for PostDummyLogin.
 type PostDummyLoginJSONBodyRole string
 
+// GetExportReceptionsParams defines parameters for GetExportReceptions.
+type GetExportReceptionsParams struct {
+	PvzId openapi_types.UUID `form:"pvzId" json:"pvzId"`
+
+	// From Начало периода (включительно), по умолчанию 30 дней назад
+	From *time.Time `form:"from,omitempty" json:"from,omitempty"`
+
+	// To Конец периода (не включительно), по умолчанию текущий момент
+	To     *time.Time                       `form:"to,omitempty" json:"to,omitempty"`
+	Format *GetExportReceptionsParamsFormat `form:"format,omitempty" json:"format,omitempty"`
+}
+
+// GetExportReceptionsParamsFormat defines parameters for GetExportReceptions.
+type GetExportReceptionsParamsFormat string
+
 // PostLoginJSONBody defines parameters for PostLogin.
 type PostLoginJSONBody struct {
 	Email    openapi_types.Email `json:"email"`
@@ -131,6 +404,17 @@ type PostProductsJSONBody struct {
 // PostProductsJSONBodyType defines parameters for PostProducts.
 type PostProductsJSONBodyType string
 
+// PostProductsBatchJSONBody defines parameters for PostProductsBatch.
+type PostProductsBatchJSONBody struct {
+	Products []struct {
+		Type PostProductsBatchJSONBodyProductsType `json:"type"`
+	} `json:"products"`
+	PvzId openapi_types.UUID `json:"pvzId"`
+}
+
+// PostProductsBatchJSONBodyProductsType defines parameters for PostProductsBatch.
+type PostProductsBatchJSONBodyProductsType string
+
 // GetPvzParams defines parameters for GetPvz.
 type GetPvzParams struct {
 	// StartDate Начальная дата диапазона
@@ -139,11 +423,42 @@ type GetPvzParams struct {
 	// EndDate Конечная дата диапазона
 	EndDate *time.Time `form:"endDate,omitempty" json:"endDate,omitempty"`
 
-	// Page Номер страницы
+	// Cursor Непрозрачный курсор из nextCursor предыдущей страницы, для первой страницы передаётся пустым (cursor=).
+	// Без cursor ответ до следующего релиза имеет прежний вид — массив PVZ без nextCursor — и заголовки
+	// Deprecation и Warning; после него cursor станет необязательным для страницы.
+	Cursor *string `form:"cursor,omitempty" json:"cursor,omitempty"`
+
+	// Page Устарел, используйте cursor; будет удалён в следующем релизе.
+	// Номер страницы по limit элементов (по умолчанию 1) для ответа прежнего вида. Вместе с cursor не передаётся.
+	// Deprecated: this property has been marked as deprecated upstream, but no `x-deprecated-reason` was set
 	Page *int `form:"page,omitempty" json:"page,omitempty"`
 
 	// Limit Количество элементов на странице
 	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// IncludeArchived Включать архивные ПВЗ
+	IncludeArchived *bool `form:"includeArchived,omitempty" json:"includeArchived,omitempty"`
+}
+
+// PostPvzImportParams defines parameters for PostPvzImport.
+type PostPvzImportParams struct {
+	// DryRun Только проверить файл, ничего не создавая
+	DryRun *bool `form:"dryRun,omitempty" json:"dryRun,omitempty"`
+}
+
+// GetPvzNearbyParams defines parameters for GetPvzNearby.
+type GetPvzNearbyParams struct {
+	// Lat Широта точки
+	Lat float64 `form:"lat" json:"lat"`
+
+	// Lon Долгота точки
+	Lon float64 `form:"lon" json:"lon"`
+
+	// RadiusKm Радиус поиска в километрах
+	RadiusKm *float64 `form:"radiusKm,omitempty" json:"radiusKm,omitempty"`
+
+	// Limit Максимальное количество ПВЗ в ответе
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
 }
 
 // PostReceptionsJSONBody defines parameters for PostReceptions.
@@ -161,6 +476,27 @@ type PostRegisterJSONBody struct {
 // PostRegisterJSONBodyRole defines parameters for PostRegister.
 type PostRegisterJSONBodyRole string
 
+// GetStatsIntakeParams defines parameters for GetStatsIntake.
+type GetStatsIntakeParams struct {
+	// From Начало периода (включительно), по умолчанию неделя назад
+	From *time.Time `form:"from,omitempty" json:"from,omitempty"`
+
+	// To Конец периода (не включительно), по умолчанию текущий момент
+	To *time.Time `form:"to,omitempty" json:"to,omitempty"`
+
+	// GroupBy Измерения группировки через запятую
+	GroupBy *[]GetStatsIntakeParamsGroupBy `form:"groupBy,omitempty" json:"groupBy,omitempty"`
+
+	// Bucket Интервал агрегации
+	Bucket *GetStatsIntakeParamsBucket `form:"bucket,omitempty" json:"bucket,omitempty"`
+}
+
+// GetStatsIntakeParamsGroupBy defines parameters for GetStatsIntake.
+type GetStatsIntakeParamsGroupBy string
+
+// GetStatsIntakeParamsBucket defines parameters for GetStatsIntake.
+type GetStatsIntakeParamsBucket string
+
 // PostDummyLoginJSONRequestBody defines body for PostDummyLogin for application/json ContentType.
 type PostDummyLoginJSONRequestBody PostDummyLoginJSONBody
 
@@ -170,9 +506,15 @@ type PostLoginJSONRequestBody PostLoginJSONBody
 // PostProductsJSONRequestBody defines body for PostProducts for application/json ContentType.
 type PostProductsJSONRequestBody PostProductsJSONBody
 
+// PostProductsBatchJSONRequestBody defines body for PostProductsBatch for application/json ContentType.
+type PostProductsBatchJSONRequestBody PostProductsBatchJSONBody
+
 // PostPvzJSONRequestBody defines body for PostPvz for application/json ContentType.
 type PostPvzJSONRequestBody = PVZ
 
+// PatchPvzPvzIdJSONRequestBody defines body for PatchPvzPvzId for application/json ContentType.
+type PatchPvzPvzIdJSONRequestBody = PVZUpdate
+
 // PostReceptionsJSONRequestBody defines body for PostReceptions for application/json ContentType.
 type PostReceptionsJSONRequestBody PostReceptionsJSONBody
 
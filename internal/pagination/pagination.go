@@ -0,0 +1,71 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	er "trainee-pvz/internal/errors"
+)
+
+// Cursor points at the last row of a page in the (registration_date DESC, id DESC) order.
+type Cursor struct {
+	RegistrationDate time.Time
+	ID               string
+}
+
+// Encode returns an opaque URL-safe token for the cursor.
+func (c Cursor) Encode() string {
+	raw := c.RegistrationDate.UTC().Format(time.RFC3339Nano) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Decode parses a token produced by Cursor.Encode.
+func Decode(token string) (Cursor, error) {
+	var c Cursor
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, errors.Wrap(er.ErrInvalidCursor, "bad encoding")
+	}
+
+	date, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return c, errors.Wrap(er.ErrInvalidCursor, "bad format")
+	}
+
+	c.RegistrationDate, err = time.Parse(time.RFC3339Nano, date)
+	if err != nil {
+		return c, errors.Wrap(er.ErrInvalidCursor, "bad date")
+	}
+
+	if _, err := uuid.Parse(id); err != nil {
+		return c, errors.Wrap(er.ErrInvalidCursor, "bad id")
+	}
+	c.ID = id
+
+	return c, nil
+}
+
+// Limits bounds the page size a client may ask for.
+type Limits struct {
+	Default int
+	Max     int
+}
+
+// Resolve returns the page size to use: Default when nothing was requested,
+// ErrInvalidLimit when the request is out of [1, Max].
+func (l Limits) Resolve(requested int) (int, error) {
+	if requested == 0 {
+		return l.Default, nil
+	}
+
+	if requested < 0 || requested > l.Max {
+		return 0, er.ErrInvalidLimit
+	}
+
+	return requested, nil
+}
@@ -0,0 +1,48 @@
+package pagination_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/pagination"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	c := pagination.Cursor{
+		RegistrationDate: time.Date(2025, 4, 16, 9, 28, 11, 123456000, time.UTC),
+		ID:               "0b7e6a57-8f0a-4c1b-9a57-2f5d2c6e8a11",
+	}
+
+	decoded, err := pagination.Decode(c.Encode())
+	assert.NoError(t, err)
+	assert.True(t, c.RegistrationDate.Equal(decoded.RegistrationDate))
+	assert.Equal(t, c.ID, decoded.ID)
+}
+
+func TestCursor_DecodeInvalid(t *testing.T) {
+	for _, token := range []string{"%%%", "bm8tc2VwYXJhdG9y", "YmFkfDBiN2U2YTU3LThmMGEtNGMxYi05YTU3LTJmNWQyYzZlOGExMQ"} {
+		_, err := pagination.Decode(token)
+		assert.ErrorIs(t, err, er.ErrInvalidCursor, token)
+	}
+}
+
+func TestLimits_Resolve(t *testing.T) {
+	l := pagination.Limits{Default: 10, Max: 30}
+
+	limit, err := l.Resolve(0)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, limit)
+
+	limit, err = l.Resolve(30)
+	assert.NoError(t, err)
+	assert.Equal(t, 30, limit)
+
+	_, err = l.Resolve(31)
+	assert.ErrorIs(t, err, er.ErrInvalidLimit)
+
+	_, err = l.Resolve(-1)
+	assert.ErrorIs(t, err, er.ErrInvalidLimit)
+}
@@ -0,0 +1,137 @@
+// Package problem turns errors into what clients get: RFC 7807 problem details over HTTP and
+// a status with ErrorInfo details over gRPC. Both carry the stable code from internal/errors,
+// errors without one are reported as internal and their text never leaves the service.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+
+	er "trainee-pvz/internal/errors"
+)
+
+const (
+	ContentType = "application/problem+json"
+
+	// Domain is the ErrorInfo domain of gRPC errors.
+	Domain = "pvz"
+
+	typePrefix      = "urn:pvz:error:"
+	internalMessage = "internal error"
+)
+
+// Problem is the HTTP error body. Message repeats Detail for clients of the former
+// {"message": ...} body, Errors lists the invalid fields of a request.
+type Problem struct {
+	Type     string          `json:"type"`
+	Title    string          `json:"title"`
+	Status   int             `json:"status"`
+	Detail   string          `json:"detail"`
+	Instance string          `json:"instance,omitempty"`
+	Code     er.Code         `json:"code"`
+	Message  string          `json:"message"`
+	Errors   []er.FieldError `json:"errors,omitempty"`
+}
+
+// mapping is the only place kinds get their transport codes. Rule violations answer 400 like
+// the API always did, gRPC tells them apart.
+var mapping = map[er.Kind]struct {
+	http int
+	grpc codes.Code
+}{
+	er.KindInternal:           {http.StatusInternalServerError, codes.Internal},
+	er.KindInvalid:            {http.StatusBadRequest, codes.InvalidArgument},
+	er.KindUnauthenticated:    {http.StatusUnauthorized, codes.Unauthenticated},
+	er.KindForbidden:          {http.StatusForbidden, codes.PermissionDenied},
+	er.KindNotFound:           {http.StatusNotFound, codes.NotFound},
+	er.KindConflict:           {http.StatusBadRequest, codes.AlreadyExists},
+	er.KindFailedPrecondition: {http.StatusBadRequest, codes.FailedPrecondition},
+	er.KindTooLarge:           {http.StatusRequestEntityTooLarge, codes.ResourceExhausted},
+}
+
+// describe returns the code and the text safe to show for err. The text of domain errors
+// keeps what they were wrapped with, e.g. which field is invalid.
+func describe(err error) (er.Kind, er.Code, string) {
+	e := er.From(err)
+	if e == nil {
+		return er.KindInternal, er.CodeInternal, internalMessage
+	}
+	if e.Kind == er.KindInternal {
+		return e.Kind, e.Code, internalMessage
+	}
+
+	return e.Kind, e.Code, err.Error()
+}
+
+func fieldsOf(kind er.Kind, err error) []er.FieldError {
+	if kind == er.KindInternal {
+		return nil
+	}
+	return er.From(err).Fields
+}
+
+// HTTPStatus returns the response status for err.
+func HTTPStatus(err error) int {
+	kind, _, _ := describe(err)
+	return mapping[kind].http
+}
+
+// New builds the problem for err, instance is the request path.
+func New(err error, instance string) Problem {
+	kind, code, detail := describe(err)
+	statusCode := mapping[kind].http
+
+	p := Problem{
+		Type:     typePrefix + string(code),
+		Title:    http.StatusText(statusCode),
+		Status:   statusCode,
+		Detail:   detail,
+		Instance: instance,
+		Code:     code,
+		Message:  detail,
+	}
+	p.Errors = fieldsOf(kind, err)
+
+	return p
+}
+
+// Write sends err as problem details.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	p := New(err, r.URL.Path)
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// GRPCStatus converts err to a status with the code in ErrorInfo.Reason and the invalid
+// fields in BadRequest. Errors that already are statuses are returned as is.
+func GRPCStatus(err error) *status.Status {
+	if st, ok := status.FromError(err); ok {
+		return st
+	}
+
+	kind, code, detail := describe(err)
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: string(code), Domain: Domain}}
+	if fields := fieldsOf(kind, err); len(fields) > 0 {
+		violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(fields))
+		for _, f := range fields {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: f.Field, Description: f.Message})
+		}
+		details = append(details, &errdetails.BadRequest{FieldViolations: violations})
+	}
+
+	st := status.New(mapping[kind].grpc, detail)
+	withDetails, detailsErr := st.WithDetails(details...)
+	if detailsErr != nil {
+		return st
+	}
+
+	return withDetails
+}
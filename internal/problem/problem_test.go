@@ -0,0 +1,111 @@
+package problem_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/problem"
+)
+
+func TestWrite(t *testing.T) {
+	cases := []struct {
+		name   string
+		err    error
+		status int
+		code   er.Code
+		detail string
+	}{
+		{"wrapped sentinel", errors.Wrap(er.ErrNoPVZ, "can't archive"), http.StatusNotFound, er.CodePVZNotFound, "can't archive: pvz not found"},
+		{"rule violation", er.ErrReceptionAlreadyExists, http.StatusBadRequest, er.CodeReceptionAlreadyExists, "reception already exists"},
+		{"invalid request", er.Invalid("invalid pvz id"), http.StatusBadRequest, er.CodeInvalidRequest, "invalid pvz id"},
+		{"unauthenticated", er.ErrMissingToken, http.StatusUnauthorized, er.CodeMissingToken, "missing token"},
+		{"unknown error is hidden", errors.New("pq: connection refused"), http.StatusInternalServerError, er.CodeInternal, "internal error"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			problem.Write(w, httptest.NewRequest(http.MethodPost, "/pvz/1/archive", nil), tc.err)
+
+			assert.Equal(t, tc.status, w.Code)
+			assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+
+			var p problem.Problem
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&p))
+			assert.Equal(t, tc.status, p.Status)
+			assert.Equal(t, tc.code, p.Code)
+			assert.Equal(t, "urn:pvz:error:"+string(tc.code), p.Type)
+			assert.Equal(t, http.StatusText(tc.status), p.Title)
+			assert.Equal(t, tc.detail, p.Detail)
+			assert.Equal(t, tc.detail, p.Message)
+			assert.Equal(t, "/pvz/1/archive", p.Instance)
+		})
+	}
+}
+
+func TestGRPCStatus(t *testing.T) {
+	cases := []struct {
+		err  error
+		code codes.Code
+		want er.Code
+	}{
+		{errors.Wrap(er.ErrInvalidCursor, "bad id"), codes.InvalidArgument, er.CodeInvalidCursor},
+		{er.ErrReceptionAlreadyExists, codes.AlreadyExists, er.CodeReceptionAlreadyExists},
+		{er.ErrPVZArchived, codes.FailedPrecondition, er.CodePVZArchived},
+		{er.ErrProductNotFound, codes.NotFound, er.CodeProductNotFound},
+		{errors.New("boom"), codes.Internal, er.CodeInternal},
+	}
+
+	for _, tc := range cases {
+		st := problem.GRPCStatus(tc.err)
+		assert.Equal(t, tc.code, st.Code(), tc.err)
+		require.Len(t, st.Details(), 1)
+		info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+		require.True(t, ok)
+		assert.Equal(t, string(tc.want), info.GetReason())
+		assert.Equal(t, problem.Domain, info.GetDomain())
+	}
+
+	assert.Equal(t, "internal error", problem.GRPCStatus(errors.New("boom")).Message())
+
+	original := status.Error(codes.Unavailable, "shutting down")
+	assert.Equal(t, codes.Unavailable, problem.GRPCStatus(original).Code(), "statuses pass as is")
+}
+
+func TestErrorIs_MatchesByCode(t *testing.T) {
+	assert.ErrorIs(t, er.Invalid("invalid lat"), er.ErrInvalidRequest)
+	assert.NotErrorIs(t, er.ErrNoPVZ, er.ErrProductNotFound)
+	assert.Equal(t, er.KindInternal, er.KindOf(errors.New("boom")))
+	assert.Equal(t, er.KindNotFound, er.KindOf(errors.Wrap(er.ErrNoPVZ, "x")))
+}
+
+func TestFieldErrors(t *testing.T) {
+	err := er.InvalidFields([]er.FieldError{{Field: "type", Message: "value is not one of the allowed values"}})
+
+	w := httptest.NewRecorder()
+	problem.Write(w, httptest.NewRequest(http.MethodPost, "/products", nil), err)
+
+	var p problem.Problem
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&p))
+	assert.Equal(t, http.StatusBadRequest, p.Status)
+	assert.Equal(t, er.CodeInvalidRequest, p.Code)
+	assert.Equal(t, er.From(err).Fields, p.Errors)
+
+	st := problem.GRPCStatus(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	require.Len(t, st.Details(), 2)
+	badRequest, ok := st.Details()[1].(*errdetails.BadRequest)
+	require.True(t, ok)
+	require.Len(t, badRequest.GetFieldViolations(), 1)
+	assert.Equal(t, "type", badRequest.GetFieldViolations()[0].GetField())
+}
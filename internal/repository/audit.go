@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"trainee-pvz/internal/audit"
+	"trainee-pvz/internal/logger"
+	"trainee-pvz/internal/models"
+)
+
+// snapshot returns the current row of the entity, nil when it doesn't exist.
+func snapshot(ctx context.Context, tx *sqlx.Tx, entityType, id string) (json.RawMessage, error) {
+	var row []byte
+	err := tx.GetContext(ctx, &row, SnapshotQueries[entityType], id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "repo: snapshot %s", entityType)
+	}
+
+	return row, nil
+}
+
+// recordChange writes an audit entry for the entity using before and its current state
+// as after. It has to be called in the transaction of the change, after the change.
+func recordChange(ctx context.Context, tx *sqlx.Tx, action, entityType, id string, before json.RawMessage) error {
+	after, err := snapshot(ctx, tx, entityType, id)
+	if err != nil {
+		return err
+	}
+
+	actor := audit.ActorFromContext(ctx)
+	query := `
+		INSERT INTO audit_log (actor_id, actor_role, action, entity_type, entity_id, before, after, request_id, ip)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err = tx.ExecContext(ctx, query,
+		nullIfEmpty(actor.UserID), nullIfEmpty(actor.Role), action, entityType, id,
+		nullJSON(before), nullJSON(after), nullIfEmpty(actor.RequestID), nullIfEmpty(actor.IP),
+	)
+	if err != nil {
+		logger.FromContext(ctx).Error("write audit log failed", slog.Any("err", err))
+		return errors.Wrap(err, "repo: write audit log")
+	}
+
+	return nil
+}
+
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
+
+func nullJSON(raw json.RawMessage) *string {
+	if raw == nil {
+		return nil
+	}
+
+	s := string(raw)
+	return &s
+}
+
+type AuditRepository struct {
+	db *sqlx.DB
+}
+
+func NewAuditRepository(db *sqlx.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// List returns entries matching the filter, newest first, with id below afterID when it's set.
+func (r *AuditRepository) List(ctx context.Context, filter models.AuditFilter, afterID int64, limit int) ([]models.AuditEntry, error) {
+	query, args := AuditListQuery(filter, afterID, limit)
+
+	// plain []byte makes database/sql copy the driver buffer, json.RawMessage would alias it
+	var rows []struct {
+		models.AuditEntry
+		Before []byte `db:"before"`
+		After  []byte `db:"after"`
+	}
+	err := r.db.SelectContext(ctx, &rows, query, args...)
+	if err != nil {
+		logger.FromContext(ctx).Error("select audit log failed", slog.Any("err", err))
+		return nil, errors.Wrap(err, "audit repo: list")
+	}
+
+	entries := make([]models.AuditEntry, 0, len(rows))
+	for _, row := range rows {
+		entry := row.AuditEntry
+		entry.Before, entry.After = row.Before, row.After
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"trainee-pvz/internal/logger"
+	"trainee-pvz/internal/models"
+)
+
+// adjustDailyIntake changes the daily counter of the product's day inside the caller's transaction.
+func adjustDailyIntake(ctx context.Context, tx *sqlx.Tx, datetime time.Time, pvzID, productType string, delta int) error {
+	_, err := tx.ExecContext(ctx, AdjustDailyIntakeQuery, datetime, pvzID, productType, delta)
+	if err != nil {
+		logger.FromContext(ctx).Error("adjust daily intake failed", slog.Any("err", err))
+		return errors.Wrap(err, "repo: adjust daily intake")
+	}
+
+	return nil
+}
+
+// RebuildDailyIntake recomputes daily_intake from products. The table is locked for the
+// duration, so concurrent product changes wait and are applied on top of the rebuilt counters.
+func (r *StatsRepository) RebuildDailyIntake(ctx context.Context) (int64, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, errors.Wrap(err, "stats repo: begin tx")
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `LOCK TABLE daily_intake IN EXCLUSIVE MODE`)
+	if err != nil {
+		return 0, errors.Wrap(err, "stats repo: lock daily intake")
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM daily_intake`)
+	if err != nil {
+		return 0, errors.Wrap(err, "stats repo: clear daily intake")
+	}
+
+	res, err := tx.ExecContext(ctx, FillDailyIntakeQuery)
+	if err != nil {
+		logger.FromContext(ctx).Error("rebuild daily intake failed", slog.Any("err", err))
+		return 0, errors.Wrap(err, "stats repo: fill daily intake")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, errors.Wrap(err, "stats repo: commit daily intake")
+	}
+
+	return res.RowsAffected()
+}
+
+// DailyIntakeMismatches lists the days where daily_intake and the raw products disagree.
+func (r *StatsRepository) DailyIntakeMismatches(ctx context.Context) ([]models.DailyIntakeMismatch, error) {
+	var mismatches []models.DailyIntakeMismatch
+	err := r.db.SelectContext(ctx, &mismatches, DailyIntakeMismatchesQuery)
+	if err != nil {
+		logger.FromContext(ctx).Error("compare daily intake failed", slog.Any("err", err))
+		return nil, errors.Wrap(err, "stats repo: compare daily intake")
+	}
+
+	return mismatches, nil
+}
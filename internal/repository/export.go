@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"trainee-pvz/internal/logger"
+	"trainee-pvz/internal/models"
+)
+
+type ExportRepository struct {
+	db *sqlx.DB
+}
+
+func NewExportRepository(db *sqlx.DB) *ExportRepository {
+	return &ExportRepository{db: db}
+}
+
+// StreamReceptions passes export rows to fn one by one as they are read from the cursor,
+// so the result set is never held in memory. An error from fn stops the iteration.
+func (r *ExportRepository) StreamReceptions(ctx context.Context, q models.ExportQuery, fn func(models.ExportRow) error) error {
+	rows, err := r.db.QueryxContext(ctx, ExportReceptionsQuery, q.PVZID, q.From, q.To)
+	if err != nil {
+		logger.FromContext(ctx).Error("select export rows failed", slog.Any("err", err))
+		return errors.Wrap(err, "export repo: select receptions")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row models.ExportRow
+		err = rows.StructScan(&row)
+		if err != nil {
+			return errors.Wrap(err, "export repo: scan row")
+		}
+
+		err = fn(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.FromContext(ctx).Error("iterate export rows failed", slog.Any("err", err))
+		return errors.Wrap(err, "export repo: iterate rows")
+	}
+
+	return nil
+}
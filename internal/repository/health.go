@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"trainee-pvz/internal/logger"
+)
+
+type HealthRepository struct {
+	db *sqlx.DB
+}
+
+func NewHealthRepository(db *sqlx.DB) *HealthRepository {
+	return &HealthRepository{db: db}
+}
+
+func (r *HealthRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// MigrationVersion returns the newest applied goose migration.
+func (r *HealthRepository) MigrationVersion(ctx context.Context) (int64, error) {
+	var version int64
+	err := r.db.GetContext(ctx, &version, MigrationVersionQuery)
+	if err != nil {
+		logger.FromContext(ctx).Error("select migration version failed", slog.Any("err", err))
+		return 0, errors.Wrap(err, "health repo: migration version")
+	}
+
+	return version, nil
+}
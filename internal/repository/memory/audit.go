@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"context"
+
+	"trainee-pvz/internal/models"
+)
+
+type AuditRepository struct {
+	s *Store
+}
+
+func NewAuditRepository(s *Store) *AuditRepository {
+	return &AuditRepository{s: s}
+}
+
+// List returns entries matching the filter, newest first, with id below afterID when it's set.
+func (r *AuditRepository) List(_ context.Context, filter models.AuditFilter, afterID int64, limit int) ([]models.AuditEntry, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var entries []models.AuditEntry
+	for i := len(r.s.audit) - 1; i >= 0 && len(entries) < limit; i-- {
+		e := r.s.audit[i]
+		switch {
+		case afterID > 0 && e.ID >= afterID,
+			filter.ActorID != "" && (e.ActorID == nil || *e.ActorID != filter.ActorID),
+			filter.Action != "" && e.Action != filter.Action,
+			filter.EntityType != "" && e.EntityType != filter.EntityType,
+			filter.EntityID != "" && e.EntityID != filter.EntityID,
+			filter.From != nil && e.CreatedAt.Before(*filter.From),
+			filter.To != nil && !e.CreatedAt.Before(*filter.To):
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
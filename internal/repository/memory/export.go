@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+
+	"trainee-pvz/internal/models"
+)
+
+type ExportRepository struct {
+	s *Store
+}
+
+func NewExportRepository(s *Store) *ExportRepository {
+	return &ExportRepository{s: s}
+}
+
+// StreamReceptions passes export rows to fn in the order of ExportReceptionsQuery. The rows
+// are collected under the lock and fn is called after it's released, so a slow writer doesn't
+// block the store. An error from fn stops the iteration.
+func (r *ExportRepository) StreamReceptions(ctx context.Context, q models.ExportQuery, fn func(models.ExportRow) error) error {
+	for _, row := range r.rows(q) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := fn(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *ExportRepository) rows(q models.ExportQuery) []models.ExportRow {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var receptions []models.Reception
+	for _, id := range r.s.pvzReceptions[q.PVZID] {
+		rec := r.s.receptions[id]
+		if !rec.DateTime.Before(q.From) && rec.DateTime.Before(q.To) {
+			receptions = append(receptions, rec)
+		}
+	}
+	slices.SortFunc(receptions, func(a, b models.Reception) int {
+		return cmp.Or(a.DateTime.Compare(b.DateTime), strings.Compare(a.ID, b.ID))
+	})
+
+	var rows []models.ExportRow
+	for _, rec := range receptions {
+		head := models.ExportRow{
+			ReceptionID:       rec.ID,
+			ReceptionDateTime: rec.DateTime,
+			ReceptionStatus:   rec.Status,
+			ReceptionClosedAt: clonePtr(rec.ClosedAt),
+			City:              r.s.pvzs[rec.PVZID].City,
+		}
+		if rec.CreatedBy != nil {
+			email := r.s.users[*rec.CreatedBy].Email
+			head.EmployeeEmail = &email
+		}
+
+		products := make([]models.Product, 0, len(r.s.receptionProducts[rec.ID]))
+		for _, id := range r.s.receptionProducts[rec.ID] {
+			products = append(products, r.s.products[id])
+		}
+		slices.SortFunc(products, func(a, b models.Product) int {
+			return cmp.Or(a.DateTime.Compare(b.DateTime), strings.Compare(a.ID, b.ID))
+		})
+
+		if len(products) == 0 {
+			rows = append(rows, head)
+		}
+		for _, p := range products {
+			row := head
+			row.ProductID, row.ProductType = &p.ID, &p.Type
+			row.ProductDateTime, row.ProductIssuedAt = &p.DateTime, clonePtr(p.IssuedAt)
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
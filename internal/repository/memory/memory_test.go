@@ -0,0 +1,24 @@
+package memory_test
+
+import (
+	"testing"
+
+	"trainee-pvz/internal/repository/memory"
+	"trainee-pvz/internal/repository/repotest"
+)
+
+func TestContract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repositories {
+		s := memory.NewStore()
+		return repotest.Repositories{
+			User:      memory.NewUserRepository(s),
+			Reception: memory.NewReceptionRepository(s),
+			Product:   memory.NewProductRepository(s),
+			PVZ:       memory.NewPVZRepository(s),
+			Stats:     memory.NewStatsRepository(s),
+			Export:    memory.NewExportRepository(s),
+			Audit:     memory.NewAuditRepository(s),
+			Health:    memory.NewHealthRepository(1),
+		}
+	})
+}
@@ -0,0 +1,164 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/models"
+)
+
+type ProductRepository struct {
+	s *Store
+}
+
+func NewProductRepository(s *Store) *ProductRepository {
+	return &ProductRepository{s: s}
+}
+
+// Add stores the product and increments the PVZ stored items counter. With enforceCapacity
+// nothing is changed when the PVZ would exceed its capacity.
+func (r *ProductRepository) Add(ctx context.Context, p models.Product, enforceCapacity bool) (models.StorageUsage, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	rec, ok := r.s.receptions[p.ReceptionID]
+	if !ok || rec.Status != "in_progress" {
+		return models.StorageUsage{}, er.ErrNoOpenReception
+	}
+	if _, ok := r.s.products[p.ID]; ok {
+		return models.StorageUsage{}, errors.Errorf("memory repo: product %s already exists", p.ID)
+	}
+	if !productTypes[p.Type] {
+		return models.StorageUsage{}, errors.Errorf("memory repo: unsupported product type %q", p.Type)
+	}
+
+	usage := r.s.usage(rec.PVZID)
+	usage.StoredItems++
+	if enforceCapacity && usage.OverCapacity() {
+		usage.StoredItems--
+		return usage, er.ErrPVZOverCapacity
+	}
+
+	p.IssuedAt = nil
+	r.s.products[p.ID] = p
+	r.s.receptionProducts[p.ReceptionID] = append(r.s.receptionProducts[p.ReceptionID], p.ID)
+	r.setStoredItems(rec.PVZID, usage.StoredItems)
+	r.s.adjustIntake(p, rec.PVZID, 1)
+	r.s.recordChange(ctx, models.AuditProductAdd, models.AuditEntityProduct, p.ID, nil)
+
+	return usage, nil
+}
+
+// AddBatch stores products of one reception and increments the PVZ stored items counter,
+// either all of them are added or none.
+func (r *ProductRepository) AddBatch(ctx context.Context, receptionID string, products []models.Product, enforceCapacity bool) (models.StorageUsage, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	rec, ok := r.s.receptions[receptionID]
+	if !ok || rec.Status != "in_progress" {
+		return models.StorageUsage{}, er.ErrNoOpenReception
+	}
+	seen := make(map[string]bool, len(products))
+	for _, p := range products {
+		if _, ok := r.s.products[p.ID]; ok || seen[p.ID] {
+			return models.StorageUsage{}, errors.Errorf("memory repo: product %s already exists", p.ID)
+		}
+		if !productTypes[p.Type] {
+			return models.StorageUsage{}, errors.Errorf("memory repo: unsupported product type %q", p.Type)
+		}
+		seen[p.ID] = true
+	}
+
+	usage := r.s.usage(rec.PVZID)
+	usage.StoredItems += len(products)
+	if enforceCapacity && usage.OverCapacity() {
+		usage.StoredItems -= len(products)
+		return usage, er.ErrPVZOverCapacity
+	}
+
+	for _, p := range products {
+		p.ReceptionID, p.IssuedAt = receptionID, nil
+		r.s.products[p.ID] = p
+		r.s.receptionProducts[receptionID] = append(r.s.receptionProducts[receptionID], p.ID)
+		r.s.adjustIntake(p, rec.PVZID, 1)
+		r.s.recordChange(ctx, models.AuditProductAdd, models.AuditEntityProduct, p.ID, nil)
+	}
+	r.setStoredItems(rec.PVZID, usage.StoredItems)
+
+	return usage, nil
+}
+
+// DeleteLast removes the newest product of the open reception of the PVZ.
+func (r *ProductRepository) DeleteLast(ctx context.Context, pvzID string) (models.StorageUsage, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	rec, ok := r.s.openReception(pvzID)
+	if !ok {
+		return models.StorageUsage{}, er.ErrNoProducts
+	}
+
+	ids := r.s.receptionProducts[rec.ID]
+	last := -1
+	for i, id := range ids {
+		if last < 0 || !r.s.products[id].DateTime.Before(r.s.products[ids[last]].DateTime) {
+			last = i
+		}
+	}
+	if last < 0 {
+		return models.StorageUsage{}, er.ErrNoProducts
+	}
+
+	product := r.s.products[ids[last]]
+	before := r.s.snapshot(models.AuditEntityProduct, product.ID)
+
+	delete(r.s.products, product.ID)
+	r.s.receptionProducts[rec.ID] = slices.Delete(ids, last, last+1)
+	r.s.adjustIntake(product, pvzID, -1)
+	r.s.recordChange(ctx, models.AuditProductDelete, models.AuditEntityProduct, product.ID, before)
+
+	return r.decrementStoredItems(pvzID), nil
+}
+
+// Issue marks a product from a closed reception as handed over and frees its storage slot.
+func (r *ProductRepository) Issue(ctx context.Context, productID string, issuedAt time.Time) (models.StorageUsage, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	product, ok := r.s.products[productID]
+	if !ok {
+		return models.StorageUsage{}, er.ErrProductNotFound
+	}
+	if product.IssuedAt != nil {
+		return models.StorageUsage{}, er.ErrProductAlreadyIssued
+	}
+	rec := r.s.receptions[product.ReceptionID]
+	if rec.Status != "close" {
+		return models.StorageUsage{}, er.ErrReceptionNotClosed
+	}
+
+	before := r.s.snapshot(models.AuditEntityProduct, productID)
+
+	product.IssuedAt = &issuedAt
+	r.s.products[productID] = product
+	usage := r.decrementStoredItems(rec.PVZID)
+	r.s.recordChange(ctx, models.AuditProductIssue, models.AuditEntityProduct, productID, before)
+
+	return usage, nil
+}
+
+func (r *ProductRepository) decrementStoredItems(pvzID string) models.StorageUsage {
+	r.setStoredItems(pvzID, r.s.pvzs[pvzID].StoredItems-1)
+	return r.s.usage(pvzID)
+}
+
+func (r *ProductRepository) setStoredItems(pvzID string, n int) {
+	pvz := r.s.pvzs[pvzID]
+	pvz.StoredItems = n
+	r.s.pvzs[pvzID] = pvz
+}
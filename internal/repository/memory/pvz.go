@@ -0,0 +1,258 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/pagination"
+	"trainee-pvz/internal/repository"
+)
+
+type PVZRepository struct {
+	s *Store
+}
+
+func NewPVZRepository(s *Store) *PVZRepository {
+	return &PVZRepository{s: s}
+}
+
+func (r *PVZRepository) Create(ctx context.Context, pvz models.PVZ) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	err := r.checkNew(pvz, nil)
+	if err != nil {
+		return err
+	}
+
+	r.insert(pvz)
+	r.s.recordChange(ctx, models.AuditPVZCreate, models.AuditEntityPVZ, pvz.ID, nil)
+
+	return nil
+}
+
+// CreateBatch checks all PVZs before storing any, so either all of them are created or none.
+func (r *PVZRepository) CreateBatch(ctx context.Context, pvzs []models.PVZ) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	codes := map[string]bool{}
+	for _, pvz := range pvzs {
+		err := r.checkNew(pvz, codes)
+		if err != nil {
+			return err
+		}
+		if pvz.ExternalCode != nil {
+			codes[*pvz.ExternalCode] = true
+		}
+	}
+
+	for _, pvz := range pvzs {
+		r.insert(pvz)
+		r.s.recordChange(ctx, models.AuditPVZImport, models.AuditEntityPVZ, pvz.ID, nil)
+	}
+
+	return nil
+}
+
+// checkNew applies the table constraints to a PVZ about to be inserted, batchCodes are the
+// external codes taken earlier in the same batch.
+func (r *PVZRepository) checkNew(pvz models.PVZ, batchCodes map[string]bool) error {
+	if _, ok := r.s.pvzs[pvz.ID]; ok {
+		return errors.Errorf("memory repo: pvz %s already exists", pvz.ID)
+	}
+	if !cities[pvz.City] {
+		return er.ErrUnsupportedCity
+	}
+	if pvz.ExternalCode == nil {
+		return nil
+	}
+	if batchCodes[*pvz.ExternalCode] || r.codeTaken(*pvz.ExternalCode) {
+		return er.ErrExternalCodeExists
+	}
+
+	return nil
+}
+
+func (r *PVZRepository) codeTaken(code string) bool {
+	for _, pvz := range r.s.pvzs {
+		if pvz.ExternalCode != nil && *pvz.ExternalCode == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// insert stores the PVZ with the columns the insert leaves to their defaults reset.
+func (r *PVZRepository) insert(pvz models.PVZ) {
+	pvz = clonePVZ(pvz)
+	pvz.ArchivedAt = nil
+	pvz.StoredItems = 0
+	r.s.pvzs[pvz.ID] = pvz
+}
+
+// ExistingExternalCodes returns those of codes that are already taken.
+func (r *PVZRepository) ExistingExternalCodes(_ context.Context, codes []string) ([]string, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var existing []string
+	for _, code := range codes {
+		if r.codeTaken(code) && !slices.Contains(existing, code) {
+			existing = append(existing, code)
+		}
+	}
+
+	return existing, nil
+}
+
+func (r *PVZRepository) GetByID(_ context.Context, id string) (models.PVZ, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	pvz, ok := r.s.pvzs[id]
+	if !ok {
+		return models.PVZ{}, er.ErrNoPVZ
+	}
+
+	return clonePVZ(pvz), nil
+}
+
+func (r *PVZRepository) Update(ctx context.Context, pvz models.PVZ) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.pvzs[pvz.ID]
+	if !ok {
+		return er.ErrNoPVZ
+	}
+	before := r.s.snapshot(models.AuditEntityPVZ, pvz.ID)
+
+	pvz = clonePVZ(pvz)
+	stored.Address = pvz.Address
+	stored.Latitude, stored.Longitude = pvz.Latitude, pvz.Longitude
+	stored.WorkingHours = pvz.WorkingHours
+	stored.Capacity = pvz.Capacity
+	r.s.pvzs[pvz.ID] = stored
+
+	r.s.recordChange(ctx, models.AuditPVZUpdate, models.AuditEntityPVZ, pvz.ID, before)
+
+	return nil
+}
+
+// Archive sets archived_at of the PVZ unless it is archived already or has an open reception.
+func (r *PVZRepository) Archive(ctx context.Context, id string, archivedAt time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.pvzs[id]
+	if !ok {
+		return er.ErrNoPVZ
+	}
+	if stored.ArchivedAt != nil {
+		return er.ErrPVZArchived
+	}
+	if _, ok := r.s.openReception(id); ok {
+		return er.ErrPVZHasOpenReception
+	}
+	before := r.s.snapshot(models.AuditEntityPVZ, id)
+
+	stored.ArchivedAt = &archivedAt
+	r.s.pvzs[id] = stored
+	r.s.recordChange(ctx, models.AuditPVZArchive, models.AuditEntityPVZ, id, before)
+
+	return nil
+}
+
+func (r *PVZRepository) SetArchivedAt(ctx context.Context, id string, archivedAt *time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.pvzs[id]
+	if !ok {
+		return er.ErrNoPVZ
+	}
+	before := r.s.snapshot(models.AuditEntityPVZ, id)
+
+	stored.ArchivedAt = clonePtr(archivedAt)
+	r.s.pvzs[id] = stored
+
+	action := models.AuditPVZArchive
+	if archivedAt == nil {
+		action = models.AuditPVZRestore
+	}
+	r.s.recordChange(ctx, action, models.AuditEntityPVZ, id, before)
+
+	return nil
+}
+
+// Nearby returns active PVZs within q.RadiusKm of the point ordered by great-circle distance.
+func (r *PVZRepository) Nearby(_ context.Context, q models.GeoQuery) ([]models.NearbyPVZ, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var res []models.NearbyPVZ
+	for _, pvz := range r.s.pvzs {
+		if pvz.ArchivedAt != nil || pvz.Latitude == nil || pvz.Longitude == nil {
+			continue
+		}
+
+		distance := haversineKm(q.Latitude, q.Longitude, *pvz.Latitude, *pvz.Longitude)
+		if distance <= q.RadiusKm {
+			res = append(res, models.NearbyPVZ{PVZ: clonePVZ(pvz), DistanceKm: distance})
+		}
+	}
+
+	slices.SortFunc(res, func(a, b models.NearbyPVZ) int {
+		return cmp.Compare(a.DistanceKm, b.DistanceKm)
+	})
+
+	return res[:min(len(res), q.Limit)], nil
+}
+
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	h := math.Pow(math.Sin(rad(lat2-lat1)/2), 2) +
+		math.Cos(rad(lat1))*math.Cos(rad(lat2))*math.Pow(math.Sin(rad(lon2-lon1)/2), 2)
+
+	return 2 * repository.EarthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// List returns PVZs in (registration_date DESC, id DESC) order starting right after the
+// given cursor (from the beginning when after is nil).
+func (r *PVZRepository) List(_ context.Context, filter models.PVZFilter, after *pagination.Cursor, limit int) ([]models.PVZ, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var res []models.PVZ
+	for _, pvz := range r.s.pvzs {
+		switch {
+		case filter.StartDate != nil && pvz.RegistrationDate.Before(*filter.StartDate),
+			filter.EndDate != nil && pvz.RegistrationDate.After(*filter.EndDate),
+			!filter.IncludeArchived && pvz.ArchivedAt != nil,
+			after != nil && comparePVZOrder(pvz, after.RegistrationDate, after.ID) >= 0:
+			continue
+		}
+		res = append(res, clonePVZ(pvz))
+	}
+
+	slices.SortFunc(res, func(a, b models.PVZ) int {
+		return -comparePVZOrder(a, b.RegistrationDate, b.ID)
+	})
+
+	return res[:min(len(res), limit)], nil
+}
+
+// comparePVZOrder compares (registration_date, id) of the PVZ with the given pair.
+func comparePVZOrder(pvz models.PVZ, date time.Time, id string) int {
+	return cmp.Or(pvz.RegistrationDate.Compare(date), strings.Compare(pvz.ID, id))
+}
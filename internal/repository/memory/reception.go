@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/models"
+)
+
+type ReceptionRepository struct {
+	s *Store
+}
+
+func NewReceptionRepository(s *Store) *ReceptionRepository {
+	return &ReceptionRepository{s: s}
+}
+
+func (r *ReceptionRepository) HasOpenReception(_ context.Context, pvzID string) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	_, ok := r.s.openReception(pvzID)
+	return ok, nil
+}
+
+func (r *ReceptionRepository) IsPVZArchived(_ context.Context, pvzID string) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	pvz, ok := r.s.pvzs[pvzID]
+	if !ok {
+		return false, er.ErrNoPVZ
+	}
+
+	return pvz.ArchivedAt != nil, nil
+}
+
+// Create stores the reception unless the PVZ is archived or already has an open one; the checks
+// and the insert can't race under the store lock.
+func (r *ReceptionRepository) Create(ctx context.Context, rec models.Reception) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.receptions[rec.ID]; ok {
+		return errors.Errorf("memory repo: reception %s already exists", rec.ID)
+	}
+	pvz, ok := r.s.pvzs[rec.PVZID]
+	if !ok {
+		return er.ErrNoPVZ
+	}
+	if pvz.ArchivedAt != nil {
+		return er.ErrPVZArchived
+	}
+	if rec.CreatedBy != nil {
+		if _, ok := r.s.users[*rec.CreatedBy]; !ok {
+			return errors.Errorf("memory repo: user %s does not exist", *rec.CreatedBy)
+		}
+	}
+	if _, ok := r.s.openReception(rec.PVZID); ok && rec.Status == "in_progress" {
+		return er.ErrReceptionAlreadyExists
+	}
+
+	rec.ClosedAt = clonePtr(rec.ClosedAt)
+	rec.CreatedBy = clonePtr(rec.CreatedBy)
+	r.s.receptions[rec.ID] = rec
+	r.s.pvzReceptions[rec.PVZID] = append(r.s.pvzReceptions[rec.PVZID], rec.ID)
+	r.s.recordChange(ctx, models.AuditReceptionCreate, models.AuditEntityReception, rec.ID, nil)
+
+	return nil
+}
+
+func (r *ReceptionRepository) Close(ctx context.Context, id string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	rec, ok := r.s.receptions[id]
+	if !ok || rec.Status != "in_progress" {
+		return er.ErrNoOpenReception
+	}
+	before := r.s.snapshot(models.AuditEntityReception, id)
+
+	now := time.Now()
+	rec.Status, rec.ClosedAt = "close", &now
+	r.s.receptions[id] = rec
+	r.s.recordChange(ctx, models.AuditReceptionClose, models.AuditEntityReception, id, before)
+
+	return nil
+}
+
+// GetOpenReceptionID returns the newest in-progress reception of the PVZ.
+func (r *ReceptionRepository) GetOpenReceptionID(_ context.Context, pvzID string) (string, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	rec, ok := r.s.openReception(pvzID)
+	if !ok {
+		return "", er.ErrNoOpenReception
+	}
+
+	return rec.ID, nil
+}
@@ -0,0 +1,205 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"trainee-pvz/internal/models"
+)
+
+type StatsRepository struct {
+	s *Store
+}
+
+func NewStatsRepository(s *Store) *StatsRepository {
+	return &StatsRepository{s: s}
+}
+
+// IntakeRows sums the daily intake counters, so the period is expected to be aligned to UTC days.
+func (r *StatsRepository) IntakeRows(_ context.Context, q models.IntakeQuery) ([]models.IntakeRow, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	type group struct {
+		bucket           time.Time
+		pvzID, city, typ string
+	}
+	byPVZ := slices.Contains(q.GroupBy, models.GroupByPVZ)
+	byCity := slices.Contains(q.GroupBy, models.GroupByCity)
+	byType := slices.Contains(q.GroupBy, models.GroupByType)
+
+	from, to := utcDay(q.From), utcDay(q.To)
+	sums := map[group]int{}
+	for key, products := range r.s.intake {
+		if key.day.Before(from) || !key.day.Before(to) || products <= 0 {
+			continue
+		}
+
+		g := group{bucket: truncateBucket(key.day, q.Bucket)}
+		if byPVZ {
+			g.pvzID = key.pvzID
+		}
+		if byCity {
+			g.city = r.s.pvzs[key.pvzID].City
+		}
+		if byType {
+			g.typ = key.typ
+		}
+		sums[g] += products
+	}
+
+	rows := make([]models.IntakeRow, 0, len(sums))
+	for g, products := range sums {
+		row := models.IntakeRow{Bucket: g.bucket, Products: products}
+		if byPVZ {
+			row.PVZID = &g.pvzID
+		}
+		if byCity {
+			row.City = &g.city
+		}
+		if byType {
+			row.ProductType = &g.typ
+		}
+		rows = append(rows, row)
+	}
+	slices.SortFunc(rows, func(a, b models.IntakeRow) int {
+		return cmp.Or(a.Bucket.Compare(b.Bucket),
+			comparePtr(a.PVZID, b.PVZID), comparePtr(a.City, b.City), comparePtr(a.ProductType, b.ProductType))
+	})
+
+	return rows, nil
+}
+
+// comparePtr orders dimension values, nil dimensions were not requested and are equal.
+func comparePtr(a, b *string) int {
+	if a == nil || b == nil {
+		return 0
+	}
+
+	return strings.Compare(*a, *b)
+}
+
+// truncateBucket works like date_trunc, weeks start on Monday.
+func truncateBucket(day time.Time, bucket string) time.Time {
+	switch bucket {
+	case models.BucketWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case models.BucketMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+func (r *StatsRepository) ReceptionSummary(_ context.Context, q models.IntakeQuery) (models.ReceptionSummary, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var (
+		summary  models.ReceptionSummary
+		closed   int
+		duration float64
+		products int
+	)
+	for _, rec := range r.s.receptions {
+		if rec.DateTime.Before(q.From) || !rec.DateTime.Before(q.To) {
+			continue
+		}
+
+		summary.Receptions++
+		products += len(r.s.receptionProducts[rec.ID])
+		if rec.ClosedAt != nil {
+			closed++
+			duration += rec.ClosedAt.Sub(rec.DateTime).Seconds()
+		}
+	}
+
+	if closed > 0 {
+		summary.AvgDurationSeconds = duration / float64(closed)
+	}
+	if summary.Receptions > 0 {
+		summary.AvgProductsPerReception = float64(products) / float64(summary.Receptions)
+	}
+
+	return summary, nil
+}
+
+// OpenReceptionsByCity counts in-progress receptions per city; cities without any are omitted.
+func (r *StatsRepository) OpenReceptionsByCity(_ context.Context) (map[string]int64, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	counts := map[string]int64{}
+	for _, rec := range r.s.receptions {
+		if rec.Status == "in_progress" {
+			counts[r.s.pvzs[rec.PVZID].City]++
+		}
+	}
+
+	return counts, nil
+}
+
+// CapacityUtilisationByCity returns stored items against capacity per city, over active PVZs
+// with a capacity; cities without such PVZs are omitted.
+func (r *StatsRepository) CapacityUtilisationByCity(_ context.Context) (map[string]float64, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	stored, capacity := map[string]int{}, map[string]int{}
+	for _, pvz := range r.s.pvzs {
+		if pvz.ArchivedAt != nil || pvz.Capacity == nil || *pvz.Capacity <= 0 {
+			continue
+		}
+		stored[pvz.City] += pvz.StoredItems
+		capacity[pvz.City] += *pvz.Capacity
+	}
+
+	utilisation := make(map[string]float64, len(capacity))
+	for city, c := range capacity {
+		utilisation[city] = float64(stored[city]) / float64(c)
+	}
+
+	return utilisation, nil
+}
+
+// RebuildDailyIntake recomputes the counters from the products and returns their number.
+func (r *StatsRepository) RebuildDailyIntake(_ context.Context) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.intake = r.s.actualIntake()
+	return int64(len(r.s.intake)), nil
+}
+
+// DailyIntakeMismatches lists the days where the counters and the products disagree.
+func (r *StatsRepository) DailyIntakeMismatches(_ context.Context) ([]models.DailyIntakeMismatch, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	actual := r.s.actualIntake()
+	keys := map[intakeKey]bool{}
+	for key := range actual {
+		keys[key] = true
+	}
+	for key := range r.s.intake {
+		keys[key] = true
+	}
+
+	var mismatches []models.DailyIntakeMismatch
+	for key := range keys {
+		stored := max(r.s.intake[key], 0)
+		if stored != actual[key] {
+			mismatches = append(mismatches, models.DailyIntakeMismatch{
+				Day: key.day, PVZID: key.pvzID, ProductType: key.typ, Stored: stored, Actual: actual[key],
+			})
+		}
+	}
+	slices.SortFunc(mismatches, func(a, b models.DailyIntakeMismatch) int {
+		return cmp.Or(a.Day.Compare(b.Day), strings.Compare(a.PVZID, b.PVZID), strings.Compare(a.ProductType, b.ProductType))
+	})
+
+	return mismatches, nil
+}
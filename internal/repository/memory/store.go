@@ -0,0 +1,193 @@
+// Package memory implements the service repositories on top of maps kept in the process. It is
+// meant for tests and local demos: nothing survives a restart.
+//
+// All repositories of one Store share a single lock, so every method runs as if it was a
+// serializable transaction. The invariants Postgres enforces with constraints (supported
+// cities, unique emails and external codes, foreign keys) and the ones the SQL repositories
+// rely on (one open reception per PVZ, products deleted newest first) are checked here
+// explicitly, and the same audit entries and daily intake counters are written.
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"reflect"
+	"sync"
+	"time"
+
+	"trainee-pvz/internal/audit"
+	"trainee-pvz/internal/models"
+)
+
+// Values the check constraints of pvz.city and products.type accept.
+var (
+	cities       = map[string]bool{"Москва": true, "Санкт-Петербург": true, "Казань": true}
+	productTypes = map[string]bool{"электроника": true, "одежда": true, "обувь": true}
+)
+
+type intakeKey struct {
+	day   time.Time
+	pvzID string
+	typ   string
+}
+
+// Store is the data shared by the repositories built on it.
+type Store struct {
+	mu sync.RWMutex
+
+	users      map[string]models.User
+	pvzs       map[string]models.PVZ
+	receptions map[string]models.Reception
+	products   map[string]models.Product
+	intake     map[intakeKey]int
+	audit      []models.AuditEntry
+
+	// ids in insertion order, the newest reception or product wins ties on datetime
+	pvzReceptions     map[string][]string
+	receptionProducts map[string][]string
+}
+
+func NewStore() *Store {
+	return &Store{
+		users:             map[string]models.User{},
+		pvzs:              map[string]models.PVZ{},
+		receptions:        map[string]models.Reception{},
+		products:          map[string]models.Product{},
+		intake:            map[intakeKey]int{},
+		pvzReceptions:     map[string][]string{},
+		receptionProducts: map[string][]string{},
+	}
+}
+
+// openReception returns the newest in-progress reception of the PVZ.
+func (s *Store) openReception(pvzID string) (models.Reception, bool) {
+	var (
+		open  models.Reception
+		found bool
+	)
+	for _, id := range s.pvzReceptions[pvzID] {
+		rec := s.receptions[id]
+		if rec.Status == "in_progress" && (!found || !rec.DateTime.Before(open.DateTime)) {
+			open, found = rec, true
+		}
+	}
+
+	return open, found
+}
+
+// adjustIntake changes the counter of the product's UTC day, like AdjustDailyIntakeQuery.
+func (s *Store) adjustIntake(p models.Product, pvzID string, delta int) {
+	s.intake[intakeKey{day: utcDay(p.DateTime), pvzID: pvzID, typ: p.Type}] += delta
+}
+
+// actualIntake counts the stored products per UTC day, PVZ and type.
+func (s *Store) actualIntake() map[intakeKey]int {
+	counts := map[intakeKey]int{}
+	for _, p := range s.products {
+		counts[intakeKey{day: utcDay(p.DateTime), pvzID: s.receptions[p.ReceptionID].PVZID, typ: p.Type}]++
+	}
+
+	return counts
+}
+
+// usage returns the storage usage of the PVZ.
+func (s *Store) usage(pvzID string) models.StorageUsage {
+	pvz := s.pvzs[pvzID]
+	return models.StorageUsage{PVZID: pvz.ID, StoredItems: pvz.StoredItems, Capacity: clonePtr(pvz.Capacity)}
+}
+
+// snapshot returns the entity as JSON with database column names, nil when it doesn't exist.
+func (s *Store) snapshot(entityType, id string) json.RawMessage {
+	switch entityType {
+	case models.AuditEntityUser:
+		if user, ok := s.users[id]; ok {
+			return rowJSON(user, "password")
+		}
+	case models.AuditEntityPVZ:
+		if pvz, ok := s.pvzs[id]; ok {
+			return rowJSON(pvz)
+		}
+	case models.AuditEntityReception:
+		if rec, ok := s.receptions[id]; ok {
+			return rowJSON(rec)
+		}
+	case models.AuditEntityProduct:
+		if p, ok := s.products[id]; ok {
+			return rowJSON(p)
+		}
+	}
+
+	return nil
+}
+
+// recordChange appends an audit entry for the entity with before and its current state as
+// after. It has to be called after the change, under the write lock.
+func (s *Store) recordChange(ctx context.Context, action, entityType, id string, before json.RawMessage) {
+	actor := audit.ActorFromContext(ctx)
+	s.audit = append(s.audit, models.AuditEntry{
+		ID:         int64(len(s.audit) + 1),
+		CreatedAt:  time.Now(),
+		ActorID:    nullIfEmpty(actor.UserID),
+		ActorRole:  nullIfEmpty(actor.Role),
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   id,
+		Before:     before,
+		After:      s.snapshot(entityType, id),
+		RequestID:  nullIfEmpty(actor.RequestID),
+		IP:         nullIfEmpty(actor.IP),
+	})
+}
+
+// rowJSON encodes the db-tagged fields of row as a JSON object, like to_jsonb of a table row.
+func rowJSON(row any, omit ...string) json.RawMessage {
+	v := reflect.ValueOf(row)
+	cols := make(map[string]any, v.NumField())
+	for i := range v.NumField() {
+		col := v.Type().Field(i).Tag.Get("db")
+		if col != "" {
+			cols[col] = v.Field(i).Interface()
+		}
+	}
+	for _, col := range omit {
+		delete(cols, col)
+	}
+
+	raw, _ := json.Marshal(cols)
+	return raw
+}
+
+func utcDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+
+	v := *p
+	return &v
+}
+
+// clonePVZ copies the pointers and the working hours, so neither the caller nor the store can
+// change the other's PVZ.
+func clonePVZ(pvz models.PVZ) models.PVZ {
+	pvz.ArchivedAt = clonePtr(pvz.ArchivedAt)
+	pvz.Latitude = clonePtr(pvz.Latitude)
+	pvz.Longitude = clonePtr(pvz.Longitude)
+	pvz.Capacity = clonePtr(pvz.Capacity)
+	pvz.ExternalCode = clonePtr(pvz.ExternalCode)
+	pvz.WorkingHours = maps.Clone(pvz.WorkingHours)
+
+	return pvz
+}
@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/models"
+)
+
+type UserRepository struct {
+	s *Store
+}
+
+func NewUserRepository(s *Store) *UserRepository {
+	return &UserRepository{s: s}
+}
+
+func (r *UserRepository) Create(ctx context.Context, user models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[user.ID]; ok {
+		return errors.Errorf("memory repo: user %s already exists", user.ID)
+	}
+	for _, u := range r.s.users {
+		if u.Email == user.Email {
+			return er.ErrUserAlreadyExists
+		}
+	}
+
+	r.s.users[user.ID] = user
+	r.s.recordChange(ctx, models.AuditUserRegister, models.AuditEntityUser, user.ID, nil)
+
+	return nil
+}
+
+// GetByEmail returns sql.ErrNoRows for an unknown email, like the SQL repositories.
+func (r *UserRepository) GetByEmail(_ context.Context, email string) (models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, u := range r.s.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+
+	return models.User{}, errors.Wrap(sql.ErrNoRows, "memory repo: get user")
+}
@@ -0,0 +1,120 @@
+// Package pgxrepo implements the service repositories on top of a pgx pool. It runs the same
+// SQL as package repository, but talks the Postgres protocol natively: statements are prepared
+// once per connection and cached, UUIDs and timestamps travel in binary form, follow-up
+// statements of a change are pipelined with pgx.Batch and bulk inserts use COPY.
+//
+// Errors of Query are not checked: pgx returns the rows in the error state and the
+// pgx.Collect* helpers report it.
+package pgxrepo
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pkg/errors"
+
+	"trainee-pvz/internal/audit"
+	"trainee-pvz/internal/logger"
+	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/repository"
+)
+
+// snapshot returns the current row of the entity, nil when it doesn't exist.
+func snapshot(ctx context.Context, tx pgx.Tx, entityType, id string) ([]byte, error) {
+	var row []byte
+	err := tx.QueryRow(ctx, repository.SnapshotQueries[entityType], id).Scan(&row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "repo: snapshot %s", entityType)
+	}
+
+	return row, nil
+}
+
+// queueChange adds an audit entry for the entity to b, with before and the state the entity
+// has when the entry is written as after. It has to be queued after the change.
+func queueChange(ctx context.Context, b *pgx.Batch, action, entityType, id string, before []byte) {
+	// the after state is read by the same statement, so the entry needs no extra round trip
+	query := `
+		WITH after AS (` + repository.SnapshotQueries[entityType] + `)
+		INSERT INTO audit_log (actor_id, actor_role, action, entity_type, entity_id, before, after, request_id, ip)
+		VALUES ($2, $3, $4, $5, $1::text, $6, (SELECT * FROM after), $7, $8)
+	`
+	actor := audit.ActorFromContext(ctx)
+	b.Queue(query, id, nullIfEmpty(actor.UserID), nullIfEmpty(actor.Role), action, entityType,
+		before, nullIfEmpty(actor.RequestID), nullIfEmpty(actor.IP))
+}
+
+// batchTables are the tables recordBatch reads the after state of new entities from.
+var batchTables = map[string]string{
+	models.AuditEntityPVZ:     "pvz",
+	models.AuditEntityProduct: "products",
+}
+
+// recordBatch writes an audit entry for each of the just created entities with one statement,
+// their rows are the after state.
+func recordBatch(ctx context.Context, tx pgx.Tx, action, entityType string, ids []string) error {
+	// ids are sent as text[], pgx can't encode strings into a binary uuid[]
+	query := `
+		INSERT INTO audit_log (actor_id, actor_role, action, entity_type, entity_id, after, request_id, ip)
+		SELECT $2, $3, $4, $5, t.id::text, to_jsonb(t), $6, $7
+		FROM ` + batchTables[entityType] + ` t
+		WHERE t.id = ANY($1::text[]::uuid[])
+	`
+	actor := audit.ActorFromContext(ctx)
+	_, err := tx.Exec(ctx, query, ids, nullIfEmpty(actor.UserID), nullIfEmpty(actor.Role),
+		action, entityType, nullIfEmpty(actor.RequestID), nullIfEmpty(actor.IP))
+	if err != nil {
+		logger.FromContext(ctx).Error("write audit log failed", slog.Any("err", err))
+		return errors.Wrap(err, "repo: write audit log")
+	}
+
+	return nil
+}
+
+// sendBatch runs the queued statements in one round trip. Outside of a transaction the batch
+// is still atomic, as it is executed in an implicit one.
+func sendBatch(ctx context.Context, db interface {
+	SendBatch(context.Context, *pgx.Batch) pgx.BatchResults
+}, b *pgx.Batch, op string) error {
+	err := db.SendBatch(ctx, b).Close()
+	if err != nil {
+		logger.FromContext(ctx).Error(op+" failed", slog.Any("err", err))
+		return errors.Wrap(err, "repo: "+op)
+	}
+
+	return nil
+}
+
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
+
+type AuditRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAuditRepository(db *pgxpool.Pool) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// List returns entries matching the filter, newest first, with id below afterID when it's set.
+func (r *AuditRepository) List(ctx context.Context, filter models.AuditFilter, afterID int64, limit int) ([]models.AuditEntry, error) {
+	query, args := repository.AuditListQuery(filter, afterID, limit)
+	rows, _ := r.db.Query(ctx, query, args...)
+	entries, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.AuditEntry])
+	if err != nil {
+		logger.FromContext(ctx).Error("select audit log failed", slog.Any("err", err))
+		return nil, errors.Wrap(err, "audit repo: list")
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,52 @@
+package pgxrepo
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pkg/errors"
+
+	"trainee-pvz/internal/logger"
+	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/repository"
+)
+
+type ExportRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewExportRepository(db *pgxpool.Pool) *ExportRepository {
+	return &ExportRepository{db: db}
+}
+
+// StreamReceptions passes export rows to fn one by one as they are read from the connection,
+// so the result set is never held in memory. An error from fn stops the iteration.
+func (r *ExportRepository) StreamReceptions(ctx context.Context, q models.ExportQuery, fn func(models.ExportRow) error) error {
+	rows, err := r.db.Query(ctx, repository.ExportReceptionsQuery, q.PVZID, q.From, q.To)
+	if err != nil {
+		logger.FromContext(ctx).Error("select export rows failed", slog.Any("err", err))
+		return errors.Wrap(err, "export repo: select receptions")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row, err := pgx.RowToStructByName[models.ExportRow](rows)
+		if err != nil {
+			return errors.Wrap(err, "export repo: scan row")
+		}
+
+		err = fn(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		logger.FromContext(ctx).Error("iterate export rows failed", slog.Any("err", err))
+		return errors.Wrap(err, "export repo: iterate rows")
+	}
+
+	return nil
+}
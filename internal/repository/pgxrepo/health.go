@@ -0,0 +1,36 @@
+package pgxrepo
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pkg/errors"
+
+	"trainee-pvz/internal/logger"
+	"trainee-pvz/internal/repository"
+)
+
+type HealthRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewHealthRepository(db *pgxpool.Pool) *HealthRepository {
+	return &HealthRepository{db: db}
+}
+
+func (r *HealthRepository) Ping(ctx context.Context) error {
+	return r.db.Ping(ctx)
+}
+
+// MigrationVersion returns the newest applied goose migration.
+func (r *HealthRepository) MigrationVersion(ctx context.Context) (int64, error) {
+	var version int64
+	err := r.db.QueryRow(ctx, repository.MigrationVersionQuery).Scan(&version)
+	if err != nil {
+		logger.FromContext(ctx).Error("select migration version failed", slog.Any("err", err))
+		return 0, errors.Wrap(err, "health repo: migration version")
+	}
+
+	return version, nil
+}
@@ -0,0 +1,45 @@
+package pgxrepo_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"trainee-pvz/config"
+	"trainee-pvz/internal/database"
+	"trainee-pvz/internal/repository/pgtest"
+	"trainee-pvz/internal/repository/pgxrepo"
+	"trainee-pvz/internal/repository/repotest"
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	pgtest.Stop()
+	os.Exit(code)
+}
+
+// TestContract runs the repository contract on the pgx repositories, every case on a freshly
+// migrated schema.
+func TestContract(t *testing.T) {
+	ctx := context.Background()
+	conn := pgtest.Connection(ctx, t)
+
+	repotest.Run(t, func(t *testing.T) repotest.Repositories {
+		pool, err := database.ConnectPool(ctx, pgtest.Schema(ctx, t, conn), config.Default().DB)
+		require.NoError(t, err)
+		t.Cleanup(pool.Close)
+
+		return repotest.Repositories{
+			User:      pgxrepo.NewUserRepository(pool),
+			Reception: pgxrepo.NewReceptionRepository(pool),
+			Product:   pgxrepo.NewProductRepository(pool),
+			PVZ:       pgxrepo.NewPVZRepository(pool, pool),
+			Stats:     pgxrepo.NewStatsRepository(pool, pool),
+			Export:    pgxrepo.NewExportRepository(pool),
+			Audit:     pgxrepo.NewAuditRepository(pool),
+			Health:    pgxrepo.NewHealthRepository(pool),
+		}
+	})
+}
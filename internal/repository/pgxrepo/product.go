@@ -0,0 +1,276 @@
+package pgxrepo
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pkg/errors"
+
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/logger"
+	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/repository"
+)
+
+const decrementStoredItemsQuery = `UPDATE pvz SET stored_items = stored_items - 1 WHERE id = $1 RETURNING id, stored_items, capacity`
+
+type ProductRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewProductRepository(db *pgxpool.Pool) *ProductRepository {
+	return &ProductRepository{db: db}
+}
+
+// scanUsage reads a RETURNING id, stored_items, capacity row into usage.
+func scanUsage(usage *models.StorageUsage) func(row pgx.Row) error {
+	return func(row pgx.Row) error {
+		return row.Scan(&usage.PVZID, &usage.StoredItems, &usage.Capacity)
+	}
+}
+
+// Add stores the product and increments the PVZ stored items counter in one transaction.
+// With enforceCapacity the transaction is rolled back when the PVZ would exceed its capacity.
+func (r *ProductRepository) Add(ctx context.Context, p models.Product, enforceCapacity bool) (models.StorageUsage, error) {
+	var usage models.StorageUsage
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return usage, errors.Wrap(err, "product repo: begin tx")
+	}
+	defer tx.Rollback(ctx)
+
+	err = lockOpenReception(ctx, tx, p.ReceptionID)
+	if err != nil {
+		return usage, err
+	}
+
+	err = scanUsage(&usage)(tx.QueryRow(ctx, repository.AddStoredItemsQuery, p.ReceptionID, 1))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return usage, er.ErrNoOpenReception
+		}
+		logger.FromContext(ctx).Error("increment stored items failed", slog.Any("err", err))
+		return usage, errors.Wrap(err, "product repo: increment stored items")
+	}
+
+	if enforceCapacity && usage.OverCapacity() {
+		usage.StoredItems--
+		return usage, er.ErrPVZOverCapacity
+	}
+
+	b := &pgx.Batch{}
+	b.Queue(`INSERT INTO products (id, datetime, type, reception_id) VALUES ($1, $2, $3, $4)`,
+		p.ID, p.DateTime, p.Type, p.ReceptionID)
+	b.Queue(repository.AdjustDailyIntakeQuery, p.DateTime, usage.PVZID, p.Type, 1)
+	queueChange(ctx, b, models.AuditProductAdd, models.AuditEntityProduct, p.ID, nil)
+	err = sendBatch(ctx, tx, b, "add product")
+	if err != nil {
+		return usage, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return usage, errors.Wrap(err, "product repo: commit add product")
+	}
+
+	return usage, nil
+}
+
+// AddBatch copies products of one reception in with COPY, the stored items counter is updated
+// before and the daily intake and audit entries after it, all in one transaction: either all
+// of them are added or none.
+func (r *ProductRepository) AddBatch(ctx context.Context, receptionID string, products []models.Product, enforceCapacity bool) (models.StorageUsage, error) {
+	var usage models.StorageUsage
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return usage, errors.Wrap(err, "product repo: begin tx")
+	}
+	defer tx.Rollback(ctx)
+
+	err = lockOpenReception(ctx, tx, receptionID)
+	if err != nil {
+		return usage, err
+	}
+
+	err = scanUsage(&usage)(tx.QueryRow(ctx, repository.AddStoredItemsQuery, receptionID, len(products)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return usage, er.ErrNoOpenReception
+		}
+		logger.FromContext(ctx).Error("increment stored items failed", slog.Any("err", err))
+		return usage, errors.Wrap(err, "product repo: increment stored items")
+	}
+
+	if enforceCapacity && usage.OverCapacity() {
+		usage.StoredItems -= len(products)
+		return usage, er.ErrPVZOverCapacity
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"products"}, []string{"id", "datetime", "type", "reception_id"},
+		pgx.CopyFromSlice(len(products), func(i int) ([]any, error) {
+			p := products[i]
+			return []any{p.ID, p.DateTime, p.Type, receptionID}, nil
+		}))
+	if err != nil {
+		logger.FromContext(ctx).Error("batch add product failed", slog.Any("err", err))
+		return usage, errors.Wrap(err, "product repo: batch add product")
+	}
+
+	ids := make([]string, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+
+	err = recordBatch(ctx, tx, models.AuditProductAdd, models.AuditEntityProduct, ids)
+	if err != nil {
+		return usage, err
+	}
+
+	_, err = tx.Exec(ctx, repository.CountDailyIntakeQuery, ids, usage.PVZID)
+	if err != nil {
+		logger.FromContext(ctx).Error("adjust daily intake failed", slog.Any("err", err))
+		return usage, errors.Wrap(err, "product repo: adjust daily intake")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return usage, errors.Wrap(err, "product repo: commit product batch")
+	}
+
+	return usage, nil
+}
+
+// DeleteLast removes the newest product of the open reception. The reception is locked first,
+// so deletions of one PVZ take turns; the delete and the counter updates follow in one batch.
+func (r *ProductRepository) DeleteLast(ctx context.Context, pvzID string) (models.StorageUsage, error) {
+	var usage models.StorageUsage
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return usage, errors.Wrap(err, "product repo: begin tx")
+	}
+	defer tx.Rollback(ctx)
+
+	var receptionID string
+	err = tx.QueryRow(ctx, repository.LockOpenReceptionIDQuery, pvzID).Scan(&receptionID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return usage, er.ErrNoProducts
+		}
+		logger.FromContext(ctx).Error("lock open reception failed", slog.Any("err", err))
+		return usage, errors.Wrap(err, "product repo: lock open reception")
+	}
+
+	var (
+		product models.Product
+		before  []byte
+	)
+	queryProduct := `
+		SELECT p.id, p.datetime, p.type, to_jsonb(p)
+		FROM products p
+		WHERE p.reception_id = $1
+		ORDER BY p.datetime DESC
+		LIMIT 1
+	`
+	err = tx.QueryRow(ctx, queryProduct, receptionID).Scan(&product.ID, &product.DateTime, &product.Type, &before)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return usage, er.ErrNoProducts
+		}
+		logger.FromContext(ctx).Error("get last product failed", slog.Any("err", err))
+		return usage, errors.Wrap(err, "product repo: get last product")
+	}
+
+	b := &pgx.Batch{}
+	b.Queue(`DELETE FROM products WHERE id = $1`, product.ID)
+	b.Queue(repository.AdjustDailyIntakeQuery, product.DateTime, pvzID, product.Type, -1)
+	queueChange(ctx, b, models.AuditProductDelete, models.AuditEntityProduct, product.ID, before)
+	b.Queue(decrementStoredItemsQuery, pvzID).QueryRow(scanUsage(&usage))
+	err = sendBatch(ctx, tx, b, "delete product")
+	if err != nil {
+		return usage, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return usage, errors.Wrap(err, "product repo: commit delete product")
+	}
+
+	return usage, nil
+}
+
+// Issue marks a product from a closed reception as handed over and frees its storage slot.
+func (r *ProductRepository) Issue(ctx context.Context, productID string, issuedAt time.Time) (models.StorageUsage, error) {
+	var usage models.StorageUsage
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return usage, errors.Wrap(err, "product repo: begin tx")
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		alreadyIssuedAt *time.Time
+		status, pvzID   string
+		before          []byte
+	)
+	queryProduct := `
+		SELECT p.issued_at, r.status, r.pvz_id, to_jsonb(p)
+		FROM products p
+		JOIN receptions r ON r.id = p.reception_id
+		WHERE p.id = $1
+		FOR UPDATE OF p
+	`
+	err = tx.QueryRow(ctx, queryProduct, productID).Scan(&alreadyIssuedAt, &status, &pvzID, &before)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return usage, er.ErrProductNotFound
+		}
+		logger.FromContext(ctx).Error("get product for issue failed", slog.Any("err", err))
+		return usage, errors.Wrap(err, "product repo: get product")
+	}
+
+	if alreadyIssuedAt != nil {
+		return usage, er.ErrProductAlreadyIssued
+	}
+	if status != "close" {
+		return usage, er.ErrReceptionNotClosed
+	}
+
+	b := &pgx.Batch{}
+	b.Queue(`UPDATE products SET issued_at = $2 WHERE id = $1`, productID, issuedAt)
+	b.Queue(decrementStoredItemsQuery, pvzID).QueryRow(scanUsage(&usage))
+	queueChange(ctx, b, models.AuditProductIssue, models.AuditEntityProduct, productID, before)
+	err = sendBatch(ctx, tx, b, "issue product")
+	if err != nil {
+		return usage, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return usage, errors.Wrap(err, "product repo: commit issue product")
+	}
+
+	return usage, nil
+}
+
+// lockOpenReception locks the reception products are added to, ErrNoOpenReception when it's
+// not in progress.
+func lockOpenReception(ctx context.Context, tx pgx.Tx, receptionID string) error {
+	var found int
+	err := tx.QueryRow(ctx, repository.LockOpenReceptionQuery, receptionID).Scan(&found)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return er.ErrNoOpenReception
+		}
+		logger.FromContext(ctx).Error("lock reception failed", slog.Any("err", err))
+		return errors.Wrap(err, "product repo: lock reception")
+	}
+
+	return nil
+}
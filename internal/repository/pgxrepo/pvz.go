@@ -0,0 +1,284 @@
+package pgxrepo
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pkg/errors"
+
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/logger"
+	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/pagination"
+	"trainee-pvz/internal/repository"
+)
+
+// pvzInsertColumns are the columns set on creation, in the order of pvzInsertValues.
+var pvzInsertColumns = []string{
+	"id", "city", "registration_date", "address", "latitude", "longitude", "working_hours", "capacity", "external_code",
+}
+
+func pvzInsertValues(pvz models.PVZ) []any {
+	return []any{
+		pvz.ID, pvz.City, pvz.RegistrationDate, pvz.Address, pvz.Latitude, pvz.Longitude,
+		pvz.WorkingHours, pvz.Capacity, pvz.ExternalCode,
+	}
+}
+
+type PVZRepository struct {
+	db      *pgxpool.Pool
+	replica *pgxpool.Pool
+}
+
+// NewPVZRepository writes to db and serves list and nearby reads from replica, which may lag
+// behind; pass db twice when there is no replica.
+func NewPVZRepository(db, replica *pgxpool.Pool) *PVZRepository {
+	return &PVZRepository{db: db, replica: replica}
+}
+
+func (r *PVZRepository) Create(ctx context.Context, pvz models.PVZ) error {
+	query := `
+		INSERT INTO pvz (id, city, registration_date, address, latitude, longitude, working_hours, capacity, external_code)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	b := &pgx.Batch{}
+	b.Queue(query, pvzInsertValues(pvz)...)
+	queueChange(ctx, b, models.AuditPVZCreate, models.AuditEntityPVZ, pvz.ID, nil)
+
+	err := r.db.SendBatch(ctx, b).Close()
+	if isExternalCodeViolation(err) {
+		return er.ErrExternalCodeExists
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("create pvz failed", slog.Any("err", err))
+		return errors.Wrap(err, "repo: create pvz")
+	}
+
+	return nil
+}
+
+// CreateBatch copies all PVZs in with COPY and writes their audit entries with one statement,
+// in one transaction: either all of them are created or none.
+func (r *PVZRepository) CreateBatch(ctx context.Context, pvzs []models.PVZ) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return errors.Wrap(err, "repo: begin tx")
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"pvz"}, pvzInsertColumns,
+		pgx.CopyFromSlice(len(pvzs), func(i int) ([]any, error) {
+			return pvzInsertValues(pvzs[i]), nil
+		}))
+	if isExternalCodeViolation(err) {
+		return er.ErrExternalCodeExists
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("batch create pvz failed", slog.Any("err", err))
+		return errors.Wrap(err, "repo: batch create pvz")
+	}
+
+	ids := make([]string, 0, len(pvzs))
+	for _, pvz := range pvzs {
+		ids = append(ids, pvz.ID)
+	}
+
+	err = recordBatch(ctx, tx, models.AuditPVZImport, models.AuditEntityPVZ, ids)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return errors.Wrap(err, "repo: commit pvz batch")
+	}
+
+	return nil
+}
+
+// ExistingExternalCodes returns those of codes that are already taken.
+func (r *PVZRepository) ExistingExternalCodes(ctx context.Context, codes []string) ([]string, error) {
+	rows, _ := r.db.Query(ctx, `SELECT external_code FROM pvz WHERE external_code = ANY($1)`, codes)
+	existing, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		logger.FromContext(ctx).Error("select external codes failed", slog.Any("err", err))
+		return nil, errors.Wrap(err, "repo: select external codes")
+	}
+
+	return existing, nil
+}
+
+func isExternalCodeViolation(err error) bool {
+	return isUniqueViolation(err, "pvz_external_code_key")
+}
+
+func isUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == constraint
+}
+
+func (r *PVZRepository) GetByID(ctx context.Context, id string) (models.PVZ, error) {
+	rows, _ := r.db.Query(ctx, `SELECT `+repository.PVZColumns+` FROM pvz WHERE id = $1`, id)
+	pvz, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.PVZ])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pvz, er.ErrNoPVZ
+		}
+		logger.FromContext(ctx).Error("get pvz failed", slog.Any("err", err))
+		return pvz, errors.Wrap(err, "repo: get pvz")
+	}
+
+	return pvz, nil
+}
+
+func (r *PVZRepository) Update(ctx context.Context, pvz models.PVZ) error {
+	query := `
+		UPDATE pvz
+		SET address = $2, latitude = $3, longitude = $4, working_hours = $5, capacity = $6
+		WHERE id = $1
+	`
+	return r.changeLocked(ctx, pvz.ID, models.AuditPVZUpdate, "update pvz", func(b *pgx.Batch) {
+		b.Queue(query, pvz.ID, pvz.Address, pvz.Latitude, pvz.Longitude, pvz.WorkingHours, pvz.Capacity)
+	})
+}
+
+// Archive sets archived_at of the PVZ unless it is archived already or has an open reception.
+// The PVZ row is locked for the checks, so a reception can't be opened in between.
+func (r *PVZRepository) Archive(ctx context.Context, id string, archivedAt time.Time) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return errors.Wrap(err, "repo: begin tx")
+	}
+	defer tx.Rollback(ctx)
+
+	var archived bool
+	err = tx.QueryRow(ctx, repository.LockPVZQuery, id).Scan(&archived)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return er.ErrNoPVZ
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("lock pvz failed", slog.Any("err", err))
+		return errors.Wrap(err, "repo: lock pvz")
+	}
+	if archived {
+		return er.ErrPVZArchived
+	}
+
+	var hasOpen bool
+	err = tx.QueryRow(ctx, repository.HasOpenReceptionQuery, id).Scan(&hasOpen)
+	if err != nil {
+		return errors.Wrap(err, "repo: check open reception")
+	}
+	if hasOpen {
+		return er.ErrPVZHasOpenReception
+	}
+
+	before, err := snapshot(ctx, tx, models.AuditEntityPVZ, id)
+	if err != nil {
+		return err
+	}
+
+	b := &pgx.Batch{}
+	b.Queue(`UPDATE pvz SET archived_at = $2 WHERE id = $1`, id, archivedAt)
+	queueChange(ctx, b, models.AuditPVZArchive, models.AuditEntityPVZ, id, before)
+	err = sendBatch(ctx, tx, b, "archive pvz")
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return errors.Wrap(err, "repo: commit archive pvz")
+	}
+
+	return nil
+}
+
+func (r *PVZRepository) SetArchivedAt(ctx context.Context, id string, archivedAt *time.Time) error {
+	action := models.AuditPVZArchive
+	if archivedAt == nil {
+		action = models.AuditPVZRestore
+	}
+
+	return r.changeLocked(ctx, id, action, "set pvz archived_at", func(b *pgx.Batch) {
+		b.Queue(`UPDATE pvz SET archived_at = $2 WHERE id = $1`, id, archivedAt)
+	})
+}
+
+// changeLocked locks the PVZ row and takes its snapshot, then sends the statements queued by
+// change together with the audit entry in one batch.
+func (r *PVZRepository) changeLocked(ctx context.Context, id, action, op string, change func(b *pgx.Batch)) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return errors.Wrap(err, "repo: begin tx")
+	}
+	defer tx.Rollback(ctx)
+
+	var before []byte
+	err = tx.QueryRow(ctx, `SELECT to_jsonb(t) FROM pvz t WHERE t.id = $1 FOR UPDATE`, id).Scan(&before)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return er.ErrNoPVZ
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("lock pvz failed", slog.Any("err", err))
+		return errors.Wrap(err, "repo: lock pvz")
+	}
+
+	b := &pgx.Batch{}
+	change(b)
+	queueChange(ctx, b, action, models.AuditEntityPVZ, id, before)
+	err = sendBatch(ctx, tx, b, op)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return errors.Wrap(err, "repo: commit "+op)
+	}
+
+	return nil
+}
+
+// Nearby returns active PVZs within q.RadiusKm of the point ordered by great-circle distance.
+// A bounding box is applied first so pvz_location_idx can be used, then the exact
+// Haversine distance filters the corners out.
+func (r *PVZRepository) Nearby(ctx context.Context, q models.GeoQuery) ([]models.NearbyPVZ, error) {
+	minLat, maxLat, minLon, maxLon := repository.BoundingBox(q.Latitude, q.Longitude, q.RadiusKm)
+
+	rows, _ := r.replica.Query(ctx, repository.NearbyPVZQuery, q.Latitude, q.Longitude, repository.EarthRadiusKm,
+		minLat, maxLat, minLon, maxLon, q.RadiusKm, q.Limit)
+	res, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.NearbyPVZ])
+	if err != nil {
+		logger.FromContext(ctx).Error("select nearby pvz failed", slog.Any("err", err))
+		return nil, errors.Wrap(err, "repo: nearby pvz")
+	}
+
+	return res, nil
+}
+
+// List returns PVZs in (registration_date DESC, id DESC) order starting right after the
+// given cursor (from the beginning when after is nil).
+func (r *PVZRepository) List(ctx context.Context, filter models.PVZFilter, after *pagination.Cursor, limit int) ([]models.PVZ, error) {
+	var (
+		afterDate *time.Time
+		afterID   *string
+	)
+	if after != nil {
+		afterDate, afterID = &after.RegistrationDate, &after.ID
+	}
+
+	rows, _ := r.replica.Query(ctx, repository.ListPVZQuery,
+		filter.StartDate, filter.EndDate, filter.IncludeArchived, afterDate, afterID, limit)
+	pvzList, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.PVZ])
+	if err != nil {
+		logger.FromContext(ctx).Error("select pvz failed", slog.Any("err", err))
+		return nil, errors.Wrap(err, "repo: list pvz")
+	}
+
+	return pvzList, nil
+}
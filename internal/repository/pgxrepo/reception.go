@@ -0,0 +1,142 @@
+package pgxrepo
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pkg/errors"
+
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/logger"
+	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/repository"
+)
+
+type ReceptionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewReceptionRepository(db *pgxpool.Pool) *ReceptionRepository {
+	return &ReceptionRepository{db: db}
+}
+
+func (r *ReceptionRepository) HasOpenReception(ctx context.Context, pvzID string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx, repository.HasOpenReceptionQuery, pvzID).Scan(&exists)
+	return exists, err
+}
+
+func (r *ReceptionRepository) IsPVZArchived(ctx context.Context, pvzID string) (bool, error) {
+	var archived bool
+	err := r.db.QueryRow(ctx, `SELECT archived_at IS NOT NULL FROM pvz WHERE id = $1`, pvzID).Scan(&archived)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, er.ErrNoPVZ
+		}
+		logger.FromContext(ctx).Error("failed to get pvz archive state", slog.Any("err", err))
+		return false, errors.Wrap(err, "reception repo: get pvz archive state")
+	}
+
+	return archived, nil
+}
+
+// GetOpenReceptionID returns the newest in-progress reception of the PVZ.
+func (r *ReceptionRepository) GetOpenReceptionID(ctx context.Context, pvzID string) (string, error) {
+	var id string
+	err := r.db.QueryRow(ctx, repository.OpenReceptionIDQuery, pvzID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", er.ErrNoOpenReception
+		}
+		logger.FromContext(ctx).Error("get open reception failed", slog.Any("err", err))
+		return "", errors.Wrap(err, "reception repo: get open reception")
+	}
+
+	return id, nil
+}
+
+// Create opens the reception unless the PVZ is archived or has an open one; the PVZ is locked
+// for the checks, so concurrent creates and archiving take turns.
+func (r *ReceptionRepository) Create(ctx context.Context, rec models.Reception) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return errors.Wrap(err, "reception repo: begin tx")
+	}
+	defer tx.Rollback(ctx)
+
+	var archived bool
+	err = tx.QueryRow(ctx, repository.LockPVZQuery, rec.PVZID).Scan(&archived)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return er.ErrNoPVZ
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("lock pvz failed", slog.Any("err", err))
+		return errors.Wrap(err, "reception repo: lock pvz")
+	}
+	if archived {
+		return er.ErrPVZArchived
+	}
+
+	var hasOpen bool
+	err = tx.QueryRow(ctx, repository.HasOpenReceptionQuery, rec.PVZID).Scan(&hasOpen)
+	if err != nil {
+		return errors.Wrap(err, "reception repo: check open reception")
+	}
+	if hasOpen {
+		return er.ErrReceptionAlreadyExists
+	}
+
+	b := &pgx.Batch{}
+	b.Queue(`INSERT INTO receptions (id, datetime, pvz_id, status, created_by) VALUES ($1, $2, $3, $4, $5)`,
+		rec.ID, rec.DateTime, rec.PVZID, rec.Status, rec.CreatedBy)
+	queueChange(ctx, b, models.AuditReceptionCreate, models.AuditEntityReception, rec.ID, nil)
+	err = sendBatch(ctx, tx, b, "create reception")
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return errors.Wrap(err, "reception repo: commit create reception")
+	}
+
+	return nil
+}
+
+// Close closes the reception; the update and its audit entry go in one batch after the
+// reception is locked and its snapshot taken.
+func (r *ReceptionRepository) Close(ctx context.Context, id string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return errors.Wrap(err, "reception repo: begin tx")
+	}
+	defer tx.Rollback(ctx)
+
+	var before []byte
+	query := `SELECT to_jsonb(t) FROM receptions t WHERE t.id = $1 AND t.status = 'in_progress' FOR UPDATE`
+	err = tx.QueryRow(ctx, query, id).Scan(&before)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return er.ErrNoOpenReception
+		}
+		logger.FromContext(ctx).Error("lock reception failed", slog.Any("err", err))
+		return errors.Wrap(err, "reception repo: lock reception")
+	}
+
+	b := &pgx.Batch{}
+	b.Queue(repository.CloseReceptionQuery, id)
+	queueChange(ctx, b, models.AuditReceptionClose, models.AuditEntityReception, id, before)
+	err = sendBatch(ctx, tx, b, "close reception")
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return errors.Wrap(err, "reception repo: commit close reception")
+	}
+
+	return nil
+}
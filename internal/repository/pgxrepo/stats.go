@@ -0,0 +1,134 @@
+package pgxrepo
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pkg/errors"
+
+	"trainee-pvz/internal/logger"
+	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/repository"
+)
+
+type StatsRepository struct {
+	db      *pgxpool.Pool
+	replica *pgxpool.Pool
+}
+
+// NewStatsRepository serves the report queries from replica and everything else from db;
+// pass db twice when there is no replica.
+func NewStatsRepository(db, replica *pgxpool.Pool) *StatsRepository {
+	return &StatsRepository{db: db, replica: replica}
+}
+
+// IntakeRows reads the pre-aggregated daily_intake table, so the period is expected to be
+// aligned to UTC days.
+func (r *StatsRepository) IntakeRows(ctx context.Context, q models.IntakeQuery) ([]models.IntakeRow, error) {
+	rows, _ := r.replica.Query(ctx, repository.IntakeRowsQuery(q.GroupBy), q.Bucket, q.From, q.To)
+	res, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.IntakeRow])
+	if err != nil {
+		logger.FromContext(ctx).Error("select intake stats failed", slog.Any("err", err))
+		return nil, errors.Wrap(err, "stats repo: intake rows")
+	}
+
+	return res, nil
+}
+
+func (r *StatsRepository) ReceptionSummary(ctx context.Context, q models.IntakeQuery) (models.ReceptionSummary, error) {
+	var summary models.ReceptionSummary
+	err := r.replica.QueryRow(ctx, repository.ReceptionSummaryQuery, q.From, q.To).
+		Scan(&summary.Receptions, &summary.AvgDurationSeconds, &summary.AvgProductsPerReception)
+	if err != nil {
+		logger.FromContext(ctx).Error("select reception summary failed", slog.Any("err", err))
+		return summary, errors.Wrap(err, "stats repo: reception summary")
+	}
+
+	return summary, nil
+}
+
+// OpenReceptionsByCity counts in-progress receptions per city; cities without any are omitted.
+func (r *StatsRepository) OpenReceptionsByCity(ctx context.Context) (map[string]int64, error) {
+	var (
+		city       string
+		receptions int64
+	)
+	counts := map[string]int64{}
+	rows, _ := r.db.Query(ctx, repository.OpenReceptionsByCityQuery)
+	_, err := pgx.ForEachRow(rows, []any{&city, &receptions}, func() error {
+		counts[city] = receptions
+		return nil
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("select open receptions failed", slog.Any("err", err))
+		return nil, errors.Wrap(err, "stats repo: open receptions")
+	}
+
+	return counts, nil
+}
+
+// CapacityUtilisationByCity returns stored items against capacity per city, over active PVZs
+// with a capacity; cities without such PVZs are omitted.
+func (r *StatsRepository) CapacityUtilisationByCity(ctx context.Context) (map[string]float64, error) {
+	var (
+		city  string
+		value float64
+	)
+	utilisation := map[string]float64{}
+	rows, _ := r.db.Query(ctx, repository.CapacityUtilisationByCityQuery)
+	_, err := pgx.ForEachRow(rows, []any{&city, &value}, func() error {
+		utilisation[city] = value
+		return nil
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("select capacity utilisation failed", slog.Any("err", err))
+		return nil, errors.Wrap(err, "stats repo: capacity utilisation")
+	}
+
+	return utilisation, nil
+}
+
+// RebuildDailyIntake recomputes daily_intake from products. The table is locked for the
+// duration, so concurrent product changes wait and are applied on top of the rebuilt counters.
+func (r *StatsRepository) RebuildDailyIntake(ctx context.Context) (int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "stats repo: begin tx")
+	}
+	defer tx.Rollback(ctx)
+
+	var filled int64
+	b := &pgx.Batch{}
+	b.Queue(`LOCK TABLE daily_intake IN EXCLUSIVE MODE`)
+	b.Queue(`DELETE FROM daily_intake`)
+	b.Queue(repository.FillDailyIntakeQuery).Exec(func(tag pgconn.CommandTag) error {
+		filled = tag.RowsAffected()
+		return nil
+	})
+	err = sendBatch(ctx, tx, b, "rebuild daily intake")
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "stats repo: commit daily intake")
+	}
+
+	return filled, nil
+}
+
+// DailyIntakeMismatches lists the days where daily_intake and the raw products disagree.
+func (r *StatsRepository) DailyIntakeMismatches(ctx context.Context) ([]models.DailyIntakeMismatch, error) {
+	rows, _ := r.db.Query(ctx, repository.DailyIntakeMismatchesQuery)
+	mismatches, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.DailyIntakeMismatch])
+	if err != nil {
+		logger.FromContext(ctx).Error("compare daily intake failed", slog.Any("err", err))
+		return nil, errors.Wrap(err, "stats repo: compare daily intake")
+	}
+
+	return mismatches, nil
+}
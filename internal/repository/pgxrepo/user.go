@@ -0,0 +1,52 @@
+package pgxrepo
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pkg/errors"
+
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/logger"
+	"trainee-pvz/internal/models"
+)
+
+type UserRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewUserRepository(db *pgxpool.Pool) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+func (r *UserRepository) Create(ctx context.Context, user models.User) error {
+	b := &pgx.Batch{}
+	b.Queue(`INSERT INTO users (id, email, password, role) VALUES ($1, $2, $3, $4)`,
+		user.ID, user.Email, user.Password, user.Role)
+	queueChange(ctx, b, models.AuditUserRegister, models.AuditEntityUser, user.ID, nil)
+
+	err := r.db.SendBatch(ctx, b).Close()
+	if isUniqueViolation(err, "users_email_key") {
+		return er.ErrUserAlreadyExists
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("create user failed", slog.Any("err", err))
+		return errors.Wrap(err, "repo: create user")
+	}
+
+	return nil
+}
+
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (models.User, error) {
+	var user models.User
+	query := `SELECT id, email, password, role FROM users WHERE email = $1`
+	err := r.db.QueryRow(ctx, query, email).Scan(&user.ID, &user.Email, &user.Password, &user.Role)
+	if err != nil {
+		logger.FromContext(ctx).Error("get user by email failed", slog.Any("err", err))
+		return user, errors.Wrap(err, "repo: get user")
+	}
+
+	return user, nil
+}
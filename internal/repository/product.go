@@ -4,14 +4,19 @@ import (
 	"context"
 	"database/sql"
 	"log/slog"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
 
 	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/logger"
 	"trainee-pvz/internal/models"
 )
 
+const insertProductQuery = `INSERT INTO products (id, datetime, type, reception_id) VALUES (:id, :datetime, :type, :reception_id)`
+
 type ProductRepository struct {
 	db *sqlx.DB
 }
@@ -20,46 +25,278 @@ func NewProductRepository(db *sqlx.DB) *ProductRepository {
 	return &ProductRepository{db: db}
 }
 
-func (r *ProductRepository) Add(ctx context.Context, p models.Product) error {
-	query := `INSERT INTO products (id, datetime, type, reception_id) VALUES (:id, :datetime, :type, :reception_id)`
-	_, err := r.db.NamedExecContext(ctx, query, p)
+// Add stores the product and increments the PVZ stored items counter in one transaction.
+// With enforceCapacity the transaction is rolled back when the PVZ would exceed its capacity.
+func (r *ProductRepository) Add(ctx context.Context, p models.Product, enforceCapacity bool) (models.StorageUsage, error) {
+	var usage models.StorageUsage
+
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
-		slog.Error("add product failed", slog.Any("err", err))
-		return errors.Wrap(err, "product repo: add product")
+		return usage, errors.Wrap(err, "product repo: begin tx")
 	}
-	return nil
+	defer tx.Rollback()
+
+	err = lockOpenReception(ctx, tx, p.ReceptionID)
+	if err != nil {
+		return usage, err
+	}
+
+	err = tx.GetContext(ctx, &usage, AddStoredItemsQuery, p.ReceptionID, 1)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return usage, er.ErrNoOpenReception
+		}
+		logger.FromContext(ctx).Error("increment stored items failed", slog.Any("err", err))
+		return usage, errors.Wrap(err, "product repo: increment stored items")
+	}
+
+	if enforceCapacity && usage.OverCapacity() {
+		usage.StoredItems--
+		return usage, er.ErrPVZOverCapacity
+	}
+
+	_, err = tx.NamedExecContext(ctx, insertProductQuery, p)
+	if err != nil {
+		logger.FromContext(ctx).Error("add product failed", slog.Any("err", err))
+		return usage, errors.Wrap(err, "product repo: add product")
+	}
+
+	err = adjustDailyIntake(ctx, tx, p.DateTime, usage.PVZID, p.Type, 1)
+	if err != nil {
+		return usage, err
+	}
+
+	err = recordChange(ctx, tx, models.AuditProductAdd, models.AuditEntityProduct, p.ID, nil)
+	if err != nil {
+		return usage, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return usage, errors.Wrap(err, "product repo: commit add product")
+	}
+
+	return usage, nil
 }
 
-func (r *ProductRepository) DeleteLast(ctx context.Context, pvzID string) error {
+// AddBatch stores products of one reception and updates the stored items and daily intake
+// counters in one transaction: either all of them are added or none.
+func (r *ProductRepository) AddBatch(ctx context.Context, receptionID string, products []models.Product, enforceCapacity bool) (models.StorageUsage, error) {
+	var usage models.StorageUsage
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return usage, errors.Wrap(err, "product repo: begin tx")
+	}
+	defer tx.Rollback()
+
+	err = lockOpenReception(ctx, tx, receptionID)
+	if err != nil {
+		return usage, err
+	}
+
+	err = tx.GetContext(ctx, &usage, AddStoredItemsQuery, receptionID, len(products))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return usage, er.ErrNoOpenReception
+		}
+		logger.FromContext(ctx).Error("increment stored items failed", slog.Any("err", err))
+		return usage, errors.Wrap(err, "product repo: increment stored items")
+	}
+
+	if enforceCapacity && usage.OverCapacity() {
+		usage.StoredItems -= len(products)
+		return usage, er.ErrPVZOverCapacity
+	}
+
+	stmt, err := tx.PrepareNamedContext(ctx, insertProductQuery)
+	if err != nil {
+		return usage, errors.Wrap(err, "product repo: prepare product insert")
+	}
+	defer stmt.Close()
+
+	ids := make([]string, 0, len(products))
+	for _, p := range products {
+		p.ReceptionID = receptionID
+		_, err = stmt.ExecContext(ctx, p)
+		if err != nil {
+			logger.FromContext(ctx).Error("batch add product failed", slog.Any("err", err))
+			return usage, errors.Wrap(err, "product repo: batch add product")
+		}
+
+		err = recordChange(ctx, tx, models.AuditProductAdd, models.AuditEntityProduct, p.ID, nil)
+		if err != nil {
+			return usage, err
+		}
+		ids = append(ids, p.ID)
+	}
+
+	_, err = tx.ExecContext(ctx, CountDailyIntakeQuery, pq.Array(ids), usage.PVZID)
+	if err != nil {
+		logger.FromContext(ctx).Error("adjust daily intake failed", slog.Any("err", err))
+		return usage, errors.Wrap(err, "repo: adjust daily intake")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return usage, errors.Wrap(err, "product repo: commit product batch")
+	}
+
+	return usage, nil
+}
+
+func (r *ProductRepository) DeleteLast(ctx context.Context, pvzID string) (models.StorageUsage, error) {
+	var usage models.StorageUsage
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return usage, errors.Wrap(err, "product repo: begin tx")
+	}
+	defer tx.Rollback()
+
 	var receptionID string
-	queryReception := `SELECT id FROM receptions WHERE pvz_id = $1 AND status = 'in_progress' ORDER BY datetime DESC LIMIT 1`
-	err := r.db.GetContext(ctx, &receptionID, queryReception, pvzID)
+	err = tx.GetContext(ctx, &receptionID, LockOpenReceptionIDQuery, pvzID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return usage, er.ErrNoProducts
+		}
+		logger.FromContext(ctx).Error("no product found", slog.Any("err", err))
+		return usage, errors.Wrap(err, "get last product id")
+	}
+	var product models.Product
+	err = tx.GetContext(ctx, &product, LastProductQuery, receptionID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return er.ErrNoProducts
+			return usage, er.ErrNoProducts
 		}
-		slog.Error("no product found", slog.Any("err", err))
-		return errors.Wrap(err, "get last product id")
+		return usage, errors.Wrap(err, "get last product id")
+	}
+
+	before, err := snapshot(ctx, tx, models.AuditEntityProduct, product.ID)
+	if err != nil {
+		return usage, err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM products WHERE id = $1`, product.ID)
+	if err != nil {
+		logger.FromContext(ctx).Error("can't delete product", slog.Any("err", err))
+		return usage, errors.Wrap(err, "delete product")
+	}
+
+	err = adjustDailyIntake(ctx, tx, product.DateTime, pvzID, product.Type, -1)
+	if err != nil {
+		return usage, err
+	}
+
+	err = recordChange(ctx, tx, models.AuditProductDelete, models.AuditEntityProduct, product.ID, before)
+	if err != nil {
+		return usage, err
+	}
+
+	usage, err = decrementStoredItems(ctx, tx, pvzID)
+	if err != nil {
+		return usage, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return usage, errors.Wrap(err, "product repo: commit delete product")
+	}
+
+	return usage, nil
+}
+
+// Issue marks a product from a closed reception as handed over and frees its storage slot.
+func (r *ProductRepository) Issue(ctx context.Context, productID string, issuedAt time.Time) (models.StorageUsage, error) {
+	var usage models.StorageUsage
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return usage, errors.Wrap(err, "product repo: begin tx")
+	}
+	defer tx.Rollback()
+
+	var product struct {
+		IssuedAt *time.Time `db:"issued_at"`
+		Status   string     `db:"status"`
+		PVZID    string     `db:"pvz_id"`
 	}
-	var productID string
 	queryProduct := `
-		SELECT id FROM products
-		WHERE reception_id = $1
-		ORDER BY datetime DESC
-		LIMIT 1
+		SELECT p.issued_at, r.status, r.pvz_id
+		FROM products p
+		JOIN receptions r ON r.id = p.reception_id
+		WHERE p.id = $1
+		FOR UPDATE OF p
 	`
-	err = r.db.GetContext(ctx, &productID, queryProduct, receptionID)
+	err = tx.GetContext(ctx, &product, queryProduct, productID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return er.ErrNoProducts
+			return usage, er.ErrProductNotFound
 		}
-		return errors.Wrap(err, "get last product id")
+		logger.FromContext(ctx).Error("get product for issue failed", slog.Any("err", err))
+		return usage, errors.Wrap(err, "product repo: get product")
+	}
+
+	if product.IssuedAt != nil {
+		return usage, er.ErrProductAlreadyIssued
+	}
+	if product.Status != "close" {
+		return usage, er.ErrReceptionNotClosed
 	}
 
-	_, err = r.db.ExecContext(ctx, `DELETE FROM products WHERE id = $1`, productID)
+	before, err := snapshot(ctx, tx, models.AuditEntityProduct, productID)
 	if err != nil {
-		slog.Error("can't delete product", slog.Any("err", err))
-		return errors.Wrap(err, "delete product")
+		return usage, err
 	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE products SET issued_at = $2 WHERE id = $1`, productID, issuedAt)
+	if err != nil {
+		logger.FromContext(ctx).Error("issue product failed", slog.Any("err", err))
+		return usage, errors.Wrap(err, "product repo: issue product")
+	}
+
+	usage, err = decrementStoredItems(ctx, tx, product.PVZID)
+	if err != nil {
+		return usage, err
+	}
+
+	err = recordChange(ctx, tx, models.AuditProductIssue, models.AuditEntityProduct, productID, before)
+	if err != nil {
+		return usage, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return usage, errors.Wrap(err, "product repo: commit issue product")
+	}
+
+	return usage, nil
+}
+
+// lockOpenReception locks the reception products are added to, ErrNoOpenReception when it's
+// not in progress.
+func lockOpenReception(ctx context.Context, tx *sqlx.Tx, receptionID string) error {
+	var found int
+	err := tx.GetContext(ctx, &found, LockOpenReceptionQuery, receptionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return er.ErrNoOpenReception
+		}
+		logger.FromContext(ctx).Error("lock reception failed", slog.Any("err", err))
+		return errors.Wrap(err, "product repo: lock reception")
+	}
+
 	return nil
 }
+
+func decrementStoredItems(ctx context.Context, tx *sqlx.Tx, pvzID string) (models.StorageUsage, error) {
+	var usage models.StorageUsage
+	query := `UPDATE pvz SET stored_items = stored_items - 1 WHERE id = $1 RETURNING id, stored_items, capacity`
+	err := tx.GetContext(ctx, &usage, query, pvzID)
+	if err != nil {
+		logger.FromContext(ctx).Error("decrement stored items failed", slog.Any("err", err))
+		return usage, errors.Wrap(err, "product repo: decrement stored items")
+	}
+
+	return usage, nil
+}
@@ -2,50 +2,318 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"log/slog"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
 
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/logger"
 	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/pagination"
 )
 
+const insertPVZQuery = `
+	INSERT INTO pvz (id, city, registration_date, address, latitude, longitude, working_hours, capacity, external_code)
+	VALUES (:id, :city, :registration_date, :address, :latitude, :longitude, :working_hours, :capacity, :external_code)
+`
+
 type PVZRepository struct {
-	db *sqlx.DB
+	db      *sqlx.DB
+	replica *sqlx.DB
 }
 
-func NewPVZRepository(db *sqlx.DB) *PVZRepository {
-	return &PVZRepository{db: db}
+// NewPVZRepository writes to db and serves list and nearby reads from replica, which may lag
+// behind; pass db twice when there is no replica.
+func NewPVZRepository(db, replica *sqlx.DB) *PVZRepository {
+	return &PVZRepository{db: db, replica: replica}
 }
 
 func (r *PVZRepository) Create(ctx context.Context, pvz models.PVZ) error {
-	query := `INSERT INTO pvz (id, city, registration_date) VALUES (:id, :city, :registration_date)`
-	_, err := r.db.NamedExecContext(ctx, query, pvz)
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "repo: begin tx")
+	}
+	defer tx.Rollback()
+
+	_, err = tx.NamedExecContext(ctx, insertPVZQuery, pvz)
+	if isExternalCodeViolation(err) {
+		return er.ErrExternalCodeExists
+	}
 	if err != nil {
-		slog.Error("create pvz failed", slog.Any("err", err))
+		logger.FromContext(ctx).Error("create pvz failed", slog.Any("err", err))
 		return errors.Wrap(err, "repo: create pvz")
 	}
+
+	err = recordChange(ctx, tx, models.AuditPVZCreate, models.AuditEntityPVZ, pvz.ID, nil)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(err, "repo: commit create pvz")
+	}
+
 	return nil
 }
 
-func (r *PVZRepository) List(ctx context.Context, start, end *time.Time, page, limit int) ([]models.PVZ, error) {
-	offset := (page - 1) * limit
-	var pvzList []models.PVZ
+// CreateBatch inserts all PVZs in one transaction, either all of them are created or none.
+func (r *PVZRepository) CreateBatch(ctx context.Context, pvzs []models.PVZ) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "repo: begin tx")
+	}
+	defer tx.Rollback()
 
-	slog.Info("start", slog.Any("time", start))
-	slog.Info("end", slog.Any("time", end))
+	stmt, err := tx.PrepareNamedContext(ctx, insertPVZQuery)
+	if err != nil {
+		return errors.Wrap(err, "repo: prepare pvz insert")
+	}
+	defer stmt.Close()
+
+	for _, pvz := range pvzs {
+		_, err = stmt.ExecContext(ctx, pvz)
+		if isExternalCodeViolation(err) {
+			return er.ErrExternalCodeExists
+		}
+		if err != nil {
+			logger.FromContext(ctx).Error("batch create pvz failed", slog.Any("err", err))
+			return errors.Wrap(err, "repo: batch create pvz")
+		}
+
+		err = recordChange(ctx, tx, models.AuditPVZImport, models.AuditEntityPVZ, pvz.ID, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(err, "repo: commit pvz batch")
+	}
+
+	return nil
+}
+
+// ExistingExternalCodes returns those of codes that are already taken.
+func (r *PVZRepository) ExistingExternalCodes(ctx context.Context, codes []string) ([]string, error) {
+	var existing []string
+	query := `SELECT external_code FROM pvz WHERE external_code = ANY($1)`
+	err := r.db.SelectContext(ctx, &existing, query, pq.Array(codes))
+	if err != nil {
+		logger.FromContext(ctx).Error("select external codes failed", slog.Any("err", err))
+		return nil, errors.Wrap(err, "repo: select external codes")
+	}
+
+	return existing, nil
+}
 
-	pvzQuery := `
-		SELECT id, city, registration_date 
-		FROM pvz
-		WHERE ($1::timestamptz IS NULL OR registration_date >= $1) AND ($2::timestamptz IS NULL OR registration_date <= $2)
-		ORDER BY registration_date DESC
-		OFFSET $3 LIMIT $4;
+func isExternalCodeViolation(err error) bool {
+	return isUniqueViolation(err, "pvz_external_code_key")
+}
+
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
+
+func (r *PVZRepository) GetByID(ctx context.Context, id string) (models.PVZ, error) {
+	var pvz models.PVZ
+	query := `SELECT ` + PVZColumns + ` FROM pvz WHERE id = $1`
+	err := r.db.GetContext(ctx, &pvz, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return pvz, er.ErrNoPVZ
+		}
+		logger.FromContext(ctx).Error("get pvz failed", slog.Any("err", err))
+		return pvz, errors.Wrap(err, "repo: get pvz")
+	}
+
+	return pvz, nil
+}
+
+func (r *PVZRepository) Update(ctx context.Context, pvz models.PVZ) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "repo: begin tx")
+	}
+	defer tx.Rollback()
+
+	before, err := lockPVZ(ctx, tx, pvz.ID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE pvz
+		SET address = :address, latitude = :latitude, longitude = :longitude,
+			working_hours = :working_hours, capacity = :capacity
+		WHERE id = :id
 	`
-	err := r.db.SelectContext(ctx, &pvzList, pvzQuery, start, end, offset, limit)
+	_, err = tx.NamedExecContext(ctx, query, pvz)
+	if err != nil {
+		logger.FromContext(ctx).Error("update pvz failed", slog.Any("err", err))
+		return errors.Wrap(err, "repo: update pvz")
+	}
+
+	err = recordChange(ctx, tx, models.AuditPVZUpdate, models.AuditEntityPVZ, pvz.ID, before)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(err, "repo: commit update pvz")
+	}
+
+	return nil
+}
+
+// Archive sets archived_at of the PVZ unless it is archived already or has an open reception.
+// The PVZ row is locked for the checks, so a reception can't be opened in between.
+func (r *PVZRepository) Archive(ctx context.Context, id string, archivedAt time.Time) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "repo: begin tx")
+	}
+	defer tx.Rollback()
+
+	var archived bool
+	err = tx.GetContext(ctx, &archived, LockPVZQuery, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return er.ErrNoPVZ
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("lock pvz failed", slog.Any("err", err))
+		return errors.Wrap(err, "repo: lock pvz")
+	}
+	if archived {
+		return er.ErrPVZArchived
+	}
+
+	var hasOpen bool
+	err = tx.GetContext(ctx, &hasOpen, HasOpenReceptionQuery, id)
+	if err != nil {
+		return errors.Wrap(err, "repo: check open reception")
+	}
+	if hasOpen {
+		return er.ErrPVZHasOpenReception
+	}
+
+	before, err := snapshot(ctx, tx, models.AuditEntityPVZ, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE pvz SET archived_at = $2 WHERE id = $1`, id, archivedAt)
+	if err != nil {
+		logger.FromContext(ctx).Error("archive pvz failed", slog.Any("err", err))
+		return errors.Wrap(err, "repo: archive pvz")
+	}
+
+	err = recordChange(ctx, tx, models.AuditPVZArchive, models.AuditEntityPVZ, id, before)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(err, "repo: commit archive pvz")
+	}
+
+	return nil
+}
+
+func (r *PVZRepository) SetArchivedAt(ctx context.Context, id string, archivedAt *time.Time) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "repo: begin tx")
+	}
+	defer tx.Rollback()
+
+	before, err := lockPVZ(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE pvz SET archived_at = $2 WHERE id = $1`
+	_, err = tx.ExecContext(ctx, query, id, archivedAt)
+	if err != nil {
+		logger.FromContext(ctx).Error("set pvz archived_at failed", slog.Any("err", err))
+		return errors.Wrap(err, "repo: set pvz archived_at")
+	}
+
+	action := models.AuditPVZArchive
+	if archivedAt == nil {
+		action = models.AuditPVZRestore
+	}
+
+	err = recordChange(ctx, tx, action, models.AuditEntityPVZ, id, before)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(err, "repo: commit set pvz archived_at")
+	}
+
+	return nil
+}
+
+// lockPVZ locks the PVZ row for the rest of the transaction and returns its snapshot.
+func lockPVZ(ctx context.Context, tx *sqlx.Tx, id string) (json.RawMessage, error) {
+	var before []byte
+	err := tx.GetContext(ctx, &before, `SELECT to_jsonb(t) FROM pvz t WHERE t.id = $1 FOR UPDATE`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, er.ErrNoPVZ
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("lock pvz failed", slog.Any("err", err))
+		return nil, errors.Wrap(err, "repo: lock pvz")
+	}
+
+	return before, nil
+}
+
+// Nearby returns active PVZs within q.RadiusKm of the point ordered by great-circle distance.
+// A bounding box is applied first so pvz_location_idx can be used, then the exact
+// Haversine distance filters the corners out.
+func (r *PVZRepository) Nearby(ctx context.Context, q models.GeoQuery) ([]models.NearbyPVZ, error) {
+	minLat, maxLat, minLon, maxLon := BoundingBox(q.Latitude, q.Longitude, q.RadiusKm)
+
+	var res []models.NearbyPVZ
+	err := r.replica.SelectContext(ctx, &res, NearbyPVZQuery, q.Latitude, q.Longitude, EarthRadiusKm,
+		minLat, maxLat, minLon, maxLon, q.RadiusKm, q.Limit)
+	if err != nil {
+		logger.FromContext(ctx).Error("select nearby pvz failed", slog.Any("err", err))
+		return nil, errors.Wrap(err, "repo: nearby pvz")
+	}
+
+	return res, nil
+}
+
+// List returns PVZs in (registration_date DESC, id DESC) order starting right after the
+// given cursor (from the beginning when after is nil).
+func (r *PVZRepository) List(ctx context.Context, filter models.PVZFilter, after *pagination.Cursor, limit int) ([]models.PVZ, error) {
+	var pvzList []models.PVZ
+
+	var (
+		afterDate *time.Time
+		afterID   *string
+	)
+	if after != nil {
+		afterDate, afterID = &after.RegistrationDate, &after.ID
+	}
+
+	err := r.replica.SelectContext(ctx, &pvzList, ListPVZQuery, filter.StartDate, filter.EndDate, filter.IncludeArchived, afterDate, afterID, limit)
 	if err != nil {
-		slog.Error("select pvz failed", slog.Any("err", err))
+		logger.FromContext(ctx).Error("select pvz failed", slog.Any("err", err))
 		return nil, err
 	}
 
@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"trainee-pvz/internal/models"
+)
+
+// The queries below are shared with the pgx repositories (see pgxrepo), so both implementations
+// run the same SQL and can be benchmarked against each other. All of them use positional
+// parameters, which both drivers understand.
+
+const PVZColumns = `id, city, registration_date, archived_at, address, latitude, longitude, working_hours, capacity, stored_items, external_code`
+
+// OpenReceptionIDQuery returns the newest in-progress reception of the PVZ $1. It and the
+// queries built on it are served by receptions_pvz_open_idx.
+const OpenReceptionIDQuery = `
+	SELECT id FROM receptions
+	WHERE pvz_id = $1 AND status = 'in_progress'
+	ORDER BY datetime DESC
+	LIMIT 1
+`
+
+const HasOpenReceptionQuery = `SELECT EXISTS (` + OpenReceptionIDQuery + `)`
+
+// LockOpenReceptionIDQuery also locks the open reception, so concurrent deletions of products
+// of the PVZ take turns. The product has to be looked up by a separate statement then, which
+// sees the deletions committed while it waited.
+const LockOpenReceptionIDQuery = OpenReceptionIDQuery + `FOR UPDATE`
+
+// LockOpenReceptionQuery locks the reception $1 while it is in progress, so products are never
+// added to a reception that was closed concurrently: closing waits for the lock, and adding
+// after a committed close finds no row.
+const LockOpenReceptionQuery = `SELECT 1 FROM receptions WHERE id = $1 AND status = 'in_progress' FOR UPDATE`
+
+// CloseReceptionQuery closes the reception $1 unless it's already closed.
+const CloseReceptionQuery = `UPDATE receptions SET status = 'close', closed_at = now() WHERE id = $1 AND status = 'in_progress'`
+
+// LockPVZQuery locks the PVZ $1 and returns whether it is archived. Opening a reception and
+// archiving take it, so they go one at a time and the open reception check that follows sees
+// the reception or the archiving committed while it waited.
+const LockPVZQuery = `SELECT archived_at IS NOT NULL FROM pvz WHERE id = $1 FOR UPDATE`
+
+// LastProductQuery returns the newest product of the reception $1, using
+// products_reception_datetime_idx.
+const LastProductQuery = `
+	SELECT id, datetime, type FROM products
+	WHERE reception_id = $1
+	ORDER BY datetime DESC
+	LIMIT 1
+`
+
+// ListPVZQuery takes start date, end date, include archived, cursor date, cursor id and limit.
+const ListPVZQuery = `
+	SELECT ` + PVZColumns + `
+	FROM pvz
+	WHERE ($1::timestamptz IS NULL OR registration_date >= $1) AND ($2::timestamptz IS NULL OR registration_date <= $2)
+		AND ($3 OR archived_at IS NULL)
+		AND ($4::timestamptz IS NULL OR (registration_date, id) < ($4, $5::uuid))
+	ORDER BY registration_date DESC, id DESC
+	LIMIT $6
+`
+
+// NearbyPVZQuery takes the point, the Earth radius, the bounding box (min/max latitude, then
+// min/max longitude), the search radius and limit.
+const NearbyPVZQuery = `
+	SELECT * FROM (
+		SELECT ` + PVZColumns + `,
+			2 * $3::float8 * asin(sqrt(
+				power(sin(radians(latitude - $1) / 2), 2) +
+				cos(radians($1)) * cos(radians(latitude)) * power(sin(radians(longitude - $2) / 2), 2)
+			)) AS distance_km
+		FROM pvz
+		WHERE archived_at IS NULL AND latitude IS NOT NULL
+			AND latitude BETWEEN $4 AND $5 AND longitude BETWEEN $6 AND $7
+	) nearby
+	WHERE distance_km <= $8
+	ORDER BY distance_km
+	LIMIT $9
+`
+
+const ReceptionSummaryQuery = `
+	SELECT COUNT(*) AS receptions,
+		COALESCE(AVG(EXTRACT(EPOCH FROM r.closed_at - r.datetime)) FILTER (WHERE r.closed_at IS NOT NULL), 0)::float8 AS avg_duration_seconds,
+		COALESCE(AVG(pc.products), 0)::float8 AS avg_products
+	FROM receptions r
+	CROSS JOIN LATERAL (SELECT COUNT(*) AS products FROM products p WHERE p.reception_id = r.id) pc
+	WHERE r.datetime >= $1 AND r.datetime < $2
+`
+
+const OpenReceptionsByCityQuery = `
+	SELECT z.city, COUNT(*) AS receptions
+	FROM receptions r
+	JOIN pvz z ON z.id = r.pvz_id
+	WHERE r.status = 'in_progress'
+	GROUP BY z.city
+`
+
+// CapacityUtilisationByCityQuery returns the share of storage capacity taken per city, over
+// active PVZs with a capacity.
+const CapacityUtilisationByCityQuery = `
+	SELECT city, SUM(stored_items)::float8 / SUM(capacity) AS utilisation
+	FROM pvz
+	WHERE archived_at IS NULL AND capacity > 0
+	GROUP BY city
+`
+
+const ExportReceptionsQuery = `
+	SELECT r.id AS reception_id, r.datetime AS reception_datetime, r.status AS reception_status,
+		r.closed_at AS reception_closed_at, z.city, u.email AS employee_email,
+		p.id AS product_id, p.type AS product_type, p.datetime AS product_datetime, p.issued_at AS product_issued_at
+	FROM receptions r
+	JOIN pvz z ON z.id = r.pvz_id
+	LEFT JOIN users u ON u.id = r.created_by
+	LEFT JOIN products p ON p.reception_id = r.id
+	WHERE r.pvz_id = $1 AND r.datetime >= $2 AND r.datetime < $3
+	ORDER BY r.datetime, r.id, p.datetime, p.id
+`
+
+// AdjustDailyIntakeQuery takes the product datetime, PVZ id, product type and delta.
+const AdjustDailyIntakeQuery = `
+	INSERT INTO daily_intake (day, pvz_id, type, products)
+	VALUES (($1::timestamptz AT TIME ZONE 'UTC')::date, $2, $3, $4)
+	ON CONFLICT (day, pvz_id, type) DO UPDATE SET products = daily_intake.products + EXCLUDED.products
+`
+
+// CountDailyIntakeQuery adds the products with ids in $1 (as text[]) to the counters of PVZ $2.
+const CountDailyIntakeQuery = `
+	INSERT INTO daily_intake (day, pvz_id, type, products)
+	SELECT (p.datetime AT TIME ZONE 'UTC')::date, $2::uuid, p.type, COUNT(*)
+	FROM products p
+	WHERE p.id = ANY($1::text[]::uuid[])
+	GROUP BY 1, 3
+	ON CONFLICT (day, pvz_id, type) DO UPDATE SET products = daily_intake.products + EXCLUDED.products
+`
+
+// AddStoredItemsQuery adds $2 products to the PVZ of reception $1. Callers lock the reception
+// with LockOpenReceptionQuery first.
+const AddStoredItemsQuery = `
+	UPDATE pvz SET stored_items = pvz.stored_items + $2
+	FROM receptions r
+	WHERE r.id = $1 AND pvz.id = r.pvz_id
+	RETURNING pvz.id, pvz.stored_items, pvz.capacity
+`
+
+// dailyIntakeSource aggregates raw products the same way daily_intake is keyed.
+const dailyIntakeSource = `
+	SELECT (p.datetime AT TIME ZONE 'UTC')::date AS day, r.pvz_id, p.type, COUNT(*) AS products
+	FROM products p
+	JOIN receptions r ON r.id = p.reception_id
+	GROUP BY 1, 2, 3
+`
+
+const FillDailyIntakeQuery = `INSERT INTO daily_intake (day, pvz_id, type, products) ` + dailyIntakeSource
+
+// DailyIntakeMismatchesQuery compares daily_intake with the raw products, zero counters are
+// treated as missing rows.
+const DailyIntakeMismatchesQuery = `
+	SELECT COALESCE(d.day, s.day) AS day, COALESCE(d.pvz_id, s.pvz_id) AS pvz_id, COALESCE(d.type, s.type) AS type,
+		COALESCE(d.products, 0) AS stored, COALESCE(s.products, 0) AS actual
+	FROM (SELECT * FROM daily_intake WHERE products > 0) d
+	FULL JOIN (` + dailyIntakeSource + `) s ON s.day = d.day AND s.pvz_id = d.pvz_id AND s.type = d.type
+	WHERE COALESCE(d.products, 0) <> COALESCE(s.products, 0)
+	ORDER BY 1, 2, 3
+`
+
+// MigrationVersionQuery returns the newest applied goose migration. goose deletes the row of
+// a rolled back migration, so the maximum is the current version.
+const MigrationVersionQuery = `SELECT COALESCE(MAX(version_id), 0) FROM goose_db_version WHERE is_applied`
+
+// SnapshotQueries select an entity row by $1 as JSON with database column names,
+// secrets are stripped before they get into the log.
+var SnapshotQueries = map[string]string{
+	models.AuditEntityUser:      `SELECT to_jsonb(t) - 'password' FROM users t WHERE t.id = $1`,
+	models.AuditEntityPVZ:       `SELECT to_jsonb(t) FROM pvz t WHERE t.id = $1`,
+	models.AuditEntityReception: `SELECT to_jsonb(t) FROM receptions t WHERE t.id = $1`,
+	models.AuditEntityProduct:   `SELECT to_jsonb(t) FROM products t WHERE t.id = $1`,
+}
+
+// intakeDimensions maps report dimensions to the columns of the daily_intake/pvz join.
+// Only these expressions can get into the query, GroupBy values are never interpolated.
+var intakeDimensions = []struct {
+	name   string
+	alias  string
+	column string
+}{
+	{models.GroupByPVZ, "pvz_id", "d.pvz_id::text"},
+	{models.GroupByCity, "city", "z.city"},
+	{models.GroupByType, "type", "d.type"},
+}
+
+// IntakeRowsQuery builds the intake report query over daily_intake for the requested
+// dimensions. It takes the bucket, from and to.
+func IntakeRowsQuery(groupBy []string) string {
+	selects := []string{`date_trunc($1, d.day::timestamp) AT TIME ZONE 'UTC' AS bucket`}
+	groups := []string{"bucket"}
+
+	for _, dim := range intakeDimensions {
+		if !slices.Contains(groupBy, dim.name) {
+			selects = append(selects, "NULL::text AS "+dim.alias)
+			continue
+		}
+		selects = append(selects, dim.column+" AS "+dim.alias)
+		groups = append(groups, dim.column)
+	}
+
+	return `
+		SELECT ` + strings.Join(selects, ", ") + `, SUM(d.products)::int8 AS products
+		FROM daily_intake d
+		JOIN pvz z ON z.id = d.pvz_id
+		WHERE d.day >= ($2::timestamptz AT TIME ZONE 'UTC')::date AND d.day < ($3::timestamptz AT TIME ZONE 'UTC')::date
+			AND d.products > 0
+		GROUP BY ` + strings.Join(groups, ", ") + `
+		ORDER BY ` + strings.Join(groups, ", ")
+}
+
+// AuditListQuery builds the query of the entries matching the filter, newest first, with id
+// below afterID when it's set.
+func AuditListQuery(filter models.AuditFilter, afterID int64, limit int) (string, []any) {
+	var (
+		conds []string
+		args  []any
+	)
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, strings.ReplaceAll(cond, "?", "$"+strconv.Itoa(len(args))))
+	}
+
+	if filter.ActorID != "" {
+		add("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		add("action = ?", filter.Action)
+	}
+	if filter.EntityType != "" {
+		add("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		add("entity_id = ?", filter.EntityID)
+	}
+	if filter.From != nil {
+		add("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		add("created_at < ?", *filter.To)
+	}
+	if afterID > 0 {
+		add("id < ?", afterID)
+	}
+
+	query := `
+		SELECT id, created_at, actor_id, actor_role, action, entity_type, entity_id, before, after, request_id, ip
+		FROM audit_log`
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	args = append(args, limit)
+	query += ` ORDER BY id DESC LIMIT $` + strconv.Itoa(len(args))
+
+	return query, args
+}
+
+const (
+	EarthRadiusKm  = 6371.0
+	kmPerDegreeLat = 111.045
+)
+
+// BoundingBox returns a lat/lon rectangle that contains the circle of radiusKm around the point.
+// Near the poles or the antimeridian the longitude range is widened to the whole globe.
+func BoundingBox(lat, lon, radiusKm float64) (minLat, maxLat, minLon, maxLon float64) {
+	dLat := radiusKm / kmPerDegreeLat
+	minLat, maxLat = math.Max(lat-dLat, -90), math.Min(lat+dLat, 90)
+
+	cosLat := math.Cos(lat * math.Pi / 180)
+	if minLat == -90 || maxLat == 90 || cosLat < 1e-6 {
+		return minLat, maxLat, -180, 180
+	}
+
+	dLon := radiusKm / (kmPerDegreeLat * cosLat)
+	minLon, maxLon = lon-dLon, lon+dLon
+	if minLon < -180 || maxLon > 180 {
+		return minLat, maxLat, -180, 180
+	}
+
+	return minLat, maxLat, minLon, maxLon
+}
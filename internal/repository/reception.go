@@ -9,6 +9,7 @@ import (
 	"github.com/pkg/errors"
 
 	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/logger"
 	"trainee-pvz/internal/models"
 )
 
@@ -21,70 +22,125 @@ func NewReceptionRepository(db *sqlx.DB) *ReceptionRepository {
 }
 
 func (r *ReceptionRepository) HasOpenReception(ctx context.Context, pvzID string) (bool, error) {
-	var count int
-	query := `SELECT COUNT(*) FROM receptions WHERE pvz_id = $1 AND status = 'in_progress'`
-	err := r.db.GetContext(ctx, &count, query, pvzID)
-	return count > 0, err
+	var exists bool
+	err := r.db.GetContext(ctx, &exists, HasOpenReceptionQuery, pvzID)
+	return exists, err
 }
 
-func (r *ReceptionRepository) GetLastReceptionID(ctx context.Context, pvzID string) (string, error) {
-	var id string
-	query := `
-		SELECT id FROM receptions
-		WHERE pvz_id = $1 AND status = 'in_progress'
-		ORDER BY datetime DESC
-		LIMIT 1
-	`
-	err := r.db.GetContext(ctx, &id, query, pvzID)
+func (r *ReceptionRepository) IsPVZArchived(ctx context.Context, pvzID string) (bool, error) {
+	var archived bool
+	query := `SELECT archived_at IS NOT NULL FROM pvz WHERE id = $1`
+	err := r.db.GetContext(ctx, &archived, query, pvzID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return "", er.ErrNoOpenReception
+			return false, er.ErrNoPVZ
 		}
-		slog.Error("failed to get last reception", slog.Any("err", err))
-		return "", errors.Wrap(err, "reception repo: cget last reception")
+		logger.FromContext(ctx).Error("failed to get pvz archive state", slog.Any("err", err))
+		return false, errors.Wrap(err, "reception repo: get pvz archive state")
 	}
 
-	return id, nil
+	return archived, nil
 }
 
+// Create inserts the reception unless the PVZ is archived or already has an open one. The PVZ
+// row is locked first, so concurrent creates and archiving can't both pass the checks.
 func (r *ReceptionRepository) Create(ctx context.Context, rec models.Reception) error {
-	query := `INSERT INTO receptions (id, datetime, pvz_id, status) VALUES (:id, :datetime, :pvz_id, :status)`
-	_, err := r.db.NamedExecContext(ctx, query, rec)
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "reception repo: begin tx")
+	}
+	defer tx.Rollback()
+
+	var archived bool
+	err = tx.GetContext(ctx, &archived, LockPVZQuery, rec.PVZID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return er.ErrNoPVZ
+	}
 	if err != nil {
-		slog.Error("create reception failed", slog.Any("err", err))
+		logger.FromContext(ctx).Error("lock pvz failed", slog.Any("err", err))
+		return errors.Wrap(err, "reception repo: lock pvz")
+	}
+	if archived {
+		return er.ErrPVZArchived
+	}
+
+	var hasOpen bool
+	err = tx.GetContext(ctx, &hasOpen, HasOpenReceptionQuery, rec.PVZID)
+	if err != nil {
+		return errors.Wrap(err, "reception repo: check open reception")
+	}
+	if hasOpen {
+		return er.ErrReceptionAlreadyExists
+	}
+
+	query := `INSERT INTO receptions (id, datetime, pvz_id, status, created_by) VALUES (:id, :datetime, :pvz_id, :status, :created_by)`
+	_, err = tx.NamedExecContext(ctx, query, rec)
+	if err != nil {
+		logger.FromContext(ctx).Error("create reception failed", slog.Any("err", err))
 		return errors.Wrap(err, "reception repo: create reception")
 	}
 
+	err = recordChange(ctx, tx, models.AuditReceptionCreate, models.AuditEntityReception, rec.ID, nil)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(err, "reception repo: commit create reception")
+	}
+
 	return nil
 }
 
 func (r *ReceptionRepository) Close(ctx context.Context, id string) error {
-	query := `UPDATE receptions SET status = 'close' WHERE id = $1`
-	_, err := r.db.ExecContext(ctx, query, id)
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "reception repo: begin tx")
+	}
+	defer tx.Rollback()
+
+	before, err := snapshot(ctx, tx, models.AuditEntityReception, id)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, CloseReceptionQuery, id)
+	if err != nil {
+		logger.FromContext(ctx).Error("close reception failed", slog.Any("err", err))
+		return errors.Wrap(err, "reception repo: close reception")
+	}
+	closed, err := res.RowsAffected()
 	if err != nil {
-		slog.Error("close reception failed", slog.Any("err", err))
 		return errors.Wrap(err, "reception repo: close reception")
 	}
+	if closed == 0 {
+		return er.ErrNoOpenReception
+	}
+
+	err = recordChange(ctx, tx, models.AuditReceptionClose, models.AuditEntityReception, id, before)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(err, "reception repo: commit close reception")
+	}
 
 	return nil
 }
 
+// GetOpenReceptionID returns the newest in-progress reception of the PVZ.
 func (r *ReceptionRepository) GetOpenReceptionID(ctx context.Context, pvzID string) (string, error) {
 	var id string
-	query := `
-		SELECT id FROM receptions
-		WHERE pvz_id = $1 AND status = 'in_progress'
-		ORDER BY datetime DESC
-		LIMIT 1
-	`
-
-	err := r.db.GetContext(ctx, &id, query, pvzID)
+	err := r.db.GetContext(ctx, &id, OpenReceptionIDQuery, pvzID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return "", er.ErrNoOpenReception
 		}
-		slog.Error("get open reception failed", slog.Any("err", err))
-		return "", errors.Wrap(err, "get open reception failed")
+		logger.FromContext(ctx).Error("get open reception failed", slog.Any("err", err))
+		return "", errors.Wrap(err, "reception repo: get open reception")
 	}
 
 	return id, nil
@@ -0,0 +1,45 @@
+package repository_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"trainee-pvz/config"
+	"trainee-pvz/internal/database"
+	"trainee-pvz/internal/repository"
+	"trainee-pvz/internal/repository/pgtest"
+	"trainee-pvz/internal/repository/repotest"
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	pgtest.Stop()
+	os.Exit(code)
+}
+
+// TestContract runs the repository contract on the sqlx repositories, every case on a freshly
+// migrated schema.
+func TestContract(t *testing.T) {
+	ctx := context.Background()
+	conn := pgtest.Connection(ctx, t)
+
+	repotest.Run(t, func(t *testing.T) repotest.Repositories {
+		db, err := database.ConnectDB(ctx, pgtest.Schema(ctx, t, conn), config.Default().DB)
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+
+		return repotest.Repositories{
+			User:      repository.NewUserRepository(db),
+			Reception: repository.NewReceptionRepository(db),
+			Product:   repository.NewProductRepository(db),
+			PVZ:       repository.NewPVZRepository(db, db),
+			Stats:     repository.NewStatsRepository(db, db),
+			Export:    repository.NewExportRepository(db),
+			Audit:     repository.NewAuditRepository(db),
+			Health:    repository.NewHealthRepository(db),
+		}
+	})
+}
@@ -0,0 +1,790 @@
+// Package repotest is the contract every storage backend of the service repositories has to
+// satisfy. Backends run it from their own tests:
+//
+//	repotest.Run(t, func(t *testing.T) repotest.Repositories { ... })
+//
+// The factory is called for every case and has to return repositories over empty storage.
+package repotest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"trainee-pvz/internal/audit"
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/pagination"
+	"trainee-pvz/internal/service"
+)
+
+// Repositories are the backend under test.
+type Repositories struct {
+	User      service.UserRepository
+	Reception service.ReceptionRepository
+	Product   service.ProductRepository
+	PVZ       service.PVZRepository
+	Stats     service.StatsRepository
+	Export    service.ExportRepository
+	Audit     service.AuditRepository
+	Health    service.HealthRepository
+}
+
+// Run runs every case of the contract as a subtest of t.
+func Run(t *testing.T, newRepos func(t *testing.T) Repositories) {
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, r Repositories)
+	}{
+		{"Users", testUsers},
+		{"PVZCreateAndUpdate", testPVZCreateAndUpdate},
+		{"PVZBatch", testPVZBatch},
+		{"PVZListAndArchive", testPVZListAndArchive},
+		{"Nearby", testNearby},
+		{"SingleOpenReception", testSingleOpenReception},
+		{"Archive", testArchive},
+		{"ProductsLIFO", testProductsLIFO},
+		{"ClosedReception", testClosedReception},
+		{"Capacity", testCapacity},
+		{"ProductBatch", testProductBatch},
+		{"Issue", testIssue},
+		{"Stats", testStats},
+		{"Export", testExport},
+		{"Audit", testAudit},
+		{"Health", testHealth},
+		{"ConcurrentProducts", testConcurrentProducts},
+		{"ConcurrentReceptions", testConcurrentReceptions},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.fn(t, newRepos(t))
+		})
+	}
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) SaveEntityCount(float64, string) {}
+
+// day is a fixed UTC day most of the data is placed on.
+var day = time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+
+func newPVZ(city string) models.PVZ {
+	return models.PVZ{ID: uuid.NewString(), RegistrationDate: day, City: city}
+}
+
+func createPVZ(t *testing.T, r Repositories, pvz models.PVZ) models.PVZ {
+	t.Helper()
+	require.NoError(t, r.PVZ.Create(context.Background(), pvz))
+	return pvz
+}
+
+func openReception(t *testing.T, r Repositories, pvzID string, at time.Time) models.Reception {
+	t.Helper()
+	rec := models.Reception{ID: uuid.NewString(), DateTime: at, PVZID: pvzID, Status: "in_progress"}
+	require.NoError(t, r.Reception.Create(context.Background(), rec))
+	return rec
+}
+
+func addProduct(t *testing.T, r Repositories, receptionID, typ string, at time.Time) (models.Product, models.StorageUsage) {
+	t.Helper()
+	p := models.Product{ID: uuid.NewString(), DateTime: at, Type: typ, ReceptionID: receptionID}
+	usage, err := r.Product.Add(context.Background(), p, true)
+	require.NoError(t, err)
+	return p, usage
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func testUsers(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	user := models.User{ID: uuid.NewString(), Email: "employee@example.com", Password: "hash", Role: "employee"}
+	require.NoError(t, r.User.Create(ctx, user))
+
+	got, err := r.User.GetByEmail(ctx, user.Email)
+	require.NoError(t, err)
+	assert.Equal(t, user, got)
+
+	err = r.User.Create(ctx, models.User{ID: uuid.NewString(), Email: user.Email, Password: "other", Role: "moderator"})
+	assert.ErrorIs(t, err, er.ErrUserAlreadyExists)
+
+	_, err = r.User.GetByEmail(ctx, "missing@example.com")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func testPVZCreateAndUpdate(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	pvz := newPVZ("Москва")
+	pvz.Address = "Тверская, 1"
+	pvz.Latitude, pvz.Longitude = ptr(55.7558), ptr(37.6173)
+	pvz.WorkingHours = models.WorkingHours{"mon": {Open: "09:00", Close: "21:00"}}
+	pvz.Capacity = ptr(10)
+	pvz.ExternalCode = ptr("EXT-1")
+	createPVZ(t, r, pvz)
+
+	got, err := r.PVZ.GetByID(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.True(t, pvz.RegistrationDate.Equal(got.RegistrationDate))
+	got.RegistrationDate = pvz.RegistrationDate
+	assert.Equal(t, pvz, got)
+
+	_, err = r.PVZ.GetByID(ctx, uuid.NewString())
+	assert.ErrorIs(t, err, er.ErrNoPVZ)
+
+	assert.Error(t, r.PVZ.Create(ctx, newPVZ("Новосибирск")), "unsupported city")
+
+	dup := newPVZ("Казань")
+	dup.ExternalCode = ptr("EXT-1")
+	assert.ErrorIs(t, r.PVZ.Create(ctx, dup), er.ErrExternalCodeExists)
+
+	pvz.Address = "Тверская, 2"
+	pvz.Capacity = ptr(20)
+	pvz.WorkingHours = nil
+	require.NoError(t, r.PVZ.Update(ctx, pvz))
+
+	got, err = r.PVZ.GetByID(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Тверская, 2", got.Address)
+	assert.Equal(t, ptr(20), got.Capacity)
+	assert.Nil(t, got.WorkingHours)
+}
+
+func testPVZBatch(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	taken := newPVZ("Москва")
+	taken.ExternalCode = ptr("B")
+	createPVZ(t, r, taken)
+
+	a, b := newPVZ("Москва"), newPVZ("Казань")
+	a.ExternalCode, b.ExternalCode = ptr("A"), ptr("B")
+	assert.ErrorIs(t, r.PVZ.CreateBatch(ctx, []models.PVZ{a, b}), er.ErrExternalCodeExists)
+
+	_, err := r.PVZ.GetByID(ctx, a.ID)
+	assert.ErrorIs(t, err, er.ErrNoPVZ, "batch is all or nothing")
+
+	existing, err := r.PVZ.ExistingExternalCodes(ctx, []string{"A", "B", "C"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"B"}, existing)
+
+	c := newPVZ("Санкт-Петербург")
+	c.ExternalCode = ptr("C")
+	require.NoError(t, r.PVZ.CreateBatch(ctx, []models.PVZ{a, c}))
+
+	existing, err = r.PVZ.ExistingExternalCodes(ctx, []string{"A", "B", "C", "D"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"A", "B", "C"}, existing)
+}
+
+func testPVZListAndArchive(t *testing.T, r Repositories) {
+	ctx := context.Background()
+
+	var pvzs []models.PVZ
+	for i := range 5 {
+		pvz := newPVZ("Москва")
+		pvz.RegistrationDate = day.Add(-time.Duration(i) * time.Hour)
+		pvzs = append(pvzs, createPVZ(t, r, pvz))
+	}
+	archivedAt := day.Add(time.Hour)
+	require.NoError(t, r.PVZ.SetArchivedAt(ctx, pvzs[2].ID, &archivedAt))
+
+	ids := func(list []models.PVZ) []string {
+		var res []string
+		for _, pvz := range list {
+			res = append(res, pvz.ID)
+		}
+		return res
+	}
+
+	list, err := r.PVZ.List(ctx, models.PVZFilter{}, nil, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{pvzs[0].ID, pvzs[1].ID, pvzs[3].ID, pvzs[4].ID}, ids(list))
+
+	list, err = r.PVZ.List(ctx, models.PVZFilter{IncludeArchived: true}, nil, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{pvzs[0].ID, pvzs[1].ID}, ids(list))
+
+	after := &pagination.Cursor{RegistrationDate: list[1].RegistrationDate, ID: list[1].ID}
+	list, err = r.PVZ.List(ctx, models.PVZFilter{IncludeArchived: true}, after, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{pvzs[2].ID, pvzs[3].ID}, ids(list))
+
+	from, to := day.Add(-time.Hour), day
+	list, err = r.PVZ.List(ctx, models.PVZFilter{StartDate: &from, EndDate: &to}, nil, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{pvzs[0].ID, pvzs[1].ID}, ids(list), "the period bounds are inclusive")
+
+	archived, err := r.Reception.IsPVZArchived(ctx, pvzs[2].ID)
+	require.NoError(t, err)
+	assert.True(t, archived)
+
+	require.NoError(t, r.PVZ.SetArchivedAt(ctx, pvzs[2].ID, nil))
+	archived, err = r.Reception.IsPVZArchived(ctx, pvzs[2].ID)
+	require.NoError(t, err)
+	assert.False(t, archived)
+
+	_, err = r.Reception.IsPVZArchived(ctx, uuid.NewString())
+	assert.ErrorIs(t, err, er.ErrNoPVZ)
+	assert.ErrorIs(t, r.PVZ.SetArchivedAt(ctx, uuid.NewString(), nil), er.ErrNoPVZ)
+}
+
+func testNearby(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	at := func(lat, lon float64) models.PVZ {
+		pvz := newPVZ("Москва")
+		pvz.Latitude, pvz.Longitude = &lat, &lon
+		return createPVZ(t, r, pvz)
+	}
+
+	center := at(55.75, 37.62)
+	near := at(55.795, 37.62) // ~5 km north
+	at(56.2, 37.62)           // ~50 km north
+	archived := at(55.75, 37.62)
+	archivedAt := day
+	require.NoError(t, r.PVZ.SetArchivedAt(ctx, archived.ID, &archivedAt))
+	createPVZ(t, r, newPVZ("Москва")) // without coordinates
+
+	res, err := r.PVZ.Nearby(ctx, models.GeoQuery{Latitude: 55.75, Longitude: 37.62, RadiusKm: 10, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Equal(t, center.ID, res[0].ID)
+	assert.InDelta(t, 0, res[0].DistanceKm, 0.01)
+	assert.Equal(t, near.ID, res[1].ID)
+	assert.InDelta(t, 5, res[1].DistanceKm, 0.1)
+
+	res, err = r.PVZ.Nearby(ctx, models.GeoQuery{Latitude: 55.75, Longitude: 37.62, RadiusKm: 100, Limit: 1})
+	require.NoError(t, err)
+	assert.Len(t, res, 1)
+}
+
+func testSingleOpenReception(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	svc := service.NewReceptionService(r.Reception, nopMetrics{})
+	pvz := createPVZ(t, r, newPVZ("Казань"))
+
+	newReception := func(at time.Time) models.Reception {
+		return models.Reception{ID: uuid.NewString(), DateTime: at, PVZID: pvz.ID, Status: "in_progress"}
+	}
+
+	first := newReception(day)
+	require.NoError(t, svc.CreateReception(ctx, first))
+	assert.ErrorIs(t, svc.CreateReception(ctx, newReception(day.Add(time.Hour))), er.ErrReceptionAlreadyExists)
+
+	open, err := r.Reception.HasOpenReception(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.True(t, open)
+	id, err := svc.GetOpenReceptionID(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, id)
+
+	require.NoError(t, svc.CloseReception(ctx, first.ID))
+	open, err = r.Reception.HasOpenReception(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.False(t, open)
+	_, err = svc.GetOpenReceptionID(ctx, pvz.ID)
+	assert.ErrorIs(t, err, er.ErrNoOpenReception)
+
+	second := newReception(day.Add(2 * time.Hour))
+	require.NoError(t, svc.CreateReception(ctx, second))
+	id, err = svc.GetOpenReceptionID(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, id)
+
+	archived := createPVZ(t, r, newPVZ("Казань"))
+	archivedAt := day
+	require.NoError(t, r.PVZ.SetArchivedAt(ctx, archived.ID, &archivedAt))
+	rec := newReception(day)
+	rec.PVZID = archived.ID
+	assert.ErrorIs(t, svc.CreateReception(ctx, rec), er.ErrPVZArchived)
+
+	rec.PVZID = uuid.NewString()
+	assert.ErrorIs(t, svc.CreateReception(ctx, rec), er.ErrNoPVZ)
+}
+
+func testArchive(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	pvz := createPVZ(t, r, newPVZ("Москва"))
+	rec := openReception(t, r, pvz.ID, day)
+
+	assert.ErrorIs(t, r.PVZ.Archive(ctx, pvz.ID, day), er.ErrPVZHasOpenReception)
+	got, err := r.PVZ.GetByID(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got.ArchivedAt)
+
+	require.NoError(t, r.Reception.Close(ctx, rec.ID))
+	require.NoError(t, r.PVZ.Archive(ctx, pvz.ID, day))
+	got, err = r.PVZ.GetByID(ctx, pvz.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got.ArchivedAt)
+	assert.True(t, day.Equal(*got.ArchivedAt))
+
+	assert.ErrorIs(t, r.PVZ.Archive(ctx, pvz.ID, day), er.ErrPVZArchived)
+	assert.ErrorIs(t, r.PVZ.Archive(ctx, uuid.NewString(), day), er.ErrNoPVZ)
+
+	// the repository rechecks under the lock, whatever the caller saw before
+	next := models.Reception{ID: uuid.NewString(), DateTime: day.Add(time.Hour), PVZID: pvz.ID, Status: "in_progress"}
+	assert.ErrorIs(t, r.Reception.Create(ctx, next), er.ErrPVZArchived)
+}
+
+func testProductsLIFO(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	pvz := createPVZ(t, r, newPVZ("Москва"))
+	rec := openReception(t, r, pvz.ID, day)
+
+	// the newest product is the one with the latest datetime, not the last one added
+	p1, _ := addProduct(t, r, rec.ID, "одежда", day.Add(time.Minute))
+	p3, _ := addProduct(t, r, rec.ID, "обувь", day.Add(3*time.Minute))
+	p2, usage := addProduct(t, r, rec.ID, "электроника", day.Add(2*time.Minute))
+	assert.Equal(t, 3, usage.StoredItems)
+	assert.Equal(t, pvz.ID, usage.PVZID)
+
+	for i, want := range []models.Product{p3, p2, p1} {
+		usage, err := r.Product.DeleteLast(ctx, pvz.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 2-i, usage.StoredItems)
+
+		entries, err := r.Audit.List(ctx, models.AuditFilter{Action: models.AuditProductDelete}, 0, 1)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, want.ID, entries[0].EntityID, "deletion %d", i)
+	}
+
+	_, err := r.Product.DeleteLast(ctx, pvz.ID)
+	assert.ErrorIs(t, err, er.ErrNoProducts)
+
+	addProduct(t, r, rec.ID, "обувь", day)
+	require.NoError(t, r.Reception.Close(ctx, rec.ID))
+	_, err = r.Product.DeleteLast(ctx, pvz.ID)
+	assert.ErrorIs(t, err, er.ErrNoProducts, "products of closed receptions are kept")
+
+	_, err = r.Product.Add(ctx, models.Product{ID: uuid.NewString(), DateTime: day, Type: "обувь", ReceptionID: uuid.NewString()}, true)
+	assert.ErrorIs(t, err, er.ErrNoOpenReception)
+}
+
+func testClosedReception(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	pvz := createPVZ(t, r, newPVZ("Москва"))
+	rec := openReception(t, r, pvz.ID, day)
+	addProduct(t, r, rec.ID, "обувь", day)
+	require.NoError(t, r.Reception.Close(ctx, rec.ID))
+
+	assert.ErrorIs(t, r.Reception.Close(ctx, rec.ID), er.ErrNoOpenReception)
+
+	// a handler may have looked the reception up before it was closed
+	late := models.Product{ID: uuid.NewString(), DateTime: day.Add(time.Minute), Type: "обувь", ReceptionID: rec.ID}
+	_, err := r.Product.Add(ctx, late, false)
+	assert.ErrorIs(t, err, er.ErrNoOpenReception)
+	_, err = r.Product.AddBatch(ctx, rec.ID, []models.Product{late}, false)
+	assert.ErrorIs(t, err, er.ErrNoOpenReception)
+
+	got, err := r.PVZ.GetByID(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.StoredItems)
+
+	entries, err := r.Audit.List(ctx, models.AuditFilter{Action: models.AuditProductAdd}, 0, 10)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	mismatches, err := r.Stats.DailyIntakeMismatches(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
+
+func testCapacity(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	pvz := newPVZ("Москва")
+	pvz.Capacity = ptr(2)
+	createPVZ(t, r, pvz)
+	rec := openReception(t, r, pvz.ID, day)
+
+	addProduct(t, r, rec.ID, "обувь", day)
+	_, usage := addProduct(t, r, rec.ID, "обувь", day.Add(time.Second))
+	assert.Equal(t, 2, usage.StoredItems)
+	assert.False(t, usage.OverCapacity())
+
+	extra := models.Product{ID: uuid.NewString(), DateTime: day.Add(2 * time.Second), Type: "обувь", ReceptionID: rec.ID}
+	usage, err := r.Product.Add(ctx, extra, true)
+	assert.ErrorIs(t, err, er.ErrPVZOverCapacity)
+	assert.Equal(t, 2, usage.StoredItems)
+
+	usage, err = r.Product.Add(ctx, extra, false)
+	require.NoError(t, err)
+	assert.Equal(t, 3, usage.StoredItems)
+	assert.True(t, usage.OverCapacity())
+
+	got, err := r.PVZ.GetByID(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, got.StoredItems)
+
+	other := newPVZ("Москва")
+	other.Capacity = ptr(4)
+	createPVZ(t, r, other)
+	createPVZ(t, r, newPVZ("Казань")) // no capacity, not counted
+	utilisation, err := r.Stats.CapacityUtilisationByCity(ctx)
+	require.NoError(t, err)
+	require.Len(t, utilisation, 1)
+	assert.InDelta(t, 0.5, utilisation["Москва"], 1e-9, "3 stored of 2+4")
+}
+
+func testProductBatch(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	pvz := newPVZ("Москва")
+	pvz.Capacity = ptr(4)
+	createPVZ(t, r, pvz)
+	rec := openReception(t, r, pvz.ID, day)
+	addProduct(t, r, rec.ID, "обувь", day)
+
+	batch := []models.Product{
+		{ID: uuid.NewString(), DateTime: day.Add(time.Minute), Type: "обувь"},
+		{ID: uuid.NewString(), DateTime: day.Add(2 * time.Minute), Type: "одежда"},
+		{ID: uuid.NewString(), DateTime: day.Add(24 * time.Hour), Type: "обувь"}, // next day
+	}
+	usage, err := r.Product.AddBatch(ctx, rec.ID, batch, true)
+	require.NoError(t, err)
+	assert.Equal(t, pvz.ID, usage.PVZID)
+	assert.Equal(t, 4, usage.StoredItems)
+
+	over := []models.Product{
+		{ID: uuid.NewString(), DateTime: day, Type: "обувь"},
+		{ID: uuid.NewString(), DateTime: day, Type: "обувь"},
+	}
+	usage, err = r.Product.AddBatch(ctx, rec.ID, over, true)
+	assert.ErrorIs(t, err, er.ErrPVZOverCapacity)
+	assert.Equal(t, 4, usage.StoredItems)
+
+	_, err = r.Product.AddBatch(ctx, uuid.NewString(), over, true)
+	assert.ErrorIs(t, err, er.ErrNoOpenReception)
+
+	got, err := r.PVZ.GetByID(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 4, got.StoredItems, "rejected batches change nothing")
+
+	// the batch counts into daily intake and the audit like single products
+	rows, err := r.Stats.IntakeRows(ctx, models.IntakeQuery{
+		From: day, To: day.AddDate(0, 0, 2), Bucket: models.BucketDay, GroupBy: []string{models.GroupByType},
+	})
+	require.NoError(t, err)
+	counts := map[string]int{}
+	for _, row := range rows {
+		counts[row.Bucket.UTC().Format(time.DateOnly)+" "+*row.ProductType] = row.Products
+	}
+	assert.Equal(t, map[string]int{"2025-03-10 обувь": 2, "2025-03-10 одежда": 1, "2025-03-11 обувь": 1}, counts)
+
+	mismatches, err := r.Stats.DailyIntakeMismatches(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, mismatches)
+
+	entries, err := r.Audit.List(ctx, models.AuditFilter{Action: models.AuditProductAdd}, 0, 10)
+	require.NoError(t, err)
+	assert.Len(t, entries, 4)
+
+	// products of the batch are deleted newest first like any others
+	_, err = r.Product.DeleteLast(ctx, pvz.ID)
+	require.NoError(t, err)
+	deleted, err := r.Audit.List(ctx, models.AuditFilter{Action: models.AuditProductDelete}, 0, 1)
+	require.NoError(t, err)
+	require.Len(t, deleted, 1)
+	assert.Equal(t, batch[2].ID, deleted[0].EntityID)
+}
+
+func testIssue(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	pvz := createPVZ(t, r, newPVZ("Москва"))
+	rec := openReception(t, r, pvz.ID, day)
+	p, _ := addProduct(t, r, rec.ID, "электроника", day)
+
+	_, err := r.Product.Issue(ctx, p.ID, day.Add(time.Hour))
+	assert.ErrorIs(t, err, er.ErrReceptionNotClosed)
+
+	require.NoError(t, r.Reception.Close(ctx, rec.ID))
+	usage, err := r.Product.Issue(ctx, p.ID, day.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, usage.StoredItems)
+
+	_, err = r.Product.Issue(ctx, p.ID, day.Add(2*time.Hour))
+	assert.ErrorIs(t, err, er.ErrProductAlreadyIssued)
+
+	_, err = r.Product.Issue(ctx, uuid.NewString(), day)
+	assert.ErrorIs(t, err, er.ErrProductNotFound)
+}
+
+func testStats(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	moscow := createPVZ(t, r, newPVZ("Москва"))
+	kazan := createPVZ(t, r, newPVZ("Казань"))
+
+	recMoscow := openReception(t, r, moscow.ID, day.Add(10*time.Hour))
+	addProduct(t, r, recMoscow.ID, "электроника", day.Add(10*time.Hour+time.Minute))
+	addProduct(t, r, recMoscow.ID, "электроника", day.Add(10*time.Hour+2*time.Minute))
+	addProduct(t, r, recMoscow.ID, "одежда", day.Add(10*time.Hour+3*time.Minute))
+	require.NoError(t, r.Reception.Close(ctx, recMoscow.ID))
+
+	recKazan := openReception(t, r, kazan.ID, day.Add(11*time.Hour))
+	addProduct(t, r, recKazan.ID, "обувь", day.Add(11*time.Hour+time.Minute))
+	addProduct(t, r, recKazan.ID, "обувь", day.Add(24*time.Hour)) // next day
+	_, err := r.Product.DeleteLast(ctx, kazan.ID)
+	require.NoError(t, err)
+
+	rows, err := r.Stats.IntakeRows(ctx, models.IntakeQuery{
+		From: day, To: day.AddDate(0, 0, 1), Bucket: models.BucketDay, GroupBy: []string{models.GroupByCity, models.GroupByType},
+	})
+	require.NoError(t, err)
+	type cityType struct {
+		city, typ string
+		products  int
+	}
+	var got []cityType
+	for _, row := range rows {
+		assert.True(t, day.Equal(row.Bucket), "bucket %s", row.Bucket)
+		assert.Nil(t, row.PVZID)
+		require.NotNil(t, row.City)
+		require.NotNil(t, row.ProductType)
+		got = append(got, cityType{*row.City, *row.ProductType, row.Products})
+	}
+	assert.ElementsMatch(t, []cityType{{"Москва", "электроника", 2}, {"Москва", "одежда", 1}, {"Казань", "обувь", 1}}, got)
+
+	rows, err = r.Stats.IntakeRows(ctx, models.IntakeQuery{From: day.AddDate(0, 0, -9), To: day.AddDate(0, 0, 22), Bucket: models.BucketMonth})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.True(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC).Equal(rows[0].Bucket))
+	assert.Equal(t, 4, rows[0].Products)
+
+	summary, err := r.Stats.ReceptionSummary(ctx, models.IntakeQuery{From: day, To: day.AddDate(0, 0, 1)})
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.Receptions)
+	assert.InDelta(t, 2.0, summary.AvgProductsPerReception, 1e-9)
+	assert.Positive(t, summary.AvgDurationSeconds)
+
+	open, err := r.Stats.OpenReceptionsByCity(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"Казань": 1}, open)
+
+	mismatches, err := r.Stats.DailyIntakeMismatches(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, mismatches)
+
+	rebuilt, err := r.Stats.RebuildDailyIntake(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, rebuilt)
+}
+
+func testExport(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	employee := models.User{ID: uuid.NewString(), Email: "employee@example.com", Password: "hash", Role: "employee"}
+	require.NoError(t, r.User.Create(ctx, employee))
+	pvz := createPVZ(t, r, newPVZ("Санкт-Петербург"))
+
+	first := models.Reception{ID: uuid.NewString(), DateTime: day, PVZID: pvz.ID, Status: "in_progress", CreatedBy: &employee.ID}
+	require.NoError(t, r.Reception.Create(ctx, first))
+	p2, _ := addProduct(t, r, first.ID, "обувь", day.Add(2*time.Minute))
+	p1, _ := addProduct(t, r, first.ID, "одежда", day.Add(time.Minute))
+	require.NoError(t, r.Reception.Close(ctx, first.ID))
+	second := openReception(t, r, pvz.ID, day.Add(time.Hour))
+	openReception(t, r, createPVZ(t, r, newPVZ("Москва")).ID, day) // another PVZ
+
+	var rows []models.ExportRow
+	q := models.ExportQuery{PVZID: pvz.ID, From: day, To: day.Add(2 * time.Hour), Format: models.ExportCSV}
+	err := r.Export.StreamReceptions(ctx, q, func(row models.ExportRow) error {
+		rows = append(rows, row)
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.Len(t, rows, 3)
+	for i, want := range []struct {
+		receptionID string
+		productID   *string
+	}{{first.ID, &p1.ID}, {first.ID, &p2.ID}, {second.ID, nil}} {
+		assert.Equal(t, want.receptionID, rows[i].ReceptionID, "row %d", i)
+		assert.Equal(t, want.productID, rows[i].ProductID, "row %d", i)
+		assert.Equal(t, "Санкт-Петербург", rows[i].City)
+	}
+	assert.Equal(t, "close", rows[0].ReceptionStatus)
+	assert.NotNil(t, rows[0].ReceptionClosedAt)
+	assert.Equal(t, ptr(employee.Email), rows[0].EmployeeEmail)
+	assert.Equal(t, ptr("одежда"), rows[0].ProductType)
+	assert.Nil(t, rows[2].EmployeeEmail)
+	assert.Nil(t, rows[2].ProductType)
+
+	stop := errors.New("stop")
+	calls := 0
+	err = r.Export.StreamReceptions(ctx, q, func(models.ExportRow) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
+
+func testAudit(t *testing.T, r Repositories) {
+	moderator := models.User{ID: uuid.NewString(), Email: "moderator@example.com", Password: "secret", Role: "moderator"}
+	require.NoError(t, r.User.Create(context.Background(), moderator))
+
+	ctx := audit.WithActor(context.Background(), audit.Actor{
+		UserID: moderator.ID, Role: "moderator", RequestID: "req-1", IP: "10.0.0.1",
+	})
+	pvz := newPVZ("Москва")
+	pvz.Address = "old"
+	require.NoError(t, r.PVZ.Create(ctx, pvz))
+	pvz.Address = "new"
+	require.NoError(t, r.PVZ.Update(ctx, pvz))
+	require.NoError(t, r.PVZ.Archive(ctx, pvz.ID, day))
+
+	entries, err := r.Audit.List(ctx, models.AuditFilter{EntityType: models.AuditEntityPVZ, EntityID: pvz.ID}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, models.AuditPVZArchive, entries[0].Action)
+	assert.Equal(t, models.AuditPVZUpdate, entries[1].Action)
+	assert.Equal(t, models.AuditPVZCreate, entries[2].Action)
+
+	create, update := entries[2], entries[1]
+	assert.Empty(t, create.Before)
+	assert.Equal(t, ptr(moderator.ID), create.ActorID)
+	assert.Equal(t, ptr("moderator"), create.ActorRole)
+	assert.Equal(t, ptr("req-1"), create.RequestID)
+	assert.Equal(t, ptr("10.0.0.1"), create.IP)
+
+	column := func(raw json.RawMessage, name string) any {
+		var row map[string]any
+		require.NoError(t, json.Unmarshal(raw, &row))
+		return row[name]
+	}
+	assert.Equal(t, "old", column(create.After, "address"))
+	assert.Equal(t, "old", column(update.Before, "address"))
+	assert.Equal(t, "new", column(update.After, "address"))
+
+	page, err := r.Audit.List(ctx, models.AuditFilter{EntityID: pvz.ID}, entries[1].ID, 10)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, entries[2].ID, page[0].ID)
+
+	registered, err := r.Audit.List(ctx, models.AuditFilter{Action: models.AuditUserRegister}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, registered, 1)
+	assert.Nil(t, registered[0].ActorID, "registration has no actor")
+	assert.NotNil(t, column(registered[0].After, "email"))
+	assert.Nil(t, column(registered[0].After, "password"), "secrets are not logged")
+
+	byActor, err := r.Audit.List(ctx, models.AuditFilter{ActorID: moderator.ID}, 0, 10)
+	require.NoError(t, err)
+	assert.Len(t, byActor, 3)
+}
+
+func testHealth(t *testing.T, r Repositories) {
+	ctx := context.Background()
+	require.NoError(t, r.Health.Ping(ctx))
+
+	version, err := r.Health.MigrationVersion(ctx)
+	require.NoError(t, err)
+	assert.Positive(t, version)
+}
+
+// testConcurrentProducts adds and deletes products of one PVZ from many goroutines, the
+// capacity and the counters must hold.
+func testConcurrentProducts(t *testing.T, r Repositories) {
+	const capacity, workers = 10, 20
+
+	ctx := context.Background()
+	pvz := newPVZ("Москва")
+	pvz.Capacity = ptr(capacity)
+	createPVZ(t, r, pvz)
+	rec := openReception(t, r, pvz.ID, day)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		added    int
+		rejected int
+	)
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := models.Product{ID: uuid.NewString(), DateTime: day.Add(time.Duration(i) * time.Second), Type: "обувь", ReceptionID: rec.ID}
+			_, err := r.Product.Add(ctx, p, true)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				added++
+			case errors.Is(err, er.ErrPVZOverCapacity):
+				rejected++
+			default:
+				t.Errorf("add product: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, capacity, added)
+	assert.Equal(t, workers-capacity, rejected)
+
+	errs := make(chan error, capacity)
+	for range capacity {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := r.Product.DeleteLast(ctx, pvz.ID)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	got, err := r.PVZ.GetByID(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, got.StoredItems)
+
+	_, err = r.Product.DeleteLast(ctx, pvz.ID)
+	assert.ErrorIs(t, err, er.ErrNoProducts)
+
+	mismatches, err := r.Stats.DailyIntakeMismatches(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
+
+func testConcurrentReceptions(t *testing.T, r Repositories) {
+	const workers = 10
+
+	ctx := context.Background()
+	pvz := createPVZ(t, r, newPVZ("Казань"))
+
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := models.Reception{ID: uuid.NewString(), DateTime: day.Add(time.Duration(i) * time.Second), PVZID: pvz.ID, Status: "in_progress"}
+			errs <- r.Reception.Create(ctx, rec)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		if err == nil {
+			created++
+			continue
+		}
+		assert.ErrorIs(t, err, er.ErrReceptionAlreadyExists)
+	}
+	assert.Equal(t, 1, created)
+
+	unknown := models.Reception{ID: uuid.NewString(), DateTime: day, PVZID: uuid.NewString(), Status: "in_progress"}
+	assert.ErrorIs(t, r.Reception.Create(ctx, unknown), er.ErrNoPVZ)
+}
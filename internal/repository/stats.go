@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"trainee-pvz/internal/logger"
+	"trainee-pvz/internal/models"
+)
+
+type StatsRepository struct {
+	db      *sqlx.DB
+	replica *sqlx.DB
+}
+
+// NewStatsRepository serves the report queries from replica and everything else from db;
+// pass db twice when there is no replica.
+func NewStatsRepository(db, replica *sqlx.DB) *StatsRepository {
+	return &StatsRepository{db: db, replica: replica}
+}
+
+// IntakeRows reads the pre-aggregated daily_intake table, so the period is expected to be
+// aligned to UTC days.
+func (r *StatsRepository) IntakeRows(ctx context.Context, q models.IntakeQuery) ([]models.IntakeRow, error) {
+	var rows []models.IntakeRow
+	err := r.replica.SelectContext(ctx, &rows, IntakeRowsQuery(q.GroupBy), q.Bucket, q.From, q.To)
+	if err != nil {
+		logger.FromContext(ctx).Error("select intake stats failed", slog.Any("err", err))
+		return nil, errors.Wrap(err, "stats repo: intake rows")
+	}
+
+	return rows, nil
+}
+
+func (r *StatsRepository) ReceptionSummary(ctx context.Context, q models.IntakeQuery) (models.ReceptionSummary, error) {
+	var summary models.ReceptionSummary
+	err := r.replica.GetContext(ctx, &summary, ReceptionSummaryQuery, q.From, q.To)
+	if err != nil {
+		logger.FromContext(ctx).Error("select reception summary failed", slog.Any("err", err))
+		return summary, errors.Wrap(err, "stats repo: reception summary")
+	}
+
+	return summary, nil
+}
+
+// OpenReceptionsByCity counts in-progress receptions per city; cities without any are omitted.
+func (r *StatsRepository) OpenReceptionsByCity(ctx context.Context) (map[string]int64, error) {
+	var rows []struct {
+		City       string `db:"city"`
+		Receptions int64  `db:"receptions"`
+	}
+	err := r.db.SelectContext(ctx, &rows, OpenReceptionsByCityQuery)
+	if err != nil {
+		logger.FromContext(ctx).Error("select open receptions failed", slog.Any("err", err))
+		return nil, errors.Wrap(err, "stats repo: open receptions")
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.City] = row.Receptions
+	}
+
+	return counts, nil
+}
+
+// CapacityUtilisationByCity returns stored items against capacity per city, over active PVZs
+// with a capacity; cities without such PVZs are omitted.
+func (r *StatsRepository) CapacityUtilisationByCity(ctx context.Context) (map[string]float64, error) {
+	var rows []struct {
+		City        string  `db:"city"`
+		Utilisation float64 `db:"utilisation"`
+	}
+	err := r.db.SelectContext(ctx, &rows, CapacityUtilisationByCityQuery)
+	if err != nil {
+		logger.FromContext(ctx).Error("select capacity utilisation failed", slog.Any("err", err))
+		return nil, errors.Wrap(err, "stats repo: capacity utilisation")
+	}
+
+	utilisation := make(map[string]float64, len(rows))
+	for _, row := range rows {
+		utilisation[row.City] = row.Utilisation
+	}
+
+	return utilisation, nil
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/logger"
 	"trainee-pvz/internal/models"
 )
 
@@ -19,13 +21,32 @@ func NewUserRepository(db *sqlx.DB) *UserRepository {
 }
 
 func (r *UserRepository) Create(ctx context.Context, user models.User) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "repo: begin tx")
+	}
+	defer tx.Rollback()
+
 	query := `INSERT INTO users (id, email, password, role) VALUES (:id, :email, :password, :role)`
-	_, err := r.db.NamedExecContext(ctx, query, user)
+	_, err = tx.NamedExecContext(ctx, query, user)
+	if isUniqueViolation(err, "users_email_key") {
+		return er.ErrUserAlreadyExists
+	}
 	if err != nil {
-		slog.Error("create user failed", slog.Any("err", err))
+		logger.FromContext(ctx).Error("create user failed", slog.Any("err", err))
 		return errors.Wrap(err, "repo: create user")
 	}
 
+	err = recordChange(ctx, tx, models.AuditUserRegister, models.AuditEntityUser, user.ID, nil)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(err, "repo: commit create user")
+	}
+
 	return nil
 }
 
@@ -34,7 +55,7 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (models.U
 	query := `SELECT id, email, password, role FROM users WHERE email = $1`
 	err := r.db.GetContext(ctx, &user, query, email)
 	if err != nil {
-		slog.Error("get user by email failed", slog.Any("err", err))
+		logger.FromContext(ctx).Error("get user by email failed", slog.Any("err", err))
 		return user, errors.Wrap(err, "repo: get user")
 	}
 
@@ -0,0 +1,33 @@
+// Package requestid checks request IDs sent by clients, so HTTP and gRPC accept the same ones.
+package requestid
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const maxLen = 128
+
+// Valid accepts client IDs that are safe to put into logs as is.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLen {
+		return false
+	}
+
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.:", c)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Accept returns the client ID when it is valid and a new one otherwise.
+func Accept(id string) string {
+	if !Valid(id) {
+		return uuid.NewString()
+	}
+	return id
+}
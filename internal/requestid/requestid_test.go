@@ -0,0 +1,38 @@
+package requestid_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"trainee-pvz/internal/requestid"
+)
+
+func TestAccept(t *testing.T) {
+	cases := []struct {
+		name  string
+		id    string
+		valid bool
+	}{
+		{"uuid", "7f5d3b2e-8c1a-4e2f-9b6d-1a2b3c4d5e6f", true},
+		{"trace style", "req_42.retry:1", true},
+		{"empty", "", false},
+		{"too long", strings.Repeat("a", 129), false},
+		{"newline", "req\nfake log line", false},
+		{"space", "req 1", false},
+		{"non ascii", "запрос", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			id := requestid.Accept(tc.id)
+			if tc.valid {
+				assert.Equal(t, tc.id, id)
+				return
+			}
+			assert.NoError(t, uuid.Validate(id), "a new id is generated")
+		})
+	}
+}
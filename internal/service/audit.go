@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/tracing"
+)
+
+type AuditRepository interface {
+	List(ctx context.Context, filter models.AuditFilter, afterID int64, limit int) ([]models.AuditEntry, error)
+}
+
+type AuditService struct {
+	repo AuditRepository
+}
+
+func NewAuditService(repo AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// ListAudit returns up to limit entries, newest first. The cursor is the id of the last
+// entry of the previous page.
+func (s *AuditService) ListAudit(ctx context.Context, filter models.AuditFilter, cursor string, limit int) (models.AuditPage, error) {
+	ctx, span := tracing.Start(ctx, "AuditService.ListAudit")
+	defer span.End()
+
+	var (
+		page    models.AuditPage
+		afterID int64
+	)
+
+	if cursor != "" {
+		id, err := strconv.ParseInt(cursor, 10, 64)
+		if err != nil || id <= 0 {
+			return page, errors.Wrap(er.ErrInvalidCursor, "audit cursor must be a positive id")
+		}
+		afterID = id
+	}
+
+	// one extra row tells whether there is a next page
+	items, err := s.repo.List(ctx, filter, afterID, limit+1)
+	if err != nil {
+		return page, errors.Wrap(err, "can't list audit log")
+	}
+
+	if len(items) > limit {
+		items = items[:limit]
+		page.NextCursor = strconv.FormatInt(items[len(items)-1].ID, 10)
+	}
+	page.Items = items
+
+	return page, nil
+}
@@ -0,0 +1,56 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/service"
+)
+
+type fakeAuditRepo struct {
+	entries []models.AuditEntry
+	afterID int64
+	limit   int
+}
+
+func (f *fakeAuditRepo) List(ctx context.Context, filter models.AuditFilter, afterID int64, limit int) ([]models.AuditEntry, error) {
+	f.afterID, f.limit = afterID, limit
+	if len(f.entries) > limit {
+		return f.entries[:limit], nil
+	}
+	return f.entries, nil
+}
+
+func TestAuditService_ListAudit_NextCursor(t *testing.T) {
+	repo := &fakeAuditRepo{entries: []models.AuditEntry{{ID: 9}, {ID: 7}, {ID: 4}}}
+	svc := service.NewAuditService(repo)
+
+	page, err := svc.ListAudit(context.Background(), models.AuditFilter{}, "10", 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), repo.afterID)
+	assert.Equal(t, 3, repo.limit)
+	assert.Len(t, page.Items, 2)
+	assert.Equal(t, "7", page.NextCursor)
+}
+
+func TestAuditService_ListAudit_LastPage(t *testing.T) {
+	repo := &fakeAuditRepo{entries: []models.AuditEntry{{ID: 2}}}
+	svc := service.NewAuditService(repo)
+
+	page, err := svc.ListAudit(context.Background(), models.AuditFilter{}, "", 2)
+	require.NoError(t, err)
+	assert.Zero(t, repo.afterID)
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestAuditService_ListAudit_InvalidCursor(t *testing.T) {
+	svc := service.NewAuditService(&fakeAuditRepo{})
+
+	_, err := svc.ListAudit(context.Background(), models.AuditFilter{}, "abc", 2)
+	assert.ErrorIs(t, err, er.ErrInvalidCursor)
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"io"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/export"
+	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/tracing"
+)
+
+type ExportRepository interface {
+	StreamReceptions(ctx context.Context, q models.ExportQuery, fn func(models.ExportRow) error) error
+}
+
+type ExportService struct {
+	repo ExportRepository
+}
+
+func NewExportService(repo ExportRepository) *ExportService {
+	return &ExportService{repo: repo}
+}
+
+// ExportReceptions streams receptions with their products to w in the requested format.
+// The query is validated before anything is written, so ErrInvalidExportQuery always
+// comes with an untouched w, and so does a failure before the first row.
+func (s *ExportService) ExportReceptions(ctx context.Context, q models.ExportQuery, w io.Writer) error {
+	ctx, span := tracing.Start(ctx, "ExportService.ExportReceptions")
+	defer span.End()
+
+	err := validateExportQuery(q)
+	if err != nil {
+		return err
+	}
+
+	out, err := export.NewWriter(q.Format, w)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.StreamReceptions(ctx, q, out.Write)
+	if err != nil {
+		// a closed file would look complete, so what's buffered is dropped
+		out.Abort()
+		return errors.Wrap(err, "can't export receptions")
+	}
+
+	err = out.Close()
+	if err != nil {
+		return errors.Wrap(err, "can't finish export")
+	}
+
+	return nil
+}
+
+func validateExportQuery(q models.ExportQuery) error {
+	if _, err := uuid.Parse(q.PVZID); err != nil {
+		return errors.Wrap(er.ErrInvalidExportQuery, "invalid pvz id")
+	}
+
+	if !q.From.Before(q.To) {
+		return errors.Wrap(er.ErrInvalidExportQuery, "from must be before to")
+	}
+
+	switch q.Format {
+	case models.ExportCSV, models.ExportXLSX:
+	default:
+		return errors.Wrapf(er.ErrInvalidExportQuery, "unknown format %q", q.Format)
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/service"
+)
+
+type fakeExportRepo struct {
+	rows   []models.ExportRow
+	err    error
+	called bool
+}
+
+func (f *fakeExportRepo) StreamReceptions(ctx context.Context, q models.ExportQuery, fn func(models.ExportRow) error) error {
+	f.called = true
+	for _, row := range f.rows {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return f.err
+}
+
+func exportQuery(format string) models.ExportQuery {
+	to := time.Date(2025, 4, 21, 0, 0, 0, 0, time.UTC)
+	return models.ExportQuery{
+		PVZID:  "8a3c4c34-6b0e-4d0a-9f0f-7a7c1f4b2f11",
+		From:   to.AddDate(0, -1, 0),
+		To:     to,
+		Format: format,
+	}
+}
+
+func TestExportService_CSV(t *testing.T) {
+	email := "employee@example.com"
+	productID := "product1"
+	productType := "обувь"
+	received := time.Date(2025, 4, 20, 10, 0, 0, 0, time.UTC)
+	repo := &fakeExportRepo{rows: []models.ExportRow{{
+		ReceptionID:       "rec1",
+		ReceptionDateTime: received,
+		ReceptionStatus:   "in_progress",
+		City:              "Казань",
+		EmployeeEmail:     &email,
+		ProductID:         &productID,
+		ProductType:       &productType,
+		ProductDateTime:   &received,
+	}}}
+	svc := service.NewExportService(repo)
+
+	var buf bytes.Buffer
+	err := svc.ExportReceptions(context.Background(), exportQuery(models.ExportCSV), &buf)
+	require.NoError(t, err)
+
+	body := buf.String()
+	assert.True(t, strings.HasPrefix(body, "\uFEFF"))
+
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(body, "\uFEFF"))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "reception_id", records[0][0])
+	assert.Equal(t, []string{
+		"rec1", "2025-04-20T10:00:00Z", "in_progress", "", "Казань", email,
+		productID, productType, "2025-04-20T10:00:00Z", "",
+	}, records[1])
+}
+
+func TestExportService_XLSX(t *testing.T) {
+	repo := &fakeExportRepo{rows: []models.ExportRow{{ReceptionID: "rec1", ReceptionDateTime: time.Now(), City: "Москва"}}}
+	svc := service.NewExportService(repo)
+
+	var buf bytes.Buffer
+	err := svc.ExportReceptions(context.Background(), exportQuery(models.ExportXLSX), &buf)
+	require.NoError(t, err)
+	// xlsx is a zip archive
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("PK")))
+}
+
+func TestExportService_InvalidQuery(t *testing.T) {
+	tests := map[string]func(q *models.ExportQuery){
+		"format": func(q *models.ExportQuery) { q.Format = "pdf" },
+		"pvz":    func(q *models.ExportQuery) { q.PVZID = "not-a-uuid" },
+		"period": func(q *models.ExportQuery) { q.From = q.To },
+	}
+
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			repo := &fakeExportRepo{}
+			svc := service.NewExportService(repo)
+			q := exportQuery(models.ExportCSV)
+			mutate(&q)
+
+			var buf bytes.Buffer
+			err := svc.ExportReceptions(context.Background(), q, &buf)
+			assert.ErrorIs(t, err, er.ErrInvalidExportQuery)
+			assert.False(t, repo.called)
+			assert.Zero(t, buf.Len())
+		})
+	}
+}
+
+func TestExportService_RepoError(t *testing.T) {
+	row := models.ExportRow{ReceptionID: "rec1", ReceptionDateTime: time.Now(), City: "Москва"}
+	cases := map[string]*fakeExportRepo{
+		"before the first row": {err: errors.New("db down")},
+		"after a row":          {rows: []models.ExportRow{row}, err: errors.New("db down")},
+	}
+
+	for name, repo := range cases {
+		for _, format := range []string{models.ExportCSV, models.ExportXLSX} {
+			t.Run(name+"/"+format, func(t *testing.T) {
+				var buf bytes.Buffer
+				err := service.NewExportService(repo).ExportReceptions(context.Background(), exportQuery(format), &buf)
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "db down")
+				assert.Zero(t, buf.Len(), "nothing is written, the handler can still answer with an error")
+			})
+		}
+	}
+}
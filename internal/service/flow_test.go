@@ -0,0 +1,73 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/repository/memory"
+	"trainee-pvz/internal/service"
+)
+
+// TestReceptionFlow_Memory runs the services together on the in-memory repositories, so the
+// rules spread over several services are checked against real state instead of fakes.
+func TestReceptionFlow_Memory(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	m := &fakeMetrics{}
+	stats := service.NewStatsService(memory.NewStatsRepository(store))
+	pvzs := service.NewPVZService(memory.NewPVZRepository(store), m)
+	receptions := service.NewReceptionService(memory.NewReceptionRepository(store), m)
+	products := service.NewProductService(memory.NewProductRepository(store), m, true)
+
+	capacity := 2
+	pvz := models.PVZ{ID: uuid.NewString(), RegistrationDate: time.Now(), City: "Казань", Capacity: &capacity}
+	require.NoError(t, pvzs.CreatePVZ(ctx, pvz))
+
+	rec := models.Reception{ID: uuid.NewString(), DateTime: time.Now(), PVZID: pvz.ID, Status: "in_progress"}
+	require.NoError(t, receptions.CreateReception(ctx, rec))
+
+	_, err := pvzs.ArchivePVZ(ctx, pvz.ID)
+	assert.ErrorIs(t, err, er.ErrPVZHasOpenReception)
+
+	var added []models.Product
+	for i := range capacity + 1 {
+		p := models.Product{ID: uuid.NewString(), DateTime: time.Now().Add(time.Duration(i) * time.Second), Type: "обувь", ReceptionID: rec.ID}
+		_, err = products.AddProduct(ctx, p)
+		if i == capacity {
+			assert.ErrorIs(t, err, er.ErrPVZOverCapacity)
+			break
+		}
+		require.NoError(t, err)
+		added = append(added, p)
+	}
+	utilisation, err := stats.CapacityUtilisationByCity(ctx)
+	require.NoError(t, err)
+	assert.InDelta(t, 1.0, utilisation["Казань"], 1e-9)
+
+	require.NoError(t, products.DeleteLastProduct(ctx, pvz.ID))
+	utilisation, err = stats.CapacityUtilisationByCity(ctx)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.5, utilisation["Казань"], 1e-9)
+
+	assert.ErrorIs(t, products.IssueProduct(ctx, added[0].ID), er.ErrReceptionNotClosed)
+	require.NoError(t, receptions.CloseReception(ctx, rec.ID))
+	require.NoError(t, products.IssueProduct(ctx, added[0].ID))
+	assert.ErrorIs(t, products.IssueProduct(ctx, added[1].ID), er.ErrProductNotFound, "deleted as the last one")
+
+	archived, err := pvzs.ArchivePVZ(ctx, pvz.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, archived.ArchivedAt)
+	utilisation, err = stats.CapacityUtilisationByCity(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, utilisation, "archived PVZs are not counted")
+
+	rec.ID = uuid.NewString()
+	assert.ErrorIs(t, receptions.CreateReception(ctx, rec), er.ErrPVZArchived)
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+
+	"trainee-pvz/internal/logger"
+	"trainee-pvz/internal/models"
+)
+
+type HealthRepository interface {
+	Ping(ctx context.Context) error
+	MigrationVersion(ctx context.Context) (int64, error)
+}
+
+type HealthService struct {
+	repo            HealthRepository
+	expectedVersion int64
+	shuttingDown    atomic.Bool
+}
+
+// NewHealthService takes the newest migration the binary was built with; the service is not
+// ready until the database has at least that version.
+func NewHealthService(repo HealthRepository, expectedVersion int64) *HealthService {
+	return &HealthService{repo: repo, expectedVersion: expectedVersion}
+}
+
+// SetShuttingDown makes readiness fail for the rest of the process life, so load balancers
+// stop sending traffic while the servers drain.
+func (s *HealthService) SetShuttingDown() {
+	s.shuttingDown.Store(true)
+}
+
+// Readiness pings the database and compares the applied migration version with the expected one.
+// A newer schema is accepted, so old replicas keep serving during a rolling deploy. The checks
+// are served without auth, so database errors are only logged and reported in general terms.
+func (s *HealthService) Readiness(ctx context.Context) models.Readiness {
+	if s.shuttingDown.Load() {
+		return models.Readiness{Checks: map[string]string{models.CheckShutdown: "in progress"}}
+	}
+
+	result := models.Readiness{Ready: true, Checks: map[string]string{}}
+	fail := func(check, reason string) {
+		result.Ready = false
+		result.Checks[check] = reason
+	}
+
+	err := s.repo.Ping(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Error("readiness: ping database failed", slog.Any("err", err))
+		fail(models.CheckDatabase, "database unavailable")
+		fail(models.CheckMigrations, "database unavailable")
+		return result
+	}
+	result.Checks[models.CheckDatabase] = models.CheckOK
+
+	version, err := s.repo.MigrationVersion(ctx)
+	switch {
+	case err != nil:
+		logger.FromContext(ctx).Error("readiness: read schema version failed", slog.Any("err", err))
+		fail(models.CheckMigrations, "schema version unavailable")
+	case version < s.expectedVersion:
+		fail(models.CheckMigrations, fmt.Sprintf("schema version %d, expected %d", version, s.expectedVersion))
+	default:
+		result.Checks[models.CheckMigrations] = models.CheckOK
+	}
+
+	return result
+}
@@ -0,0 +1,70 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/service"
+)
+
+type fakeHealthRepo struct {
+	pingErr    error
+	versionErr error
+	version    int64
+	pinged     bool
+}
+
+func (f *fakeHealthRepo) Ping(ctx context.Context) error {
+	f.pinged = true
+	return f.pingErr
+}
+
+func (f *fakeHealthRepo) MigrationVersion(ctx context.Context) (int64, error) {
+	return f.version, f.versionErr
+}
+
+func TestHealthService_Readiness(t *testing.T) {
+	tests := []struct {
+		name   string
+		repo   *fakeHealthRepo
+		ready  bool
+		failed string
+	}{
+		{"up to date", &fakeHealthRepo{version: 5}, true, ""},
+		{"newer schema", &fakeHealthRepo{version: 6}, true, ""},
+		{"pending migrations", &fakeHealthRepo{version: 4}, false, models.CheckMigrations},
+		{"database down", &fakeHealthRepo{pingErr: errors.New("dial tcp db.internal:5432: connection refused")}, false, models.CheckDatabase},
+		{"no version table", &fakeHealthRepo{versionErr: errors.New(`relation "goose_db_version" does not exist`)}, false, models.CheckMigrations},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := service.NewHealthService(tt.repo, 5)
+
+			result := svc.Readiness(context.Background())
+			assert.Equal(t, tt.ready, result.Ready)
+			if tt.failed != "" {
+				assert.NotEqual(t, models.CheckOK, result.Checks[tt.failed])
+			}
+			// the checks are public, database errors stay in the log
+			for _, reason := range result.Checks {
+				assert.NotContains(t, reason, "db.internal")
+				assert.NotContains(t, reason, "goose_db_version")
+			}
+		})
+	}
+}
+
+func TestHealthService_ShuttingDown(t *testing.T) {
+	repo := &fakeHealthRepo{version: 5}
+	svc := service.NewHealthService(repo, 5)
+	svc.SetShuttingDown()
+
+	result := svc.Readiness(context.Background())
+	assert.False(t, result.Ready)
+	assert.Contains(t, result.Checks, models.CheckShutdown)
+	assert.False(t, repo.pinged)
+}
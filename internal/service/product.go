@@ -2,37 +2,98 @@ package service
 
 import (
 	"context"
+	"log/slog"
+	"time"
 
 	"github.com/pkg/errors"
 
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/logger"
 	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/tracing"
 )
 
 type ProductRepository interface {
-	Add(ctx context.Context, product models.Product) error
-	DeleteLast(ctx context.Context, pvzID string) error
+	Add(ctx context.Context, product models.Product, enforceCapacity bool) (models.StorageUsage, error)
+	AddBatch(ctx context.Context, receptionID string, products []models.Product, enforceCapacity bool) (models.StorageUsage, error)
+	DeleteLast(ctx context.Context, pvzID string) (models.StorageUsage, error)
+	Issue(ctx context.Context, productID string, issuedAt time.Time) (models.StorageUsage, error)
 }
 
 type ProductService struct {
-	repo    ProductRepository
-	metrics metrics
+	repo               ProductRepository
+	metrics            metrics
+	rejectOverCapacity bool
 }
 
-func NewProductService(repo ProductRepository, m metrics) *ProductService {
-	return &ProductService{repo: repo, metrics: m}
+// NewProductService creates the service. With rejectOverCapacity products that don't fit
+// into the PVZ are rejected, otherwise they are accepted and reported as over capacity.
+func NewProductService(repo ProductRepository, m metrics, rejectOverCapacity bool) *ProductService {
+	return &ProductService{repo: repo, metrics: m, rejectOverCapacity: rejectOverCapacity}
 }
 
-func (s *ProductService) AddProduct(ctx context.Context, p models.Product) error {
-	err := s.repo.Add(ctx, p)
+func (s *ProductService) AddProduct(ctx context.Context, p models.Product) (models.StorageUsage, error) {
+	ctx, span := tracing.Start(ctx, "ProductService.AddProduct")
+	defer span.End()
+
+	ctx = logger.With(ctx, slog.String("reception_id", p.ReceptionID), slog.String("product_id", p.ID))
+
+	usage, err := s.repo.Add(ctx, p, s.rejectOverCapacity)
+	if errors.Is(err, er.ErrPVZOverCapacity) {
+		return usage, err
+	}
 	if err != nil {
-		return errors.Wrap(err, "can't add product")
+		return usage, errors.Wrap(err, "can't add product")
 	}
 
 	s.metrics.SaveEntityCount(1, "product")
 
-	return nil
+	if usage.OverCapacity() {
+		logger.FromContext(ctx).Warn("pvz is over capacity",
+			slog.String("pvz_id", usage.PVZID), slog.Int("stored_items", usage.StoredItems), slog.Int("capacity", *usage.Capacity))
+	}
+
+	return usage, nil
+}
+
+// AddProducts adds products to the reception at once, either all of them or none.
+func (s *ProductService) AddProducts(ctx context.Context, receptionID string, products []models.Product) (models.StorageUsage, error) {
+	ctx, span := tracing.Start(ctx, "ProductService.AddProducts")
+	defer span.End()
+
+	ctx = logger.With(ctx, slog.String("reception_id", receptionID), slog.Int("products", len(products)))
+
+	if len(products) == 0 {
+		return models.StorageUsage{}, er.Invalid("no products to add")
+	}
+
+	usage, err := s.repo.AddBatch(ctx, receptionID, products, s.rejectOverCapacity)
+	if errors.Is(err, er.ErrPVZOverCapacity) {
+		return usage, err
+	}
+	if err != nil {
+		return usage, errors.Wrap(err, "can't add products")
+	}
+
+	s.metrics.SaveEntityCount(float64(len(products)), "product")
+
+	return usage, nil
 }
 
 func (s *ProductService) DeleteLastProduct(ctx context.Context, pvzID string) error {
-	return s.repo.DeleteLast(ctx, pvzID)
+	ctx, span := tracing.Start(ctx, "ProductService.DeleteLastProduct")
+	defer span.End()
+
+	_, err := s.repo.DeleteLast(ctx, pvzID)
+	return err
+}
+
+func (s *ProductService) IssueProduct(ctx context.Context, productID string) error {
+	ctx, span := tracing.Start(ctx, "ProductService.IssueProduct")
+	defer span.End()
+
+	ctx = logger.With(ctx, slog.String("product_id", productID))
+
+	_, err := s.repo.Issue(ctx, productID, time.Now().UTC())
+	return err
 }
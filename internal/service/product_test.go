@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	er "trainee-pvz/internal/errors"
 	"trainee-pvz/internal/models"
 	"trainee-pvz/internal/service"
 )
@@ -16,34 +18,51 @@ type fakeMetrics struct{}
 func (f *fakeMetrics) SaveEntityCount(value float64, entity string) {}
 
 type fakeProductRepo struct {
-	addErr    error
-	deleteErr error
+	addErr          error
+	deleteErr       error
+	issueErr        error
+	usage           models.StorageUsage
+	enforceCapacity bool
+	batch           []models.Product
 }
 
-func (f *fakeProductRepo) Add(ctx context.Context, p models.Product) error {
-	return f.addErr
+func (f *fakeProductRepo) Add(ctx context.Context, p models.Product, enforceCapacity bool) (models.StorageUsage, error) {
+	f.enforceCapacity = enforceCapacity
+	return f.usage, f.addErr
 }
 
-func (f *fakeProductRepo) DeleteLast(ctx context.Context, pvzID string) error {
-	return f.deleteErr
+func (f *fakeProductRepo) AddBatch(ctx context.Context, receptionID string, products []models.Product, enforceCapacity bool) (models.StorageUsage, error) {
+	f.enforceCapacity = enforceCapacity
+	f.batch = products
+	return f.usage, f.addErr
 }
+
+func (f *fakeProductRepo) DeleteLast(ctx context.Context, pvzID string) (models.StorageUsage, error) {
+	return f.usage, f.deleteErr
+}
+
+func (f *fakeProductRepo) Issue(ctx context.Context, productID string, issuedAt time.Time) (models.StorageUsage, error) {
+	return f.usage, f.issueErr
+}
+
 func TestProductService_AddProduct_Success(t *testing.T) {
 	repo := &fakeProductRepo{}
-	svc := service.NewProductService(repo, &fakeMetrics{})
+	svc := service.NewProductService(repo, &fakeMetrics{}, true)
 
-	err := svc.AddProduct(context.Background(), models.Product{
+	_, err := svc.AddProduct(context.Background(), models.Product{
 		ID:          "id1",
 		Type:        "одежда",
 		ReceptionID: "rec1",
 	})
 	assert.NoError(t, err)
+	assert.True(t, repo.enforceCapacity)
 }
 
 func TestProductService_AddProduct_Fail(t *testing.T) {
 	repo := &fakeProductRepo{addErr: errors.New("fail add")}
-	svc := service.NewProductService(repo, &fakeMetrics{})
+	svc := service.NewProductService(repo, &fakeMetrics{}, true)
 
-	err := svc.AddProduct(context.Background(), models.Product{
+	_, err := svc.AddProduct(context.Background(), models.Product{
 		ID:          "id2",
 		Type:        "обувь",
 		ReceptionID: "rec2",
@@ -51,9 +70,54 @@ func TestProductService_AddProduct_Fail(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "fail add")
 }
+
+func TestProductService_AddProduct_OverCapacityRejected(t *testing.T) {
+	repo := &fakeProductRepo{addErr: er.ErrPVZOverCapacity}
+	svc := service.NewProductService(repo, &fakeMetrics{}, true)
+
+	_, err := svc.AddProduct(context.Background(), models.Product{ID: "id3", ReceptionID: "rec3"})
+	assert.ErrorIs(t, err, er.ErrPVZOverCapacity)
+}
+
+func TestProductService_AddProduct_OverCapacityWarning(t *testing.T) {
+	capacity := 2
+	repo := &fakeProductRepo{usage: models.StorageUsage{PVZID: "pvz1", StoredItems: 3, Capacity: &capacity}}
+	svc := service.NewProductService(repo, &fakeMetrics{}, false)
+
+	usage, err := svc.AddProduct(context.Background(), models.Product{ID: "id4", ReceptionID: "rec4"})
+	assert.NoError(t, err)
+	assert.False(t, repo.enforceCapacity)
+	assert.True(t, usage.OverCapacity())
+}
+
+func TestProductService_AddProduct_NoCapacity(t *testing.T) {
+	repo := &fakeProductRepo{usage: models.StorageUsage{PVZID: "pvz1", StoredItems: 3}}
+	svc := service.NewProductService(repo, &fakeMetrics{}, true)
+
+	usage, err := svc.AddProduct(context.Background(), models.Product{ID: "id5", ReceptionID: "rec5"})
+	assert.NoError(t, err)
+	assert.False(t, usage.OverCapacity())
+}
+
+func TestProductService_AddProducts(t *testing.T) {
+	capacity := 4
+	repo := &fakeProductRepo{usage: models.StorageUsage{PVZID: "pvz1", StoredItems: 2, Capacity: &capacity}}
+	svc := service.NewProductService(repo, &fakeMetrics{}, true)
+
+	products := []models.Product{{ID: "id1", Type: "обувь"}, {ID: "id2", Type: "одежда"}}
+	_, err := svc.AddProducts(context.Background(), "rec1", products)
+	assert.NoError(t, err)
+	assert.Equal(t, products, repo.batch)
+	assert.True(t, repo.enforceCapacity)
+
+	_, err = svc.AddProducts(context.Background(), "rec1", nil)
+	assert.ErrorIs(t, err, er.ErrInvalidRequest)
+}
+
 func TestProductService_DeleteLastProduct_Success(t *testing.T) {
-	repo := &fakeProductRepo{}
-	svc := service.NewProductService(repo, &fakeMetrics{})
+	capacity := 4
+	repo := &fakeProductRepo{usage: models.StorageUsage{PVZID: "pvz1", StoredItems: 1, Capacity: &capacity}}
+	svc := service.NewProductService(repo, &fakeMetrics{}, true)
 
 	err := svc.DeleteLastProduct(context.Background(), "pvz1")
 	assert.NoError(t, err)
@@ -61,9 +125,26 @@ func TestProductService_DeleteLastProduct_Success(t *testing.T) {
 
 func TestProductService_DeleteLastProduct_Fail(t *testing.T) {
 	repo := &fakeProductRepo{deleteErr: errors.New("nothing to delete")}
-	svc := service.NewProductService(repo, &fakeMetrics{})
+	svc := service.NewProductService(repo, &fakeMetrics{}, true)
 
 	err := svc.DeleteLastProduct(context.Background(), "pvz2")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "nothing to delete")
 }
+
+func TestProductService_IssueProduct_Success(t *testing.T) {
+	capacity := 10
+	repo := &fakeProductRepo{usage: models.StorageUsage{PVZID: "pvz1", StoredItems: 5, Capacity: &capacity}}
+	svc := service.NewProductService(repo, &fakeMetrics{}, true)
+
+	err := svc.IssueProduct(context.Background(), "product1")
+	assert.NoError(t, err)
+}
+
+func TestProductService_IssueProduct_AlreadyIssued(t *testing.T) {
+	repo := &fakeProductRepo{issueErr: er.ErrProductAlreadyIssued}
+	svc := service.NewProductService(repo, &fakeMetrics{}, true)
+
+	err := svc.IssueProduct(context.Background(), "product1")
+	assert.ErrorIs(t, err, er.ErrProductAlreadyIssued)
+}
@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"math"
+	"slices"
 	"time"
 
 	"github.com/pkg/errors"
@@ -9,13 +11,31 @@ import (
 	er "trainee-pvz/internal/errors"
 	"trainee-pvz/internal/models"
 	"trainee-pvz/internal/openapi"
+	"trainee-pvz/internal/pagination"
+	"trainee-pvz/internal/tracing"
 )
 
 type PVZRepository interface {
 	Create(ctx context.Context, pvz models.PVZ) error
-	List(ctx context.Context, start, end *time.Time, page, limit int) ([]models.PVZ, error)
+	CreateBatch(ctx context.Context, pvzs []models.PVZ) error
+	ExistingExternalCodes(ctx context.Context, codes []string) ([]string, error)
+	GetByID(ctx context.Context, id string) (models.PVZ, error)
+	Update(ctx context.Context, pvz models.PVZ) error
+	Archive(ctx context.Context, id string, archivedAt time.Time) error
+	SetArchivedAt(ctx context.Context, id string, archivedAt *time.Time) error
+	List(ctx context.Context, filter models.PVZFilter, after *pagination.Cursor, limit int) ([]models.PVZ, error)
+	Nearby(ctx context.Context, q models.GeoQuery) ([]models.NearbyPVZ, error)
 }
 
+const (
+	hoursLayout       = "15:04"
+	maxNearbyRadiusKm = 500
+
+	// maxPageRows bounds the rows read for the deprecated page parameter, which reads all the
+	// pages before the requested one
+	maxPageRows = 10000
+)
+
 type metrics interface {
 	SaveEntityCount(value float64, entity string)
 }
@@ -30,22 +50,246 @@ func NewPVZService(repo PVZRepository, m metrics) *PVZService {
 }
 
 func (s *PVZService) CreatePVZ(ctx context.Context, pvz models.PVZ) error {
-	switch pvz.City {
-	case string(openapi.Москва), string(openapi.Казань), string(openapi.СанктПетербург):
-		err := s.repo.Create(ctx, pvz)
+	ctx, span := tracing.Start(ctx, "PVZService.CreatePVZ")
+	defer span.End()
+
+	err := ValidatePVZ(pvz)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.Create(ctx, pvz)
+	if errors.Is(err, er.ErrExternalCodeExists) {
+		return err
+	}
+	if err != nil {
+		return errors.Wrap(err, "can't create PVZ")
+	}
+
+	s.metrics.SaveEntityCount(1, "pvz")
+
+	return nil
+}
+
+// UpdatePVZ applies a partial profile update and validates the result before saving.
+func (s *PVZService) UpdatePVZ(ctx context.Context, id string, upd models.PVZUpdate) (models.PVZ, error) {
+	ctx, span := tracing.Start(ctx, "PVZService.UpdatePVZ")
+	defer span.End()
+
+	pvz, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return pvz, err
+	}
+
+	if upd.Address != nil {
+		pvz.Address = *upd.Address
+	}
+	if upd.Latitude != nil {
+		pvz.Latitude = upd.Latitude
+	}
+	if upd.Longitude != nil {
+		pvz.Longitude = upd.Longitude
+	}
+	if upd.WorkingHours != nil {
+		pvz.WorkingHours = upd.WorkingHours
+	}
+	if upd.Capacity != nil {
+		pvz.Capacity = upd.Capacity
+	}
+
+	err = ValidatePVZ(pvz)
+	if err != nil {
+		return pvz, err
+	}
+
+	err = s.repo.Update(ctx, pvz)
+	if err != nil {
+		return pvz, errors.Wrap(err, "can't update PVZ")
+	}
+
+	return pvz, nil
+}
+
+// ListPVZ returns up to limit PVZs after the opaque cursor (first page when it's empty).
+func (s *PVZService) ListPVZ(ctx context.Context, filter models.PVZFilter, cursor string, limit int) (models.PVZPage, error) {
+	ctx, span := tracing.Start(ctx, "PVZService.ListPVZ")
+	defer span.End()
+
+	var (
+		page  models.PVZPage
+		after *pagination.Cursor
+	)
+
+	if cursor != "" {
+		c, err := pagination.Decode(cursor)
 		if err != nil {
-			return errors.Wrap(err, "can't create PVZ")
+			return page, err
 		}
+		after = &c
+	}
+
+	// one extra row tells whether there is a next page
+	items, err := s.repo.List(ctx, filter, after, limit+1)
+	if err != nil {
+		return page, err
+	}
+
+	if len(items) > limit {
+		items = items[:limit]
+		last := items[len(items)-1]
+		page.NextCursor = pagination.Cursor{RegistrationDate: last.RegistrationDate, ID: last.ID}.Encode()
+	}
+	page.Items = items
+
+	return page, nil
+}
+
+// ListPVZByPage returns the 1-based page of PVZs for the deprecated page parameter of GET /pvz.
+// Unlike the cursor it skips or repeats rows when PVZs are created meanwhile; it is kept for one
+// release, until clients move to cursors.
+func (s *PVZService) ListPVZByPage(ctx context.Context, filter models.PVZFilter, page, limit int) ([]models.PVZ, error) {
+	ctx, span := tracing.Start(ctx, "PVZService.ListPVZByPage")
+	defer span.End()
+
+	if page < 1 {
+		return nil, er.Invalid("page must be a positive number, use cursor instead")
+	}
+	if page > maxPageRows/limit {
+		return nil, er.Invalid("page is too far, use cursor instead")
+	}
+
+	items, err := s.repo.List(ctx, filter, nil, page*limit)
+	if err != nil {
+		return nil, err
+	}
+
+	skip := (page - 1) * limit
+	if len(items) <= skip {
+		return []models.PVZ{}, nil
+	}
+
+	return items[skip:], nil
+}
 
-		s.metrics.SaveEntityCount(1, "pvz")
+// NearbyPVZ finds active PVZs around the point, closest first.
+func (s *PVZService) NearbyPVZ(ctx context.Context, q models.GeoQuery) ([]models.NearbyPVZ, error) {
+	ctx, span := tracing.Start(ctx, "PVZService.NearbyPVZ")
+	defer span.End()
 
-		return nil
+	if !finite(q.Latitude, q.Longitude, q.RadiusKm) {
+		return nil, er.Invalid("coordinates and radius must be finite numbers")
+	}
+
+	if q.Latitude < -90 || q.Latitude > 90 || q.Longitude < -180 || q.Longitude > 180 {
+		return nil, er.ErrInvalidCoordinates
+	}
+
+	if q.RadiusKm <= 0 || q.RadiusKm > maxNearbyRadiusKm {
+		return nil, er.ErrInvalidRadius
+	}
+
+	return s.repo.Nearby(ctx, q)
+}
+
+// ArchivePVZ hides the PVZ from listings and blocks new receptions in it.
+// History (receptions and products) is kept untouched.
+func (s *PVZService) ArchivePVZ(ctx context.Context, id string) (models.PVZ, error) {
+	ctx, span := tracing.Start(ctx, "PVZService.ArchivePVZ")
+	defer span.End()
+
+	// the repository checks the PVZ and archives it in one transaction, so a reception opened
+	// meanwhile can't end up in an archived PVZ
+	err := s.repo.Archive(ctx, id, time.Now().UTC())
+	if err != nil {
+		return models.PVZ{}, errors.Wrap(err, "can't archive PVZ")
+	}
+
+	return s.repo.GetByID(ctx, id)
+}
 
+func (s *PVZService) RestorePVZ(ctx context.Context, id string) (models.PVZ, error) {
+	ctx, span := tracing.Start(ctx, "PVZService.RestorePVZ")
+	defer span.End()
+
+	pvz, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return pvz, err
+	}
+
+	if pvz.ArchivedAt == nil {
+		return pvz, er.ErrPVZNotArchived
+	}
+
+	err = s.repo.SetArchivedAt(ctx, id, nil)
+	if err != nil {
+		return pvz, errors.Wrap(err, "can't restore PVZ")
+	}
+
+	pvz.ArchivedAt = nil
+
+	return pvz, nil
+}
+
+// ValidatePVZ checks the business rules every stored PVZ has to satisfy.
+func ValidatePVZ(pvz models.PVZ) error {
+	switch pvz.City {
+	case string(openapi.Москва), string(openapi.Казань), string(openapi.СанктПетербург):
 	default:
 		return er.ErrUnsupportedCity
 	}
+
+	if (pvz.Latitude == nil) != (pvz.Longitude == nil) {
+		return errors.Wrap(er.ErrInvalidCoordinates, "latitude and longitude must be set together")
+	}
+	if pvz.Latitude != nil && !finite(*pvz.Latitude, *pvz.Longitude) {
+		return errors.Wrap(er.ErrInvalidCoordinates, "coordinates must be finite numbers")
+	}
+	if pvz.Latitude != nil && (*pvz.Latitude < -90 || *pvz.Latitude > 90) {
+		return errors.Wrap(er.ErrInvalidCoordinates, "latitude out of range")
+	}
+	if pvz.Longitude != nil && (*pvz.Longitude < -180 || *pvz.Longitude > 180) {
+		return errors.Wrap(er.ErrInvalidCoordinates, "longitude out of range")
+	}
+
+	if pvz.Capacity != nil && *pvz.Capacity <= 0 {
+		return er.ErrInvalidCapacity
+	}
+
+	return validateWorkingHours(pvz.WorkingHours)
+}
+
+// finite reports whether none of vs is NaN or infinite. NaN passes every range check, and
+// ParseFloat accepts "NaN" and "Inf".
+func finite(vs ...float64) bool {
+	for _, v := range vs {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+
+	return true
 }
 
-func (s *PVZService) ListPVZ(ctx context.Context, start, end *time.Time, page, limit int) ([]models.PVZ, error) {
-	return s.repo.List(ctx, start, end, page, limit)
+func validateWorkingHours(hours models.WorkingHours) error {
+	for day, h := range hours {
+		if !slices.Contains(models.Weekdays, day) {
+			return errors.Wrapf(er.ErrInvalidWorkingHours, "unknown day %q", day)
+		}
+
+		open, err := time.Parse(hoursLayout, h.Open)
+		if err != nil {
+			return errors.Wrapf(er.ErrInvalidWorkingHours, "%s: bad open time %q", day, h.Open)
+		}
+
+		closeAt, err := time.Parse(hoursLayout, h.Close)
+		if err != nil {
+			return errors.Wrapf(er.ErrInvalidWorkingHours, "%s: bad close time %q", day, h.Close)
+		}
+
+		if !open.Before(closeAt) {
+			return errors.Wrapf(er.ErrInvalidWorkingHours, "%s: open time must be before close time", day)
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/logger"
+	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/tracing"
+)
+
+const maxImportRows = 5000
+
+// Columns of the import file, matched by header name in any order.
+const (
+	importColCity         = "city"
+	importColAddress      = "address"
+	importColLatitude     = "latitude"
+	importColLongitude    = "longitude"
+	importColExternalCode = "external_code"
+)
+
+var importColumns = []string{importColCity, importColAddress, importColLatitude, importColLongitude, importColExternalCode}
+
+// ImportPVZ creates PVZs from a CSV file. Every row goes through ValidatePVZ and the external
+// code uniqueness check, all errors are collected into the result. PVZs are created in one
+// transaction and only when the whole file is valid, nothing is created in dry-run mode.
+func (s *PVZService) ImportPVZ(ctx context.Context, r io.Reader, dryRun bool) (models.PVZImportResult, error) {
+	ctx, span := tracing.Start(ctx, "PVZService.ImportPVZ")
+	defer span.End()
+
+	result := models.PVZImportResult{DryRun: dryRun}
+
+	items, rows, rowErrors, err := parsePVZImport(r)
+	if err != nil {
+		return result, err
+	}
+	result.Errors = rowErrors
+
+	codes := make([]string, 0, len(items))
+	for _, pvz := range items {
+		codes = append(codes, *pvz.ExternalCode)
+	}
+
+	existing, err := s.repo.ExistingExternalCodes(ctx, codes)
+	if err != nil {
+		return result, errors.Wrap(err, "can't check external codes")
+	}
+
+	taken := make(map[string]bool, len(existing))
+	for _, code := range existing {
+		taken[code] = true
+	}
+
+	for i, pvz := range items {
+		if taken[*pvz.ExternalCode] {
+			result.Errors = append(result.Errors, models.PVZImportRowError{
+				Row:     rows[i],
+				Message: fmt.Sprintf("external code %q already exists", *pvz.ExternalCode),
+			})
+			continue
+		}
+		result.Items = append(result.Items, pvz)
+	}
+
+	if len(result.Errors) > 0 || dryRun {
+		logger.FromContext(ctx).Info("pvz import checked",
+			slog.Bool("dry_run", dryRun), slog.Int("valid", len(result.Items)), slog.Int("invalid", len(result.Errors)))
+		return result, nil
+	}
+
+	err = s.repo.CreateBatch(ctx, result.Items)
+	if err != nil {
+		return result, errors.Wrap(err, "can't import PVZ")
+	}
+
+	result.Imported = len(result.Items)
+	s.metrics.SaveEntityCount(float64(result.Imported), "pvz")
+
+	return result, nil
+}
+
+// parsePVZImport reads the file and returns valid PVZs with their line numbers and errors of the
+// other rows. An error is returned only when the file itself can't be used.
+func parsePVZImport(r io.Reader) ([]models.PVZ, []int, []models.PVZImportRowError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, nil, errors.Wrap(er.ErrInvalidImport, "file is empty")
+	}
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(er.ErrInvalidImport, err.Error())
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF")))
+		index[name] = i
+	}
+	for _, col := range importColumns {
+		if _, ok := index[col]; !ok {
+			return nil, nil, nil, errors.Wrapf(er.ErrInvalidImport, "missing column %q", col)
+		}
+	}
+
+	var (
+		items     []models.PVZ
+		rows      []int
+		rowErrors []models.PVZImportRowError
+		seen      = map[string]int{}
+		now       = time.Now().UTC()
+	)
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, nil, errors.Wrap(er.ErrInvalidImport, err.Error())
+		}
+		if line-1 > maxImportRows {
+			return nil, nil, nil, errors.Wrapf(er.ErrInvalidImport, "more than %d rows", maxImportRows)
+		}
+
+		field := func(col string) string {
+			i := index[col]
+			if i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		pvz, err := parseImportRow(field, now)
+		if err == nil {
+			err = ValidatePVZ(pvz)
+		}
+		if err == nil {
+			if prev, ok := seen[*pvz.ExternalCode]; ok {
+				err = errors.Errorf("external code %q duplicates row %d", *pvz.ExternalCode, prev)
+			}
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, models.PVZImportRowError{Row: line, Message: err.Error()})
+			continue
+		}
+
+		seen[*pvz.ExternalCode] = line
+		items = append(items, pvz)
+		rows = append(rows, line)
+	}
+
+	return items, rows, rowErrors, nil
+}
+
+func parseImportRow(field func(col string) string, now time.Time) (models.PVZ, error) {
+	pvz := models.PVZ{
+		ID:               uuid.New().String(),
+		RegistrationDate: now,
+		City:             field(importColCity),
+		Address:          field(importColAddress),
+	}
+
+	code := field(importColExternalCode)
+	if code == "" {
+		return pvz, errors.New("external code is required")
+	}
+	pvz.ExternalCode = &code
+
+	var err error
+	pvz.Latitude, err = parseCoordinate(field(importColLatitude))
+	if err != nil {
+		return pvz, errors.Wrap(er.ErrInvalidCoordinates, "bad latitude")
+	}
+
+	pvz.Longitude, err = parseCoordinate(field(importColLongitude))
+	if err != nil {
+		return pvz, errors.Wrap(er.ErrInvalidCoordinates, "bad longitude")
+	}
+
+	return pvz, nil
+}
+
+func parseCoordinate(v string) (*float64, error) {
+	if v == "" {
+		return nil, nil
+	}
+
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
@@ -0,0 +1,105 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/service"
+)
+
+const importHeader = "city,address,latitude,longitude,external_code\n"
+
+func TestPVZService_ImportPVZ_Success(t *testing.T) {
+	repo := &fakePVZRepo{}
+	svc := service.NewPVZService(repo, &fakeMetrics{})
+
+	file := "\uFEFF" + importHeader +
+		"Москва,Тверская 1,55.76,37.61,MSK-1\n" +
+		"Казань,Баумана 2,,,KZN-1\n"
+
+	result, err := svc.ImportPVZ(context.Background(), strings.NewReader(file), false)
+	require.NoError(t, err)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, 2, result.Imported)
+	require.Len(t, repo.batch, 2)
+	assert.Equal(t, "MSK-1", *repo.batch[0].ExternalCode)
+	assert.Nil(t, repo.batch[1].Latitude)
+}
+
+func TestPVZService_ImportPVZ_DryRun(t *testing.T) {
+	repo := &fakePVZRepo{}
+	svc := service.NewPVZService(repo, &fakeMetrics{})
+
+	file := importHeader + "Москва,Тверская 1,55.76,37.61,MSK-1\n"
+
+	result, err := svc.ImportPVZ(context.Background(), strings.NewReader(file), true)
+	require.NoError(t, err)
+	assert.True(t, result.DryRun)
+	assert.Len(t, result.Items, 1)
+	assert.Zero(t, result.Imported)
+	assert.Nil(t, repo.batch)
+}
+
+func TestPVZService_ImportPVZ_RowErrorsRejectWholeFile(t *testing.T) {
+	repo := &fakePVZRepo{existing: []string{"SPB-1"}}
+	svc := service.NewPVZService(repo, &fakeMetrics{})
+
+	file := importHeader +
+		"Москва,Тверская 1,55.76,37.61,MSK-1\n" +
+		"Новосибирск,Ленина 1,,,NSK-1\n" +
+		"Москва,Арбат 1,95,37.6,MSK-2\n" +
+		"Москва,Арбат 2,55.7,,MSK-3\n" +
+		"Казань,Баумана 2,,,MSK-1\n" +
+		"Санкт-Петербург,Невский 1,,,SPB-1\n" +
+		"Казань,Баумана 3,,,\n"
+
+	result, err := svc.ImportPVZ(context.Background(), strings.NewReader(file), false)
+	require.NoError(t, err)
+	assert.Zero(t, result.Imported)
+	assert.Nil(t, repo.batch)
+
+	rows := make([]int, 0, len(result.Errors))
+	for _, e := range result.Errors {
+		rows = append(rows, e.Row)
+	}
+	assert.ElementsMatch(t, []int{3, 4, 5, 6, 7, 8}, rows)
+}
+
+func TestPVZService_ImportPVZ_NonFiniteCoordinates(t *testing.T) {
+	repo := &fakePVZRepo{}
+	svc := service.NewPVZService(repo, &fakeMetrics{})
+
+	file := importHeader +
+		"Москва,Тверская 1,NaN,37.61,MSK-1\n" +
+		"Казань,Баумана 2,55.79,Inf,KZN-1\n"
+
+	result, err := svc.ImportPVZ(context.Background(), strings.NewReader(file), true)
+	require.NoError(t, err)
+	require.Len(t, result.Errors, 2)
+	assert.Equal(t, 2, result.Errors[0].Row)
+	assert.Contains(t, result.Errors[0].Message, "finite")
+	assert.Equal(t, 3, result.Errors[1].Row)
+	assert.Empty(t, result.Items)
+}
+
+func TestPVZService_ImportPVZ_InvalidFile(t *testing.T) {
+	tests := map[string]string{
+		"empty":          "",
+		"missing column": "city,address,latitude,longitude\nМосква,,,\n",
+	}
+
+	for name, file := range tests {
+		t.Run(name, func(t *testing.T) {
+			repo := &fakePVZRepo{}
+			svc := service.NewPVZService(repo, &fakeMetrics{})
+
+			_, err := svc.ImportPVZ(context.Background(), strings.NewReader(file), false)
+			assert.ErrorIs(t, err, er.ErrInvalidImport)
+		})
+	}
+}
@@ -3,30 +3,96 @@ package service_test
 import (
 	"context"
 	"errors"
+	"math"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	er "trainee-pvz/internal/errors"
 	"trainee-pvz/internal/models"
 	"trainee-pvz/internal/openapi"
+	"trainee-pvz/internal/pagination"
 	"trainee-pvz/internal/service"
 )
 
 type fakePVZRepo struct {
-	createErr error
-	listErr   error
-	data      []models.PVZ
+	createErr   error
+	listErr     error
+	data        []models.PVZ
+	pvz         models.PVZ
+	getErr      error
+	hasOpen     bool
+	archiveErr  error
+	archivedArg *time.Time
+	updateErr   error
+	updated     *models.PVZ
+	nearby      []models.NearbyPVZ
+	nearbyQuery *models.GeoQuery
+	listAfter   *pagination.Cursor
+	listLimit   int
+	batch       []models.PVZ
+	existing    []string
 }
 
 func (f *fakePVZRepo) Create(ctx context.Context, pvz models.PVZ) error {
 	return f.createErr
 }
 
-func (f *fakePVZRepo) List(ctx context.Context, start, end *time.Time, page, limit int) ([]models.PVZ, error) {
+func (f *fakePVZRepo) CreateBatch(ctx context.Context, pvzs []models.PVZ) error {
+	if f.createErr != nil {
+		return f.createErr
+	}
+	f.batch = pvzs
+	return nil
+}
+
+func (f *fakePVZRepo) ExistingExternalCodes(ctx context.Context, codes []string) ([]string, error) {
+	return f.existing, nil
+}
+
+func (f *fakePVZRepo) GetByID(ctx context.Context, id string) (models.PVZ, error) {
+	return f.pvz, f.getErr
+}
+
+func (f *fakePVZRepo) Update(ctx context.Context, pvz models.PVZ) error {
+	f.updated = &pvz
+	return f.updateErr
+}
+
+func (f *fakePVZRepo) Nearby(ctx context.Context, q models.GeoQuery) ([]models.NearbyPVZ, error) {
+	f.nearbyQuery = &q
+	return f.nearby, nil
+}
+
+func (f *fakePVZRepo) Archive(ctx context.Context, id string, archivedAt time.Time) error {
+	switch {
+	case f.getErr != nil:
+		return f.getErr
+	case f.pvz.ArchivedAt != nil:
+		return er.ErrPVZArchived
+	case f.hasOpen:
+		return er.ErrPVZHasOpenReception
+	}
+	f.archivedArg = &archivedAt
+	f.pvz.ArchivedAt = &archivedAt
+	return f.archiveErr
+}
+
+func (f *fakePVZRepo) SetArchivedAt(ctx context.Context, id string, archivedAt *time.Time) error {
+	f.archivedArg = archivedAt
+	return f.archiveErr
+}
+
+func (f *fakePVZRepo) List(ctx context.Context, filter models.PVZFilter, after *pagination.Cursor, limit int) ([]models.PVZ, error) {
+	f.listAfter, f.listLimit = after, limit
 	if f.listErr != nil {
 		return nil, f.listErr
 	}
+	if len(f.data) > limit {
+		return f.data[:limit], nil
+	}
 	return f.data, nil
 }
 
@@ -83,26 +149,260 @@ func TestPVZService_ListPVZ_Success(t *testing.T) {
 	start := now.Add(-time.Hour * 24)
 	end := now.Add(time.Hour * 24)
 
-	result, err := svc.ListPVZ(context.Background(), &start, &end, 1, 10)
+	result, err := svc.ListPVZ(context.Background(), models.PVZFilter{StartDate: &start, EndDate: &end}, "", 10)
 	assert.NoError(t, err)
-	assert.Len(t, result, 1)
-	assert.Equal(t, "Казань", result[0].City)
+	assert.Len(t, result.Items, 1)
+	assert.Equal(t, "Казань", result.Items[0].City)
+	assert.Empty(t, result.NextCursor)
+	assert.Nil(t, repo.listAfter)
+	assert.Equal(t, 11, repo.listLimit)
+}
+
+func TestPVZService_ListPVZ_NextCursor(t *testing.T) {
+	now := time.Now().UTC()
+	repo := &fakePVZRepo{
+		data: []models.PVZ{
+			{ID: "5d1c4b0e-6a43-4bd4-9a4f-5b1c0c8e7f01", RegistrationDate: now},
+			{ID: "5d1c4b0e-6a43-4bd4-9a4f-5b1c0c8e7f02", RegistrationDate: now.Add(-time.Minute)},
+			{ID: "5d1c4b0e-6a43-4bd4-9a4f-5b1c0c8e7f03", RegistrationDate: now.Add(-2 * time.Minute)},
+		},
+	}
+	svc := service.NewPVZService(repo, &fakeMetrics{})
+
+	result, err := svc.ListPVZ(context.Background(), models.PVZFilter{}, "", 2)
+	assert.NoError(t, err)
+	assert.Len(t, result.Items, 2)
+	assert.NotEmpty(t, result.NextCursor)
+
+	_, err = svc.ListPVZ(context.Background(), models.PVZFilter{}, result.NextCursor, 2)
+	assert.NoError(t, err)
+	assert.NotNil(t, repo.listAfter)
+	assert.Equal(t, "5d1c4b0e-6a43-4bd4-9a4f-5b1c0c8e7f02", repo.listAfter.ID)
+	assert.True(t, now.Add(-time.Minute).Equal(repo.listAfter.RegistrationDate))
+}
+
+func TestPVZService_ListPVZ_InvalidCursor(t *testing.T) {
+	repo := &fakePVZRepo{}
+	svc := service.NewPVZService(repo, &fakeMetrics{})
+
+	_, err := svc.ListPVZ(context.Background(), models.PVZFilter{}, "not a cursor", 10)
+	assert.ErrorIs(t, err, er.ErrInvalidCursor)
+	assert.Zero(t, repo.listLimit)
 }
 
 func TestPVZService_ListPVZ_Empty(t *testing.T) {
 	repo := &fakePVZRepo{data: []models.PVZ{}}
 	svc := service.NewPVZService(repo, &fakeMetrics{})
 
-	result, err := svc.ListPVZ(context.Background(), nil, nil, 1, 10)
+	result, err := svc.ListPVZ(context.Background(), models.PVZFilter{}, "", 10)
 	assert.NoError(t, err)
-	assert.Empty(t, result)
+	assert.Empty(t, result.Items)
 }
 
 func TestPVZService_ListPVZ_Error(t *testing.T) {
 	repo := &fakePVZRepo{listErr: errors.New("list fail")}
 	svc := service.NewPVZService(repo, &fakeMetrics{})
 
-	result, err := svc.ListPVZ(context.Background(), nil, nil, 1, 10)
+	result, err := svc.ListPVZ(context.Background(), models.PVZFilter{}, "", 10)
 	assert.Error(t, err)
-	assert.Nil(t, result)
+	assert.Nil(t, result.Items)
+}
+
+func TestPVZService_ListPVZByPage(t *testing.T) {
+	repo := &fakePVZRepo{data: []models.PVZ{{ID: "1"}, {ID: "2"}, {ID: "3"}, {ID: "4"}, {ID: "5"}}}
+	svc := service.NewPVZService(repo, &fakeMetrics{})
+
+	items, err := svc.ListPVZByPage(context.Background(), models.PVZFilter{}, 2, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []models.PVZ{{ID: "3"}, {ID: "4"}}, items)
+	assert.Nil(t, repo.listAfter)
+	assert.Equal(t, 4, repo.listLimit)
+
+	items, err = svc.ListPVZByPage(context.Background(), models.PVZFilter{}, 4, 2)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+	assert.NotNil(t, items, "an empty page is an empty array")
+
+	for _, page := range []int{0, -1, 5001} {
+		_, err = svc.ListPVZByPage(context.Background(), models.PVZFilter{}, page, 2)
+		assert.ErrorIs(t, err, er.ErrInvalidRequest, "page %d", page)
+	}
+}
+
+func TestPVZService_ArchivePVZ_Success(t *testing.T) {
+	repo := &fakePVZRepo{pvz: models.PVZ{ID: "1", City: "Москва"}}
+	svc := service.NewPVZService(repo, &fakeMetrics{})
+
+	pvz, err := svc.ArchivePVZ(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.NotNil(t, pvz.ArchivedAt)
+	assert.NotNil(t, repo.archivedArg)
+}
+
+func TestPVZService_ArchivePVZ_NotFound(t *testing.T) {
+	repo := &fakePVZRepo{getErr: er.ErrNoPVZ}
+	svc := service.NewPVZService(repo, &fakeMetrics{})
+
+	_, err := svc.ArchivePVZ(context.Background(), "1")
+	assert.ErrorIs(t, err, er.ErrNoPVZ)
+}
+
+func TestPVZService_ArchivePVZ_AlreadyArchived(t *testing.T) {
+	now := time.Now()
+	repo := &fakePVZRepo{pvz: models.PVZ{ID: "1", ArchivedAt: &now}}
+	svc := service.NewPVZService(repo, &fakeMetrics{})
+
+	_, err := svc.ArchivePVZ(context.Background(), "1")
+	assert.ErrorIs(t, err, er.ErrPVZArchived)
+	assert.Nil(t, repo.archivedArg)
+}
+
+func TestPVZService_ArchivePVZ_HasOpenReception(t *testing.T) {
+	repo := &fakePVZRepo{pvz: models.PVZ{ID: "1"}, hasOpen: true}
+	svc := service.NewPVZService(repo, &fakeMetrics{})
+
+	_, err := svc.ArchivePVZ(context.Background(), "1")
+	assert.ErrorIs(t, err, er.ErrPVZHasOpenReception)
+	assert.Nil(t, repo.archivedArg)
+}
+
+func TestPVZService_RestorePVZ_Success(t *testing.T) {
+	now := time.Now()
+	repo := &fakePVZRepo{pvz: models.PVZ{ID: "1", ArchivedAt: &now}, archivedArg: &now}
+	svc := service.NewPVZService(repo, &fakeMetrics{})
+
+	pvz, err := svc.RestorePVZ(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Nil(t, pvz.ArchivedAt)
+	assert.Nil(t, repo.archivedArg)
+}
+
+func TestPVZService_RestorePVZ_NotArchived(t *testing.T) {
+	repo := &fakePVZRepo{pvz: models.PVZ{ID: "1"}}
+	svc := service.NewPVZService(repo, &fakeMetrics{})
+
+	_, err := svc.RestorePVZ(context.Background(), "1")
+	assert.ErrorIs(t, err, er.ErrPVZNotArchived)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestPVZService_CreatePVZ_InvalidProfile(t *testing.T) {
+	cases := []struct {
+		name string
+		pvz  models.PVZ
+		err  error
+	}{
+		{"latitude without longitude", models.PVZ{Latitude: ptr(55.7)}, er.ErrInvalidCoordinates},
+		{"latitude out of range", models.PVZ{Latitude: ptr(91.0), Longitude: ptr(37.6)}, er.ErrInvalidCoordinates},
+		{"longitude out of range", models.PVZ{Latitude: ptr(55.7), Longitude: ptr(-181.0)}, er.ErrInvalidCoordinates},
+		{"NaN latitude", models.PVZ{Latitude: ptr(math.NaN()), Longitude: ptr(37.6)}, er.ErrInvalidCoordinates},
+		{"infinite longitude", models.PVZ{Latitude: ptr(55.7), Longitude: ptr(math.Inf(-1))}, er.ErrInvalidCoordinates},
+		{"zero capacity", models.PVZ{Capacity: ptr(0)}, er.ErrInvalidCapacity},
+		{"unknown day", models.PVZ{WorkingHours: models.WorkingHours{"monday": {Open: "09:00", Close: "18:00"}}}, er.ErrInvalidWorkingHours},
+		{"malformed time", models.PVZ{WorkingHours: models.WorkingHours{"mon": {Open: "9am", Close: "18:00"}}}, er.ErrInvalidWorkingHours},
+		{"close before open", models.PVZ{WorkingHours: models.WorkingHours{"mon": {Open: "18:00", Close: "09:00"}}}, er.ErrInvalidWorkingHours},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := service.NewPVZService(&fakePVZRepo{}, &fakeMetrics{})
+
+			tc.pvz.ID = "1"
+			tc.pvz.City = string(openapi.Москва)
+			err := svc.CreatePVZ(context.Background(), tc.pvz)
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestPVZService_CreatePVZ_FullProfile(t *testing.T) {
+	svc := service.NewPVZService(&fakePVZRepo{}, &fakeMetrics{})
+
+	err := svc.CreatePVZ(context.Background(), models.PVZ{
+		ID:           "1",
+		City:         string(openapi.Москва),
+		Address:      "ул. Тверская, 1",
+		Latitude:     ptr(55.757),
+		Longitude:    ptr(37.615),
+		WorkingHours: models.WorkingHours{"mon": {Open: "09:00", Close: "21:00"}},
+		Capacity:     ptr(500),
+	})
+	assert.NoError(t, err)
+}
+
+func TestPVZService_UpdatePVZ_Success(t *testing.T) {
+	repo := &fakePVZRepo{pvz: models.PVZ{ID: "1", City: string(openapi.Казань), Address: "old"}}
+	svc := service.NewPVZService(repo, &fakeMetrics{})
+
+	pvz, err := svc.UpdatePVZ(context.Background(), "1", models.PVZUpdate{
+		Latitude:  ptr(55.79),
+		Longitude: ptr(49.12),
+		Capacity:  ptr(100),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "old", pvz.Address)
+	assert.Equal(t, 100, *pvz.Capacity)
+	assert.NotNil(t, repo.updated)
+	assert.Equal(t, 55.79, *repo.updated.Latitude)
+}
+
+func TestPVZService_UpdatePVZ_Invalid(t *testing.T) {
+	repo := &fakePVZRepo{pvz: models.PVZ{ID: "1", City: string(openapi.Казань)}}
+	svc := service.NewPVZService(repo, &fakeMetrics{})
+
+	_, err := svc.UpdatePVZ(context.Background(), "1", models.PVZUpdate{Latitude: ptr(55.79)})
+	assert.ErrorIs(t, err, er.ErrInvalidCoordinates)
+	assert.Nil(t, repo.updated)
+}
+
+func TestPVZService_UpdatePVZ_NotFound(t *testing.T) {
+	repo := &fakePVZRepo{getErr: er.ErrNoPVZ}
+	svc := service.NewPVZService(repo, &fakeMetrics{})
+
+	_, err := svc.UpdatePVZ(context.Background(), "1", models.PVZUpdate{})
+	assert.ErrorIs(t, err, er.ErrNoPVZ)
+}
+
+func TestPVZService_NearbyPVZ_Success(t *testing.T) {
+	repo := &fakePVZRepo{nearby: []models.NearbyPVZ{{PVZ: models.PVZ{ID: "1"}, DistanceKm: 1.5}}}
+	svc := service.NewPVZService(repo, &fakeMetrics{})
+
+	q := models.GeoQuery{Latitude: 55.75, Longitude: 37.61, RadiusKm: 5, Limit: 10}
+	result, err := svc.NearbyPVZ(context.Background(), q)
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, q, *repo.nearbyQuery)
+}
+
+func TestPVZService_NearbyPVZ_InvalidQuery(t *testing.T) {
+	cases := []struct {
+		name string
+		q    models.GeoQuery
+		err  error
+	}{
+		{"latitude out of range", models.GeoQuery{Latitude: -91, RadiusKm: 1}, er.ErrInvalidCoordinates},
+		{"longitude out of range", models.GeoQuery{Longitude: 180.5, RadiusKm: 1}, er.ErrInvalidCoordinates},
+		{"zero radius", models.GeoQuery{Latitude: 55, Longitude: 37}, er.ErrInvalidRadius},
+		{"huge radius", models.GeoQuery{Latitude: 55, Longitude: 37, RadiusKm: 10000}, er.ErrInvalidRadius},
+		{"NaN latitude", models.GeoQuery{Latitude: math.NaN(), Longitude: 37, RadiusKm: 1}, er.ErrInvalidRequest},
+		{"NaN longitude", models.GeoQuery{Latitude: 55, Longitude: math.NaN(), RadiusKm: 1}, er.ErrInvalidRequest},
+		{"NaN radius", models.GeoQuery{Latitude: 55, Longitude: 37, RadiusKm: math.NaN()}, er.ErrInvalidRequest},
+		{"infinite latitude", models.GeoQuery{Latitude: math.Inf(1), Longitude: 37, RadiusKm: 1}, er.ErrInvalidRequest},
+		{"negative infinite longitude", models.GeoQuery{Latitude: 55, Longitude: math.Inf(-1), RadiusKm: 1}, er.ErrInvalidRequest},
+		{"infinite radius", models.GeoQuery{Latitude: 55, Longitude: 37, RadiusKm: math.Inf(1)}, er.ErrInvalidRequest},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &fakePVZRepo{}
+			svc := service.NewPVZService(repo, &fakeMetrics{})
+
+			_, err := svc.NearbyPVZ(context.Background(), tc.q)
+			assert.ErrorIs(t, err, tc.err)
+			assert.Nil(t, repo.nearbyQuery)
+		})
+	}
 }
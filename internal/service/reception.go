@@ -2,16 +2,19 @@ package service
 
 import (
 	"context"
+	"log/slog"
 
 	"github.com/pkg/errors"
 
 	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/logger"
 	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/tracing"
 )
 
 type ReceptionRepository interface {
+	IsPVZArchived(ctx context.Context, pvzID string) (bool, error)
 	HasOpenReception(ctx context.Context, pvzID string) (bool, error)
-	GetLastReceptionID(ctx context.Context, pvzID string) (string, error)
 	Create(ctx context.Context, r models.Reception) error
 	GetOpenReceptionID(ctx context.Context, pvzID string) (string, error)
 	Close(ctx context.Context, id string) error
@@ -27,6 +30,20 @@ func NewReceptionService(repo ReceptionRepository, m metrics) *ReceptionService
 }
 
 func (s *ReceptionService) CreateReception(ctx context.Context, rec models.Reception) error {
+	ctx, span := tracing.Start(ctx, "ReceptionService.CreateReception")
+	defer span.End()
+
+	ctx = logger.With(ctx, slog.String("reception_id", rec.ID))
+
+	archived, err := s.repo.IsPVZArchived(ctx, rec.PVZID)
+	if err != nil {
+		return err
+	}
+
+	if archived {
+		return er.ErrPVZArchived
+	}
+
 	hasOpen, err := s.repo.HasOpenReception(ctx, rec.PVZID)
 	if err != nil {
 		return err
@@ -47,13 +64,16 @@ func (s *ReceptionService) CreateReception(ctx context.Context, rec models.Recep
 }
 
 func (s *ReceptionService) CloseReception(ctx context.Context, id string) error {
-	return s.repo.Close(ctx, id)
-}
+	ctx, span := tracing.Start(ctx, "ReceptionService.CloseReception")
+	defer span.End()
 
-func (s *ReceptionService) GetLastReceptionID(ctx context.Context, pvzID string) (string, error) {
-	return s.repo.GetLastReceptionID(ctx, pvzID)
+	ctx = logger.With(ctx, slog.String("reception_id", id))
+	return s.repo.Close(ctx, id)
 }
 
 func (s *ReceptionService) GetOpenReceptionID(ctx context.Context, pvzID string) (string, error) {
+	ctx, span := tracing.Start(ctx, "ReceptionService.GetOpenReceptionID")
+	defer span.End()
+
 	return s.repo.GetOpenReceptionID(ctx, pvzID)
 }
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"testing"
 
+	er "trainee-pvz/internal/errors"
 	"trainee-pvz/internal/models"
 	"trainee-pvz/internal/service"
 
@@ -12,16 +13,20 @@ import (
 )
 
 type fakeReceptionRepo struct {
+	archived         bool
+	archivedErr      error
 	hasOpen          bool
 	hasOpenErr       error
 	createErr        error
 	closeErr         error
-	lastReceptionID  string
-	lastReceptionErr error
 	openReceptionID  string
 	openReceptionErr error
 }
 
+func (f *fakeReceptionRepo) IsPVZArchived(ctx context.Context, pvzID string) (bool, error) {
+	return f.archived, f.archivedErr
+}
+
 func (f *fakeReceptionRepo) HasOpenReception(ctx context.Context, pvzID string) (bool, error) {
 	return f.hasOpen, f.hasOpenErr
 }
@@ -34,10 +39,6 @@ func (f *fakeReceptionRepo) Close(ctx context.Context, id string) error {
 	return f.closeErr
 }
 
-func (f *fakeReceptionRepo) GetLastReceptionID(ctx context.Context, pvzID string) (string, error) {
-	return f.lastReceptionID, f.lastReceptionErr
-}
-
 func (f *fakeReceptionRepo) GetOpenReceptionID(ctx context.Context, pvzID string) (string, error) {
 	return f.openReceptionID, f.openReceptionErr
 }
@@ -78,21 +79,35 @@ func TestReceptionService_CreateReception_HasOpenErr(t *testing.T) {
 	assert.Contains(t, err.Error(), "db error")
 }
 
-func TestReceptionService_CloseReception_Success(t *testing.T) {
-	repo := &fakeReceptionRepo{}
+func TestReceptionService_CreateReception_PVZArchived(t *testing.T) {
+	repo := &fakeReceptionRepo{archived: true}
 	svc := service.NewReceptionService(repo, &fakeMetrics{})
 
-	err := svc.CloseReception(context.Background(), "reception-id")
-	assert.NoError(t, err)
+	err := svc.CreateReception(context.Background(), models.Reception{
+		ID:    "r4",
+		PVZID: "pvz4",
+	})
+	assert.Error(t, err)
+	assert.Equal(t, "pvz is archived", err.Error())
+}
+
+func TestReceptionService_CreateReception_PVZNotFound(t *testing.T) {
+	repo := &fakeReceptionRepo{archivedErr: er.ErrNoPVZ}
+	svc := service.NewReceptionService(repo, &fakeMetrics{})
+
+	err := svc.CreateReception(context.Background(), models.Reception{
+		ID:    "r5",
+		PVZID: "pvz5",
+	})
+	assert.ErrorIs(t, err, er.ErrNoPVZ)
 }
 
-func TestReceptionService_GetLastReceptionID_Success(t *testing.T) {
-	repo := &fakeReceptionRepo{lastReceptionID: "last-id"}
+func TestReceptionService_CloseReception_Success(t *testing.T) {
+	repo := &fakeReceptionRepo{}
 	svc := service.NewReceptionService(repo, &fakeMetrics{})
 
-	id, err := svc.GetLastReceptionID(context.Background(), "pvz-id")
+	err := svc.CloseReception(context.Background(), "reception-id")
 	assert.NoError(t, err)
-	assert.Equal(t, "last-id", id)
 }
 
 func TestReceptionService_GetOpenReceptionID_Success(t *testing.T) {
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/tracing"
+)
+
+type StatsRepository interface {
+	IntakeRows(ctx context.Context, q models.IntakeQuery) ([]models.IntakeRow, error)
+	ReceptionSummary(ctx context.Context, q models.IntakeQuery) (models.ReceptionSummary, error)
+	RebuildDailyIntake(ctx context.Context) (int64, error)
+	DailyIntakeMismatches(ctx context.Context) ([]models.DailyIntakeMismatch, error)
+	OpenReceptionsByCity(ctx context.Context) (map[string]int64, error)
+	CapacityUtilisationByCity(ctx context.Context) (map[string]float64, error)
+}
+
+type StatsService struct {
+	repo StatsRepository
+}
+
+func NewStatsService(repo StatsRepository) *StatsService {
+	return &StatsService{repo: repo}
+}
+
+// IntakeReport counts received products per time bucket and requested dimensions
+// and adds reception averages over the same period. Counters are kept per UTC day,
+// so the period is widened to whole days and the report carries the aligned period.
+func (s *StatsService) IntakeReport(ctx context.Context, q models.IntakeQuery) (models.IntakeReport, error) {
+	ctx, span := tracing.Start(ctx, "StatsService.IntakeReport")
+	defer span.End()
+
+	report := models.IntakeReport{Query: q}
+
+	err := validateIntakeQuery(q)
+	if err != nil {
+		return report, err
+	}
+
+	q.From, q.To = alignToDays(q.From, q.To)
+	report.Query = q
+
+	report.Rows, err = s.repo.IntakeRows(ctx, q)
+	if err != nil {
+		return report, errors.Wrap(err, "can't get intake rows")
+	}
+
+	report.Summary, err = s.repo.ReceptionSummary(ctx, q)
+	if err != nil {
+		return report, errors.Wrap(err, "can't get reception summary")
+	}
+
+	return report, nil
+}
+
+// OpenReceptionsByCity returns the number of in-progress receptions per city.
+func (s *StatsService) OpenReceptionsByCity(ctx context.Context) (map[string]int64, error) {
+	ctx, span := tracing.Start(ctx, "StatsService.OpenReceptionsByCity")
+	defer span.End()
+
+	counts, err := s.repo.OpenReceptionsByCity(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't count open receptions")
+	}
+
+	return counts, nil
+}
+
+// CapacityUtilisationByCity returns the share of storage capacity taken per city.
+func (s *StatsService) CapacityUtilisationByCity(ctx context.Context) (map[string]float64, error) {
+	ctx, span := tracing.Start(ctx, "StatsService.CapacityUtilisationByCity")
+	defer span.End()
+
+	utilisation, err := s.repo.CapacityUtilisationByCity(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't get capacity utilisation")
+	}
+
+	return utilisation, nil
+}
+
+// RebuildDailyIntake recomputes the daily counters from the raw products and returns
+// the number of stored rows.
+func (s *StatsService) RebuildDailyIntake(ctx context.Context) (int64, error) {
+	ctx, span := tracing.Start(ctx, "StatsService.RebuildDailyIntake")
+	defer span.End()
+
+	return s.repo.RebuildDailyIntake(ctx)
+}
+
+// CheckDailyIntake returns the counters that don't match the raw products, empty when consistent.
+func (s *StatsService) CheckDailyIntake(ctx context.Context) ([]models.DailyIntakeMismatch, error) {
+	ctx, span := tracing.Start(ctx, "StatsService.CheckDailyIntake")
+	defer span.End()
+
+	return s.repo.DailyIntakeMismatches(ctx)
+}
+
+// alignToDays moves from to the start of its UTC day and to to the start of the next day
+// unless it already is midnight.
+func alignToDays(from, to time.Time) (time.Time, time.Time) {
+	from = from.UTC().Truncate(24 * time.Hour)
+
+	day := to.UTC().Truncate(24 * time.Hour)
+	if day.Before(to) {
+		day = day.Add(24 * time.Hour)
+	}
+
+	return from, day
+}
+
+func validateIntakeQuery(q models.IntakeQuery) error {
+	if !q.From.Before(q.To) {
+		return errors.Wrap(er.ErrInvalidReportQuery, "from must be before to")
+	}
+
+	switch q.Bucket {
+	case models.BucketDay, models.BucketWeek, models.BucketMonth:
+	default:
+		return errors.Wrapf(er.ErrInvalidReportQuery, "unknown bucket %q", q.Bucket)
+	}
+
+	for i, dim := range q.GroupBy {
+		switch dim {
+		case models.GroupByPVZ, models.GroupByCity, models.GroupByType:
+		default:
+			return errors.Wrapf(er.ErrInvalidReportQuery, "unknown group %q", dim)
+		}
+
+		if slices.Contains(q.GroupBy[:i], dim) {
+			return errors.Wrapf(er.ErrInvalidReportQuery, "duplicated group %q", dim)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/service"
+)
+
+type fakeStatsRepo struct {
+	rows       []models.IntakeRow
+	rowsErr    error
+	summary    models.ReceptionSummary
+	summaryErr error
+	called     bool
+	query      models.IntakeQuery
+	mismatches []models.DailyIntakeMismatch
+	open       map[string]int64
+	capacity   map[string]float64
+}
+
+func (f *fakeStatsRepo) IntakeRows(ctx context.Context, q models.IntakeQuery) ([]models.IntakeRow, error) {
+	f.called = true
+	f.query = q
+	return f.rows, f.rowsErr
+}
+
+func (f *fakeStatsRepo) RebuildDailyIntake(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeStatsRepo) DailyIntakeMismatches(ctx context.Context) ([]models.DailyIntakeMismatch, error) {
+	return f.mismatches, nil
+}
+
+func (f *fakeStatsRepo) OpenReceptionsByCity(ctx context.Context) (map[string]int64, error) {
+	return f.open, nil
+}
+
+func (f *fakeStatsRepo) CapacityUtilisationByCity(ctx context.Context) (map[string]float64, error) {
+	return f.capacity, nil
+}
+
+func (f *fakeStatsRepo) ReceptionSummary(ctx context.Context, q models.IntakeQuery) (models.ReceptionSummary, error) {
+	return f.summary, f.summaryErr
+}
+
+func weekQuery() models.IntakeQuery {
+	to := time.Date(2025, 4, 21, 0, 0, 0, 0, time.UTC)
+	return models.IntakeQuery{
+		From:    to.AddDate(0, 0, -7),
+		To:      to,
+		Bucket:  models.BucketDay,
+		GroupBy: []string{models.GroupByPVZ, models.GroupByType},
+	}
+}
+
+func TestStatsService_IntakeReport_Success(t *testing.T) {
+	pvzID, productType := "pvz1", "обувь"
+	repo := &fakeStatsRepo{
+		rows:    []models.IntakeRow{{PVZID: &pvzID, ProductType: &productType, Products: 7}},
+		summary: models.ReceptionSummary{Receptions: 2, AvgDurationSeconds: 600, AvgProductsPerReception: 3.5},
+	}
+	svc := service.NewStatsService(repo)
+
+	report, err := svc.IntakeReport(context.Background(), weekQuery())
+	assert.NoError(t, err)
+	assert.Len(t, report.Rows, 1)
+	assert.Equal(t, 7, report.Rows[0].Products)
+	assert.Equal(t, 3.5, report.Summary.AvgProductsPerReception)
+}
+
+func TestStatsService_IntakeReport_AlignsToDays(t *testing.T) {
+	repo := &fakeStatsRepo{}
+	svc := service.NewStatsService(repo)
+
+	q := weekQuery()
+	q.From = time.Date(2025, 4, 14, 15, 30, 0, 0, time.UTC)
+	q.To = time.Date(2025, 4, 21, 9, 0, 0, 0, time.UTC)
+
+	report, err := svc.IntakeReport(context.Background(), q)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 4, 14, 0, 0, 0, 0, time.UTC), repo.query.From)
+	assert.Equal(t, time.Date(2025, 4, 22, 0, 0, 0, 0, time.UTC), repo.query.To)
+	assert.Equal(t, repo.query, report.Query)
+}
+
+func TestStatsService_IntakeReport_InvalidQuery(t *testing.T) {
+	cases := map[string]func(q *models.IntakeQuery){
+		"empty range":     func(q *models.IntakeQuery) { q.To = q.From },
+		"unknown bucket":  func(q *models.IntakeQuery) { q.Bucket = "year" },
+		"unknown group":   func(q *models.IntakeQuery) { q.GroupBy = []string{"employee"} },
+		"duplicate group": func(q *models.IntakeQuery) { q.GroupBy = []string{"city", "city"} },
+	}
+
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			repo := &fakeStatsRepo{}
+			svc := service.NewStatsService(repo)
+
+			q := weekQuery()
+			mutate(&q)
+			_, err := svc.IntakeReport(context.Background(), q)
+			assert.ErrorIs(t, err, er.ErrInvalidReportQuery)
+			assert.False(t, repo.called)
+		})
+	}
+}
+
+func TestStatsService_IntakeReport_RepoError(t *testing.T) {
+	repo := &fakeStatsRepo{summaryErr: errors.New("db error")}
+	svc := service.NewStatsService(repo)
+
+	_, err := svc.IntakeReport(context.Background(), weekQuery())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "db error")
+}
+
+func TestStatsService_CheckDailyIntake(t *testing.T) {
+	mismatch := models.DailyIntakeMismatch{PVZID: "pvz1", ProductType: "обувь", Stored: 3, Actual: 4}
+	repo := &fakeStatsRepo{mismatches: []models.DailyIntakeMismatch{mismatch}}
+	svc := service.NewStatsService(repo)
+
+	mismatches, err := svc.CheckDailyIntake(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []models.DailyIntakeMismatch{mismatch}, mismatches)
+}
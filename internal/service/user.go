@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"trainee-pvz/internal/models"
+	"trainee-pvz/internal/tracing"
 )
 
 type UserRepository interface {
@@ -19,9 +20,15 @@ func NewUserService(repo UserRepository) *UserService {
 }
 
 func (s *UserService) Register(ctx context.Context, user models.User) error {
+	ctx, span := tracing.Start(ctx, "UserService.Register")
+	defer span.End()
+
 	return s.repo.Create(ctx, user)
 }
 
 func (s *UserService) Login(ctx context.Context, email string) (models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Login")
+	defer span.End()
+
 	return s.repo.GetByEmail(ctx, email)
 }
@@ -0,0 +1,141 @@
+// Package validation checks JSON request bodies against the OpenAPI spec, so handlers get
+// only bodies with known fields, allowed enum values and well-formed uuids and emails.
+package validation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	er "trainee-pvz/internal/errors"
+)
+
+func init() {
+	openapi3.DefineStringFormat("email", openapi3.FormatOfStringForEmail)
+	openapi3.DefineStringFormatCallback("uuid", func(s string) error {
+		_, err := uuid.Parse(s)
+		return err
+	})
+}
+
+type body struct {
+	schema   *openapi3.Schema
+	required bool
+}
+
+// Validator holds the JSON body schemas of the spec operations.
+type Validator struct {
+	bodies map[string]body // by method and path, "POST /pvz/{pvzId}/archive"
+}
+
+// New loads the spec. Paths are matched as written there, which is the chi route pattern.
+func New(spec []byte) (*Validator, error) {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(spec)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't load spec")
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, errors.Wrap(err, "invalid spec")
+	}
+
+	v := &Validator{bodies: map[string]body{}}
+	for path, item := range doc.Paths.Map() {
+		for method, op := range item.Operations() {
+			if op.RequestBody == nil || op.RequestBody.Value == nil {
+				continue
+			}
+			media := op.RequestBody.Value.Content.Get("application/json")
+			if media == nil || media.Schema == nil {
+				continue
+			}
+			v.bodies[method+" "+path] = body{schema: media.Schema.Value, required: op.RequestBody.Value.Required}
+		}
+	}
+
+	return v, nil
+}
+
+// HasBody reports whether the route takes a JSON body.
+func (v *Validator) HasBody(method, pattern string) bool {
+	_, ok := v.bodies[method+" "+pattern]
+	return ok
+}
+
+// Validate checks the body of the route. Schema violations are returned as er.InvalidFields
+// with one entry per field, sorted by path.
+func (v *Validator) Validate(method, pattern string, data []byte) error {
+	b, ok := v.bodies[method+" "+pattern]
+	if !ok {
+		return nil
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		if b.required {
+			return er.Invalid("request body is required")
+		}
+		return nil
+	}
+
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return er.Invalid("invalid json")
+	}
+
+	err := b.schema.VisitJSON(value, openapi3.MultiErrors(), openapi3.VisitAsRequest())
+	if err == nil {
+		return nil
+	}
+
+	fields := fieldErrors(err, nil)
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+
+	return er.InvalidFields(fields)
+}
+
+// fieldErrors flattens the nested multi errors of VisitJSON.
+func fieldErrors(err error, fields []er.FieldError) []er.FieldError {
+	var multi openapi3.MultiError
+	if errors.As(err, &multi) {
+		for _, e := range multi {
+			fields = fieldErrors(e, fields)
+		}
+		return fields
+	}
+
+	var schemaErr *openapi3.SchemaError
+	if !errors.As(err, &schemaErr) {
+		// read-only properties in a request are reported without a path
+		name, _ := propertyName(err.Error(), "readOnly property ", " in request")
+		return append(fields, er.FieldError{Field: name, Message: err.Error()})
+	}
+
+	path := schemaErr.JSONPointer()
+	// unknown properties are reported on the object that has them
+	if name, ok := propertyName(schemaErr.Reason, "property ", " is unsupported"); ok {
+		path = append(path, name)
+	}
+
+	return append(fields, er.FieldError{Field: strings.Join(path, "."), Message: schemaErr.Reason})
+}
+
+// propertyName takes the quoted property name out of a kin-openapi message.
+func propertyName(message, prefix, suffix string) (string, bool) {
+	quoted, ok := strings.CutPrefix(message, prefix)
+	if !ok {
+		return "", false
+	}
+	quoted, ok = strings.CutSuffix(quoted, suffix)
+	if !ok {
+		return "", false
+	}
+	name, err := strconv.Unquote(quoted)
+	return name, err == nil
+}
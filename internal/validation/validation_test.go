@@ -0,0 +1,75 @@
+package validation_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"trainee-pvz/api"
+	er "trainee-pvz/internal/errors"
+	"trainee-pvz/internal/validation"
+)
+
+func TestValidate(t *testing.T) {
+	v, err := validation.New(api.Spec)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name    string
+		method  string
+		pattern string
+		body    string
+		fields  []string // invalid fields, nil when the body is valid
+	}{
+		{"valid product", http.MethodPost, "/products", `{"type":"обувь","pvzId":"7f5d3b2e-8c1a-4e2f-9b6d-1a2b3c4d5e6f"}`, nil},
+		{"unknown product type", http.MethodPost, "/products", `{"type":"мебель","pvzId":"7f5d3b2e-8c1a-4e2f-9b6d-1a2b3c4d5e6f"}`, []string{"type"}},
+		{"bad uuid", http.MethodPost, "/receptions", `{"pvzId":"42"}`, []string{"pvzId"}},
+		{"missing field", http.MethodPost, "/receptions", `{}`, []string{"pvzId"}},
+		{"unknown field", http.MethodPost, "/dummyLogin", `{"role":"employee","admin":true}`, []string{"admin"}},
+		{"unknown role and bad email", http.MethodPost, "/register", `{"email":"nope","password":"x","role":"admin"}`, []string{"email", "role"}},
+		{"nested field", http.MethodPatch, "/pvz/{pvzId}", `{"workingHours":{"mon":{"open":"25:00","close":"21:00"}}}`, []string{"workingHours.mon.open"}},
+		{"wrong type", http.MethodPost, "/pvz", `{"city":"Москва","capacity":"ten"}`, []string{"capacity"}},
+		{"read only field", http.MethodPost, "/pvz", `{"city":"Москва","storedItems":3}`, []string{"storedItems"}},
+		{"not an object", http.MethodPost, "/login", `[]`, []string{""}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.True(t, v.HasBody(tc.method, tc.pattern))
+
+			err := v.Validate(tc.method, tc.pattern, []byte(tc.body))
+			if tc.fields == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, er.ErrInvalidRequest)
+			var fields []string
+			for _, f := range er.From(err).Fields {
+				fields = append(fields, f.Field)
+				assert.NotEmpty(t, f.Message)
+			}
+			assert.Equal(t, tc.fields, fields)
+		})
+	}
+}
+
+func TestValidate_Malformed(t *testing.T) {
+	v, err := validation.New(api.Spec)
+	require.NoError(t, err)
+
+	err = v.Validate(http.MethodPost, "/products", nil)
+	require.ErrorIs(t, err, er.ErrInvalidRequest)
+	assert.EqualError(t, err, "request body is required")
+
+	err = v.Validate(http.MethodPost, "/products", []byte(`{"type":`))
+	require.ErrorIs(t, err, er.ErrInvalidRequest)
+	assert.EqualError(t, err, "invalid json")
+	assert.Empty(t, er.From(err).Fields)
+
+	assert.False(t, v.HasBody(http.MethodGet, "/pvz"))
+	assert.False(t, v.HasBody(http.MethodPost, "/pvz/import"), "csv is not validated")
+	assert.NoError(t, v.Validate(http.MethodGet, "/pvz", []byte("anything")))
+}
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE pvz ADD COLUMN archived_at TIMESTAMPTZ;
+
+ALTER TABLE receptions
+    DROP CONSTRAINT receptions_pvz_id_fkey,
+    ADD CONSTRAINT receptions_pvz_id_fkey FOREIGN KEY (pvz_id) REFERENCES pvz(id) ON DELETE RESTRICT;
+
+ALTER TABLE products
+    DROP CONSTRAINT products_reception_id_fkey,
+    ADD CONSTRAINT products_reception_id_fkey FOREIGN KEY (reception_id) REFERENCES receptions(id) ON DELETE RESTRICT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE products
+    DROP CONSTRAINT products_reception_id_fkey,
+    ADD CONSTRAINT products_reception_id_fkey FOREIGN KEY (reception_id) REFERENCES receptions(id) ON DELETE CASCADE;
+
+ALTER TABLE receptions
+    DROP CONSTRAINT receptions_pvz_id_fkey,
+    ADD CONSTRAINT receptions_pvz_id_fkey FOREIGN KEY (pvz_id) REFERENCES pvz(id) ON DELETE CASCADE;
+
+ALTER TABLE pvz DROP COLUMN archived_at;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE pvz
+    ADD COLUMN address TEXT NOT NULL DEFAULT '',
+    ADD COLUMN latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90),
+    ADD COLUMN longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180),
+    ADD COLUMN working_hours JSONB,
+    ADD COLUMN capacity INTEGER CHECK (capacity > 0),
+    ADD CONSTRAINT pvz_coordinates_check CHECK ((latitude IS NULL) = (longitude IS NULL));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE pvz
+    DROP CONSTRAINT pvz_coordinates_check,
+    DROP COLUMN capacity,
+    DROP COLUMN working_hours,
+    DROP COLUMN longitude,
+    DROP COLUMN latitude,
+    DROP COLUMN address;
+-- +goose StatementEnd
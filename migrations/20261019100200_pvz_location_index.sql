@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX pvz_location_idx ON pvz (latitude, longitude)
+    WHERE archived_at IS NULL AND latitude IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS pvz_location_idx;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE products ADD COLUMN issued_at TIMESTAMPTZ;
+
+ALTER TABLE pvz ADD COLUMN stored_items INTEGER NOT NULL DEFAULT 0 CHECK (stored_items >= 0);
+
+UPDATE pvz
+SET stored_items = counts.total
+FROM (
+    SELECT r.pvz_id, COUNT(*) AS total
+    FROM products p
+    JOIN receptions r ON r.id = p.reception_id
+    WHERE p.issued_at IS NULL
+    GROUP BY r.pvz_id
+) counts
+WHERE pvz.id = counts.pvz_id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE pvz DROP COLUMN stored_items;
+ALTER TABLE products DROP COLUMN issued_at;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE receptions ADD COLUMN closed_at TIMESTAMPTZ;
+
+-- closing time was not stored before, the last added product is the best approximation
+UPDATE receptions r
+SET closed_at = COALESCE((SELECT MAX(p.datetime) FROM products p WHERE p.reception_id = r.id), r.datetime)
+WHERE r.status = 'close';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE receptions DROP COLUMN closed_at;
+-- +goose StatementEnd
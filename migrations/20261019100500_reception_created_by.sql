@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- author of the reception, unknown for receptions opened before and for dummy tokens
+ALTER TABLE receptions ADD COLUMN created_by UUID REFERENCES users(id) ON DELETE RESTRICT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE receptions DROP COLUMN created_by;
+-- +goose StatementEnd
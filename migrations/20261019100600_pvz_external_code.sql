@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- identifier of the PVZ in the partner's system, set by bulk import
+ALTER TABLE pvz ADD COLUMN external_code TEXT;
+ALTER TABLE pvz ADD CONSTRAINT pvz_external_code_key UNIQUE (external_code);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE pvz DROP COLUMN external_code;
+-- +goose StatementEnd
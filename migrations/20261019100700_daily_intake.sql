@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- number of products received per UTC day, PVZ and product type,
+-- kept in sync with products by the repository transactions
+CREATE TABLE daily_intake (
+    day DATE NOT NULL,
+    pvz_id UUID NOT NULL REFERENCES pvz(id) ON DELETE RESTRICT,
+    type TEXT NOT NULL,
+    products INTEGER NOT NULL CHECK (products >= 0),
+    PRIMARY KEY (day, pvz_id, type)
+);
+
+INSERT INTO daily_intake (day, pvz_id, type, products)
+SELECT (p.datetime AT TIME ZONE 'UTC')::date, r.pvz_id, p.type, COUNT(*)
+FROM products p
+JOIN receptions r ON r.id = p.reception_id
+GROUP BY 1, 2, 3;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS daily_intake;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- append-only, rows are written in the same transaction as the change they describe
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    actor_id UUID,
+    actor_role TEXT,
+    action TEXT NOT NULL,
+    entity_type TEXT NOT NULL,
+    entity_id TEXT NOT NULL,
+    before JSONB,
+    after JSONB,
+    request_id TEXT,
+    ip TEXT
+);
+
+CREATE INDEX audit_log_entity_idx ON audit_log (entity_type, entity_id, id);
+CREATE INDEX audit_log_actor_idx ON audit_log (actor_id, id);
+CREATE INDEX audit_log_created_at_idx ON audit_log (created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS audit_log;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose NO TRANSACTION
+-- built concurrently so that products and receptions stay writable on big tables,
+-- which needs a statement per index outside of a transaction
+
+-- +goose Up
+-- open reception of a PVZ: OpenReceptionIDQuery (finding the reception to add products to or
+-- close), HasOpenReceptionQuery (opening a reception, archiving), LockOpenReceptionIDQuery
+-- (deleting products)
+CREATE INDEX CONCURRENTLY IF NOT EXISTS receptions_pvz_open_idx ON receptions (pvz_id, datetime DESC)
+    WHERE status = 'in_progress';
+
+-- receptions of a PVZ in a period: export, and the ON DELETE RESTRICT check of
+-- receptions_pvz_id_fkey when a pvz row is deleted
+CREATE INDEX CONCURRENTLY IF NOT EXISTS receptions_pvz_datetime_idx ON receptions (pvz_id, datetime);
+
+-- receptions in a period: reception summary of the intake report
+CREATE INDEX CONCURRENTLY IF NOT EXISTS receptions_datetime_idx ON receptions (datetime);
+
+-- products of a reception newest first: LastProductQuery (DeleteLast), export, per-reception counts,
+-- and the ON DELETE RESTRICT check of products_reception_id_fkey when a reception is deleted
+CREATE INDEX CONCURRENTLY IF NOT EXISTS products_reception_datetime_idx ON products (reception_id, datetime DESC);
+
+-- +goose Down
+DROP INDEX CONCURRENTLY IF EXISTS products_reception_datetime_idx;
+DROP INDEX CONCURRENTLY IF EXISTS receptions_datetime_idx;
+DROP INDEX CONCURRENTLY IF EXISTS receptions_pvz_datetime_idx;
+DROP INDEX CONCURRENTLY IF EXISTS receptions_pvz_open_idx;
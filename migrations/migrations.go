@@ -0,0 +1,57 @@
+// Package migrations embeds the goose SQL migrations, so the binary knows which schema version it expects.
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+// LatestVersion returns the version of the newest embedded migration, taken from the numeric
+// prefix of its file name.
+func LatestVersion() (int64, error) {
+	files, err := fs.Glob(FS, "*.sql")
+	if err != nil {
+		return 0, errors.Wrap(err, "can't list migrations")
+	}
+
+	var latest int64
+	for _, name := range files {
+		prefix, _, ok := strings.Cut(name, "_")
+		if !ok {
+			return 0, errors.Errorf("migration %q has no version prefix", name)
+		}
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return 0, errors.Wrapf(err, "migration %q has invalid version", name)
+		}
+		latest = max(latest, version)
+	}
+
+	return latest, nil
+}
+
+// NewProvider returns a goose provider over the embedded migrations. Every run takes a Postgres
+// advisory lock for its whole duration, so replicas migrating on startup apply each migration once.
+func NewProvider(db *sql.DB) (*goose.Provider, error) {
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, errors.Wrap(err, "can't create migration lock")
+	}
+
+	provider, err := goose.NewProvider(goose.DialectPostgres, db, FS, goose.WithSessionLocker(locker))
+	if err != nil {
+		return nil, errors.Wrap(err, "can't create migration provider")
+	}
+
+	return provider, nil
+}
@@ -0,0 +1,28 @@
+package migrations_test
+
+import (
+	"io/fs"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"trainee-pvz/migrations"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	files, err := fs.Glob(migrations.FS, "*.sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, name := range files {
+		content, err := fs.ReadFile(migrations.FS, name)
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "-- +goose Up", name)
+	}
+
+	latest, err := migrations.LatestVersion()
+	require.NoError(t, err)
+	assert.Equal(t, files[len(files)-1][:len("20060102150405")], strconv.FormatInt(latest, 10))
+}